@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// batchResult is the summary of one quote file in batch output. Exactly
+// one of Measurements and Error is set.
+type batchResult struct {
+	File         string             `json:"file"`
+	Format       string             `json:"format,omitempty"`
+	Measurements *rtmr.Measurements `json:"measurements,omitempty"`
+	Error        string             `json:"error,omitempty"`
+
+	report *rtmr.Report
+}
+
+func runBatch(args []string) {
+	flags := newFlagSet("batch", "[--csv | --ndjson] [--parallel N] [--keep-going] <dir>")
+	flags.BoolVar(&csvOutput, "csv", false, "Print a CSV row per file instead of a JSON array")
+	flags.BoolVar(&ndjson, "ndjson", false, "Print one JSON object per file, on its own line, as each file is done, instead of a JSON array at the end")
+	flags.BoolVar(&csvCRLF, "csv-crlf", false, "End --csv lines with CRLF instead of LF")
+	flags.TextVar(&hashFormat, "hash-format", rtmr.HashHex, "Encoding of measurements: hex, hex0x or base64")
+	parallel := flags.Int("parallel", 1, "Number of files to process concurrently")
+	keepGoing := flags.Bool("keep-going", false, "Exit zero even if some files could not be extracted")
+	parseArgs(flags, args, 1)
+	if *parallel < 1 {
+		fatalf(exitUsage, "--parallel must be at least 1")
+	}
+	if csvOutput && ndjson {
+		fatalf(exitUsage, "--csv and --ndjson cannot be used together")
+	}
+
+	// Keep stdout for the summary.
+	diag = os.Stderr
+
+	var paths []string
+	err := filepath.WalkDir(flags.Arg(0), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		fatalf(exitError, "Failed to read directory: %v", err)
+	}
+
+	results := make([]batchResult, len(paths))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	// With --ndjson each result is written as soon as it and every result
+	// before it are ready, so the lines are in file order whatever
+	// --parallel is and the output of a directory is the same on every run.
+	var streamMu sync.Mutex
+	stream := json.NewEncoder(os.Stdout)
+	done := make([]bool, len(paths))
+	next := 0
+	for range *parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = extractFile(paths[i])
+				if ndjson {
+					streamMu.Lock()
+					done[i] = true
+					for ; next < len(results) && done[next]; next++ {
+						if err := stream.Encode(results[next]); err != nil {
+							fatalf(exitError, "Failed to encode JSON: %v", err)
+						}
+					}
+					streamMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+			logger.Warn("failed to extract quote", "file", r.File, "err", r.Error)
+		}
+	}
+	logger.Info("batch done", "files", len(results), "failed", failed)
+
+	if csvOutput {
+		writeBatchCSV(results)
+	} else if !ndjson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fatalf(exitError, "Failed to encode JSON: %v", err)
+		}
+	}
+
+	if failed > 0 && !*keepGoing {
+		os.Exit(exitParse)
+	}
+}
+
+// extractFile loads and parses one quote file of a batch.
+func extractFile(path string) batchResult {
+	result := batchResult{File: path}
+	quoteData, err := loadQuoteData(path)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	report, err := rtmr.ParseQuote(quoteData)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	m := report.MeasurementsIn(hashFormat)
+	m.SetMeanings(rtmrLabels)
+	result.Format = report.Format.String()
+	result.Measurements = &m
+	result.report = report
+	return result
+}
+
+// writeBatchCSV writes the batch results as --csv rows led by the file name
+// and the error, if any; the other columns are empty for failed files.
+func writeBatchCSV(results []batchResult) {
+	header := csvHeader()
+	records := [][]string{append([]string{"file", "error"}, header...)}
+	for _, r := range results {
+		row := []string{r.File, r.Error}
+		if r.report != nil {
+			row = append(row, csvRow(r.report)...)
+		} else {
+			row = append(row, make([]string, len(header))...)
+		}
+		records = append(records, row)
+	}
+	writeCSV(records...)
+}
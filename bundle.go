@@ -0,0 +1,83 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/go-tdx-guest/abi"
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// Entries of an --export-bundle archive besides the collateral files, which
+// keep their --collateral-dir names.
+const (
+	bundleQuote    = "quote.dat"
+	bundleManifest = "bundle.json"
+)
+
+// exportBundle is the --export-bundle archive path.
+var exportBundle string
+
+func addExportBundleFlag(fs *flag.FlagSet) {
+	fs.StringVar(&exportBundle, "export-bundle", "", "Write the quote and the collateral used to verify it to this tar file, to repeat the verification offline with --collateral-dir")
+}
+
+// bundleInfo is the bundle.json manifest of an --export-bundle archive.
+type bundleInfo struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Source    string    `json:"source"`
+	Files     []string  `json:"files"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// writeBundle writes the quote of report and the recorded collateral to a
+// tar file at path, with a manifest holding when the collateral was fetched,
+// where from, and the verification error if there was one.
+func writeBundle(path string, report *rtmr.Report, collateral rtmr.Collateral, fetchedAt time.Time, verifyErr error) error {
+	quoteData, err := abi.QuoteToAbiBytes(report.Quote)
+	if err != nil {
+		return fmt.Errorf("encoding quote: %v", err)
+	}
+	files := map[string][]byte{bundleQuote: quoteData}
+	for name, data := range collateral {
+		files[name] = data
+	}
+
+	info := bundleInfo{FetchedAt: fetchedAt, Source: pcsURL}
+	if collateralDir != "" {
+		info.Source = collateralDir
+	}
+	for name := range files {
+		info.Files = append(info.Files, name)
+	}
+	sort.Strings(info.Files)
+	if verifyErr != nil {
+		info.Error = verifyErr.Error()
+	}
+	manifest, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	files[bundleManifest] = append(manifest, '\n')
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range append([]string{bundleManifest}, info.Files...) {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), ModTime: fetchedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+)
+
+// noColor is --no-color. A non-empty NO_COLOR environment variable
+// (https://no-color.org) has the same effect.
+var noColor bool
+
+func addColorFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&noColor, "no-color", false, "Print check results as plain PASS/FAIL text, without ANSI color or symbols (also set by NO_COLOR)")
+}
+
+// colorEnabled reports whether w may receive ANSI color and symbols: it
+// must be a terminal, and color must not be turned off.
+func colorEnabled(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(f)
+}
+
+// verdict formats the result word of a check (PASS, FAIL, MATCH, ...) for
+// diag: green with ✅ or red with ❌ when color is enabled, and the plain
+// word otherwise, so logs stay parseable.
+func verdict(word string, ok bool) string {
+	if !colorEnabled(diag) {
+		return word
+	}
+	if ok {
+		return "\x1b[32m✅ " + word + "\x1b[0m"
+	}
+	return "\x1b[31m❌ " + word + "\x1b[0m"
+}
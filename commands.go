@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// command is a subcommand. Each one parses its own flags from args.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+var commands = []command{
+	{"extract", "Print the RTMRs and measurements of a quote (the default)", runExtract},
+	{"get", "Print a single measurement (rtmr0..3, mrtd, reportdata, ...) of a quote", runGet},
+	{"verify", "Fully verify a quote against the Intel PCS", runVerify},
+	{"dump", "Print the whole parsed quote as protobuf text", runDump},
+	{"replay", "Replay a CCEL/TCG2 event log against a quote's RTMRs", runReplay},
+	{"predict", "Compute the expected RTMR0 from a JSON list of UEFI events", runPredict},
+	{"predict-mrtd", "Compute the expected MRTD of a TDVF firmware image", runPredictMRTD},
+	{"canonicalize", "Write a quote in any accepted encoding as raw QuoteV4 bytes", runCanonicalize},
+	{"diff", "Compare the measurements of two quotes field by field", runDiff},
+	{"batch", "Extract every quote file in a directory into one JSON or CSV summary", runBatch},
+	{"serve", "Run an HTTP server that verifies quotes (POST /verify)", runServe},
+	{"grpc", "Run a gRPC server with Extract and Verify RPCs (with reflection)", runGRPC},
+	{"fetch", "Request a fresh quote from configfs-tsm and write it out", runFetch},
+	{"selftest", "Fetch a quote with random ReportData and check it end to end", runSelftest},
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [args]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s [flags] <quote-file>  (same as extract)\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+func addVerifyFlags(fs *flag.FlagSet) {
+	fs.StringVar(&pcsURL, "pcs-url", rtmr.DefaultPCSURL, "Base URL of the Intel PCS or a mirror of it, used for verification")
+	fs.StringVar(&collateralDir, "collateral-dir", "", "Directory of collateral files to verify against instead of the Intel PCS (see README)")
+	fs.IntVar(&collateralRetries, "collateral-retries", 3, "Retries for collateral requests that fail transiently (network error, timeout, 429, 5xx)")
+	fs.DurationVar(&collateralTimeout, "collateral-timeout", rtmr.DefaultCollateralTimeout, "Timeout of each collateral request")
+	fs.DurationVar(&maxCollateralAge, "max-collateral-age", 0, "Fail verification if the TCB info collateral was issued longer ago than this, e.g. 72h (0 for no limit)")
+	fs.Func("pck-ca-fingerprint", "SHA-256 fingerprint (hex, colons allowed) of a CA certificate that must be in the quote's PCK chain; verification fails otherwise", func(s string) error {
+		fp, err := parseFingerprint(s)
+		pckCAFingerprint = fp
+		return err
+	})
+	fs.StringVar(&rootCAFile, "root-ca", "", "PEM file of the root CA to trust instead of the built-in Intel SGX Root CA, e.g. for test platforms")
+	fs.StringVar(&minTCB, "min-tcb", "UpToDate", "Worst TCB status to accept, e.g. SWHardeningNeeded or OutOfDate; worse statuses fail verification")
+}
+
+// verifyOptions returns the verification options set by addVerifyFlags.
+func verifyOptions() rtmr.VerifyOptions {
+	return rtmr.VerifyOptions{
+		PCSURL:            pcsURL,
+		CollateralDir:     collateralDir,
+		MinTCB:            minTCBStatus,
+		CollateralRetries: collateralRetries,
+		CollateralTimeout: collateralTimeout,
+		MaxCollateralAge:  maxCollateralAge,
+		PCKCAFingerprint:  pckCAFingerprint,
+		RootCA:            rootCA,
+	}
+}
+
+// parseFingerprint parses a SHA-256 certificate fingerprint given as hex,
+// optionally colon-separated as printed by openssl.
+func parseFingerprint(s string) ([]byte, error) {
+	s = strings.ReplaceAll(strings.TrimPrefix(strings.TrimSpace(s), "0x"), ":", "")
+	fp, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %v", err)
+	}
+	if len(fp) != sha256.Size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", sha256.Size, len(fp))
+	}
+	return fp, nil
+}
+
+// parseVerifyFlags validates --min-tcb and loads --root-ca, exiting on
+// error.
+func parseVerifyFlags() {
+	status, err := rtmr.ParseTCBStatus(minTCB)
+	if err != nil {
+		fatalf(exitUsage, "Invalid --min-tcb value: %v", err)
+	}
+	minTCBStatus = status
+
+	if rootCAFile == "" {
+		return
+	}
+	data, err := os.ReadFile(rootCAFile)
+	if err != nil {
+		fatalf(exitUsage, "Failed to read --root-ca: %v", err)
+	}
+	certs, err := rtmr.ParseCertificates(data)
+	if err == nil && len(certs) == 0 {
+		err = fmt.Errorf("no PEM certificates found")
+	}
+	if err != nil {
+		fatalf(exitUsage, "Invalid --root-ca file: %v", err)
+	}
+	rootCA = data
+	for _, cert := range certs {
+		logger.Warn("trusting a custom root CA instead of the Intel SGX Root CA", "subject", cert.Subject.String(), "path", rootCAFile)
+	}
+}
+
+// addTimeoutFlag registers --timeout, which bounds the network IO of
+// verification; see startTimeout.
+func addTimeoutFlag(fs *flag.FlagSet) {
+	fs.DurationVar(&timeout, "timeout", 0, "Give up fetching a quote URL or verifying, including collateral fetches, if the whole run takes longer than this, e.g. 2m (0 for no limit)")
+}
+
+// startTimeout starts the --timeout deadline of the run, if one was given.
+func startTimeout() {
+	if timeout > 0 {
+		runCtx, cancelRun = context.WithTimeout(context.Background(), timeout)
+	}
+}
+
+// addMaxQuoteSizeFlag registers --max-quote-size, which bounds every quote
+// read from a file, stdin or a request body; see readLimited.
+func addMaxQuoteSizeFlag(fs *flag.FlagSet) {
+	fs.Int64Var(&maxQuoteSize, "max-quote-size", defaultMaxQuoteSize, "Reject input larger than this many bytes, before reading it into memory (after gzip decompression too)")
+}
+
+// dryRun is --dry-run: print what a configfs-tsm fetch would do instead of
+// doing it; see printDryRun.
+var dryRun bool
+
+func addDryRunFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&dryRun, "dry-run", false, "Print the configfs-tsm operations and ReportData of the quote request, then exit without touching "+rtmr.TSMReportPath)
+}
+
+func addShowQEFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&showQE, "show-qe", false, "Print the Quoting Enclave report and PCK certificate chain summary")
+}
+
+// newFlagSet returns a flag set for a subcommand whose usage line is
+// "<tool> <name> <synopsis>".
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n", os.Args[0], name, synopsis)
+		fs.PrintDefaults()
+	}
+	addLogLevelFlag(fs)
+	addColorFlag(fs)
+	addMaxQuoteSizeFlag(fs)
+	return fs
+}
+
+// parseArgs parses args into fs and exits with usage unless exactly n
+// positional arguments remain.
+func parseArgs(fs *flag.FlagSet, args []string, n int) {
+	fs.Parse(args)
+	if fs.NArg() != n {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+}
+
+// parseQuote parses quoteData, exiting on error.
+func parseQuote(quoteData []byte) *rtmr.Report {
+	report, err := rtmr.ParseQuote(quoteData)
+	if err != nil {
+		fatalf(exitParse, "Failed to extract TD Report from quote: %v", err)
+	}
+	logger.Info("detected quote format", "format", report.Format.String())
+	return report
+}
+
+func runVerify(args []string) {
+	fs := newFlagSet("verify", "[--pcs-url url | --collateral-dir dir] [--min-tcb status] [--export-bundle out.tar] [--remote-verify url] <quote-file>")
+	addVerifyFlags(fs)
+	addExportBundleFlag(fs)
+	addRemoteVerifyFlags(fs)
+	addTimeoutFlag(fs)
+	fs.BoolVar(&explain, "explain", false, "Walk through each link of the verification chain, with the data it uses and its result")
+	addQuietFlag(fs)
+	parseArgs(fs, args, 1)
+	silence()
+	parseVerifyFlags()
+	if explain && remoteVerifyURL != "" {
+		fatalf(exitUsage, "--explain and --remote-verify cannot be used together")
+	}
+	startTimeout()
+
+	quoteData := loadQuote(fs.Arg(0))
+	report := parseQuote(quoteData)
+	code := exitOK
+	switch {
+	case explain:
+		code = explainChain(report, true)
+	case remoteVerifyURL != "":
+		code = verifyWithRemote(report, quoteData)
+	default:
+		code = verifyQuote(report)
+	}
+	if code != exitOK {
+		os.Exit(code)
+	}
+}
+
+func runGet(args []string) {
+	fs := newFlagSet("get", "[--allow-zero] <field> <quote-file>")
+	allowZero := fs.Bool("allow-zero", false, "Print the field even if it is all zeros (an RTMR that was never extended) instead of failing")
+	parseArgs(fs, args, 2)
+
+	name := strings.ToLower(fs.Arg(0))
+	if !slices.Contains(rtmr.FieldNames(), name) {
+		fatalf(exitUsage, "Unknown field %q; want one of %s", fs.Arg(0), strings.Join(rtmr.FieldNames(), ", "))
+	}
+
+	report := parseQuote(loadQuote(fs.Arg(1)))
+	value, _ := report.Field(name)
+	if !*allowZero && bytes.Equal(value, make([]byte, len(value))) {
+		fatalf(exitError, "%s is all zeros (uninitialized); use --allow-zero to print it anyway", name)
+	}
+	fmt.Println(formatHash(value))
+}
+
+func runDump(args []string) {
+	fs := newFlagSet("dump", "[--show-qe] <quote-file>")
+	addShowQEFlag(fs)
+	parseArgs(fs, args, 1)
+
+	report := parseQuote(loadQuote(fs.Arg(0)))
+	if report.Quote == nil {
+		printProtoText(report)
+		os.Exit(exitParse)
+	}
+	if showQE {
+		printQEReport(report)
+	}
+	printProtoText(report)
+}
+
+func runReplay(args []string) {
+	fs := newFlagSet("replay", "<quote-file> <eventlog>")
+	parseArgs(fs, args, 2)
+
+	report := parseQuote(loadQuote(fs.Arg(0)))
+	if !checkEventLog(&report.TDReport, fs.Arg(1)) {
+		os.Exit(exitMismatch)
+	}
+}
+
+func runPredict(args []string) {
+	fs := newFlagSet("predict", "[--quote quote-file] <events.json>")
+	quotePath := fs.String("quote", "", "Quote to compare the predicted RTMR0 against")
+	parseArgs(fs, args, 1)
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fatalf(exitError, "Failed to read events: %v", err)
+	}
+	events, err := rtmr.ParseUEFIMeasurements(data)
+	if err != nil {
+		fatalf(exitParse, "Failed to parse events: %v", err)
+	}
+	predicted, err := rtmr.PredictRTMR0(events)
+	if err != nil {
+		fatalf(exitParse, "Failed to predict RTMR0: %v", err)
+	}
+	fmt.Printf("RTMR[0]: %s\n", formatHash(predicted[:]))
+
+	if *quotePath == "" {
+		return
+	}
+	// Keep stdout for the prediction itself.
+	diag = os.Stderr
+	report := parseQuote(loadQuote(*quotePath))
+	if report.Rtmr0 != predicted {
+		fmt.Fprintf(diag, "RTMR[0]: %s\n", verdict("MISMATCH", false))
+		fmt.Fprintf(diag, "  predicted: %s\n", formatHash(predicted[:]))
+		fmt.Fprintf(diag, "  actual:    %s\n", formatHash(report.Rtmr0[:]))
+		os.Exit(exitMismatch)
+	}
+	fmt.Fprintf(diag, "RTMR[0]: %s\n", verdict("MATCH", true))
+}
+
+func runPredictMRTD(args []string) {
+	fs := newFlagSet("predict-mrtd", "[--quote quote-file | --expected-mrtd hex] <firmware.fd>")
+	quotePath := fs.String("quote", "", "Quote to compare the predicted MRTD against")
+	fs.StringVar(&expectedTd, "expected-mrtd", "", "Precomputed MRTD as hex to compare the prediction against")
+	parseArgs(fs, args, 1)
+	if *quotePath != "" && expectedTd != "" {
+		fatalf(exitUsage, "--quote and --expected-mrtd both give the MRTD to compare with; use one of them")
+	}
+
+	firmware, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fatalf(exitError, "Failed to read firmware: %v", err)
+	}
+	predicted, err := rtmr.PredictMRTD(firmware)
+	if err != nil {
+		fatalf(exitParse, "Failed to predict MRTD: %v", err)
+	}
+	fmt.Printf("MRTD: %s\n", formatHash(predicted[:]))
+
+	var actual []byte
+	switch {
+	case *quotePath != "":
+		report := parseQuote(loadQuote(*quotePath))
+		actual = report.MrTd[:]
+	case expectedTd != "":
+		if actual, err = parseMeasurement(expectedTd); err != nil {
+			fatalf(exitUsage, "Invalid --expected-mrtd value: %v", err)
+		}
+	default:
+		return
+	}
+
+	// Keep stdout for the prediction itself.
+	diag = os.Stderr
+	if !bytes.Equal(actual, predicted[:]) {
+		fmt.Fprintf(diag, "MRTD: %s\n", verdict("MISMATCH", false))
+		fmt.Fprintf(diag, "  predicted: %s\n", formatHash(predicted[:]))
+		fmt.Fprintf(diag, "  actual:    %s\n", formatHash(actual))
+		os.Exit(exitMismatch)
+	}
+	fmt.Fprintf(diag, "MRTD: %s\n", verdict("MATCH", true))
+}
+
+func runFetch(args []string) {
+	fs := newFlagSet("fetch", "[--report-data-hex hex] [--dry-run] [-o file]")
+	fs.StringVar(&reportData, "report-data-hex", "", "ReportData as hex (up to 64 bytes, zero-padded) to include in the quote")
+	output := fs.String("o", "-", "File to write the raw quote to, or - for stdout")
+	addDryRunFlag(fs)
+	parseArgs(fs, args, 0)
+
+	// Keep stdout clean for the quote bytes.
+	diag = os.Stderr
+
+	var requestData []byte
+	if reportData != "" {
+		var err error
+		if requestData, err = parseReportData(reportData); err != nil {
+			fatalf(exitUsage, "Invalid --report-data-hex value: %v", err)
+		}
+	}
+
+	quoteData := fetchQuote(requestData)
+	if *output == "-" {
+		if _, err := os.Stdout.Write(quoteData); err != nil {
+			fatalf(exitError, "Failed to write quote: %v", err)
+		}
+		return
+	}
+	if err := os.WriteFile(*output, quoteData, 0o644); err != nil {
+		fatalf(exitError, "Failed to write quote: %v", err)
+	}
+}
+
+func runCanonicalize(args []string) {
+	fs := newFlagSet("canonicalize", "[--out file] <quote-file>")
+	output := fs.String("out", "-", "File to write the raw quote to, replaced atomically, or - for stdout")
+	parseArgs(fs, args, 1)
+
+	// Keep stdout clean for the quote bytes.
+	diag = os.Stderr
+	report := parseQuote(loadQuote(fs.Arg(0)))
+	if report.Quote == nil {
+		fatalf(exitParse, "canonicalize needs a QuoteV4, got %s", report.Format)
+	}
+	raw, err := rtmr.QuoteToRawBytes(report.Quote)
+	if err != nil {
+		fatalf(exitParse, "Failed to serialize quote: %v", err)
+	}
+
+	if *output == "-" {
+		if _, err := os.Stdout.Write(raw); err != nil {
+			fatalf(exitError, "Failed to write quote: %v", err)
+		}
+		return
+	}
+	if err := writeFileAtomic(*output, raw); err != nil {
+		fatalf(exitError, "Failed to write quote: %v", err)
+	}
+	logger.Info("wrote canonical quote", "path", *output, "bytes", len(raw))
+}
+
+func runDiff(args []string) {
+	fs := newFlagSet("diff", "<quote-a> <quote-b>")
+	parseArgs(fs, args, 2)
+
+	// Keep stdout for the comparison itself.
+	diag = os.Stderr
+	a := parseQuote(loadQuote(fs.Arg(0)))
+	b := parseQuote(loadQuote(fs.Arg(1)))
+
+	color := colorEnabled(os.Stdout)
+	fmt.Printf("  %-14s %-96s  %s\n", "Field", "A: "+fs.Arg(0), "B: "+fs.Arg(1))
+
+	same := true
+	for _, d := range rtmr.Diff(&a.TDReport, &b.TDReport) {
+		line := fmt.Sprintf("%-14s %-96x  %x", d.Name, d.A, d.B)
+		switch {
+		case !d.Differs:
+			fmt.Println("  " + line)
+		case color:
+			same = false
+			fmt.Println("\x1b[31m* " + line + "\x1b[0m")
+		default:
+			same = false
+			fmt.Println("* " + line)
+		}
+	}
+
+	if !same {
+		os.Exit(exitMismatch)
+	}
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// compareTo is --compare-to: a reference quote, as a path or http(s) URL,
+// whose measurements the quote must match.
+var compareTo string
+
+// loadReference reads and parses the --compare-to quote, exiting on error.
+func loadReference(source string) *rtmr.TDReport {
+	quoteData, err := loadQuoteData(source)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fatalf(exitTimeout, "Failed to load --compare-to reference %s: %v", source, err)
+	}
+	if err != nil {
+		fatalf(exitError, "Failed to load --compare-to reference %s: %v", source, err)
+	}
+	report, err := rtmr.ParseQuote(quoteData)
+	if err != nil {
+		fatalf(exitParse, "Failed to extract TD Report from --compare-to reference %s: %v", source, err)
+	}
+	logger.Info("loaded reference quote", "source", source, "format", report.Format.String())
+	return &report.TDReport
+}
+
+// checkReference compares the measurements of the report with those of the
+// --compare-to reference and reports whether none drifted.
+func checkReference(tdReport, reference *rtmr.TDReport) bool {
+	fmt.Fprintln(diag, "\nReference Comparison:")
+	fmt.Fprintln(diag, "=====================")
+	fmt.Fprintf(diag, "Reference: %s\n", compareTo)
+
+	diffs := rtmr.Diff(tdReport, reference)
+	drifted := 0
+	for _, d := range diffs {
+		if !d.Differs {
+			fmt.Fprintf(diag, "%s: %s\n", d.Name, verdict("SAME", true))
+			continue
+		}
+		drifted++
+		fmt.Fprintf(diag, "%s: %s\n", d.Name, verdict("CHANGED", false))
+		fmt.Fprintf(diag, "  reference: %s\n", formatHash(d.B))
+		fmt.Fprintf(diag, "  actual:    %s\n", formatHash(d.A))
+	}
+	if drifted > 0 {
+		fmt.Fprintf(diag, "Drift: %d of %d measurements changed\n", drifted, len(diffs))
+		return false
+	}
+	return true
+}
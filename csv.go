@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// CSV output options: --csv and --csv-crlf.
+var (
+	csvOutput bool
+	csvCRLF   bool
+)
+
+// csvHeader returns the --csv column names: the quote version and TEE type,
+// every TD Report field in byte order, and whether each RTMR is initialized.
+func csvHeader() []string {
+	header := []string{"quote_version", "tee_type"}
+	header = append(header, rtmr.FieldNames()...)
+	for i := range 4 {
+		header = append(header, fmt.Sprintf("rtmr%d_initialized", i))
+	}
+	return header
+}
+
+// csvRow returns the --csv values of report, in csvHeader order. The
+// version and TEE type are empty for raw inputs too short for a header.
+func csvRow(report *rtmr.Report) []string {
+	row := []string{"", ""}
+	if h := report.Header; h != nil {
+		row = []string{strconv.FormatUint(uint64(h.GetVersion()), 10), fmt.Sprintf("0x%08x", h.GetTeeType())}
+	}
+	for _, name := range rtmr.FieldNames() {
+		value, _ := report.Field(name)
+		row = append(row, hashFormat.Encode(value))
+	}
+	for _, initialized := range report.Initialized() {
+		row = append(row, strconv.FormatBool(initialized))
+	}
+	return row
+}
+
+// writeCSV writes records to the result output as RFC 4180 CSV.
+func writeCSV(records ...[]string) {
+	w := csv.NewWriter(out)
+	w.UseCRLF = csvCRLF
+	if err := w.WriteAll(records); err != nil {
+		fatalf(exitError, "Failed to write CSV output: %v", err)
+	}
+}
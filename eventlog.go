@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// checkEventLog replays the CCEL/TCG2 event log at path against the report's
+// RTMRs, printing per-register results, and reports whether all matched.
+func checkEventLog(tdReport *rtmr.TDReport, path string) bool {
+	fmt.Fprintln(diag, "\nEvent Log Replay:")
+	fmt.Fprintln(diag, "=================")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(diag, "%s Could not read event log: %v\n", verdict("FAIL:", false), err)
+		return false
+	}
+	events, err := rtmr.ParseEventLog(data)
+	if err != nil {
+		fmt.Fprintf(diag, "%s Could not parse event log: %v\n", verdict("FAIL:", false), err)
+		return false
+	}
+	fmt.Fprintf(diag, "Event log: %s (%d measured events)\n", path, len(events))
+
+	ok := true
+	rtmrs := tdReport.RTMRs()
+	for i, res := range tdReport.ReplayEventLog(events) {
+		if res.Match {
+			fmt.Fprintf(diag, "RTMR[%d]: %s (%d events)\n", i, verdict("MATCH", true), res.Events)
+			continue
+		}
+		ok = false
+		if res.FirstBadEvent < 0 {
+			fmt.Fprintf(diag, "RTMR[%d]: %s (no events in log)\n", i, verdict("DIVERGES", false))
+		} else {
+			fmt.Fprintf(diag, "RTMR[%d]: %s at event %d (%d events)\n", i, verdict("DIVERGES", false), res.FirstBadEvent, res.Events)
+		}
+		fmt.Fprintf(diag, "  replayed: %x\n", res.Replayed[:])
+		fmt.Fprintf(diag, "  actual:   %x\n", rtmrs[i][:])
+	}
+	return ok
+}
+
+// checkManifest replays the --rtmr3-manifest artifacts into RTMR3 from start
+// and reports whether the result matches the quote, naming the first
+// artifact that diverges otherwise.
+func checkManifest(tdReport *rtmr.TDReport, entries []rtmr.ManifestEntry, start [48]byte) bool {
+	fmt.Fprintln(diag, "\nRTMR3 Manifest Replay:")
+	fmt.Fprintln(diag, "======================")
+
+	res := rtmr.ReplayManifest(start, tdReport.Rtmr3, entries)
+	if res.Match {
+		fmt.Fprintf(diag, "RTMR[3]: %s (%d artifacts)\n", verdict("MATCH", true), len(entries))
+		return true
+	}
+	bad := entries[res.FirstBadEntry]
+	fmt.Fprintf(diag, "RTMR[3]: %s at artifact %d of %d: %s\n", verdict("DIVERGES", false), res.FirstBadEntry, len(entries), bad.Path)
+	fmt.Fprintf(diag, "  digest:   %x\n", bad.Digest[:])
+	fmt.Fprintf(diag, "  replayed: %x\n", res.Replayed[:])
+	fmt.Fprintf(diag, "  actual:   %x\n", tdReport.Rtmr3[:])
+	return false
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// Exit codes. They are part of the command-line contract, so that scripts
+// can tell a quote that could not be read from one whose measurements are
+// wrong. Keep README.md in sync.
+const (
+	exitOK       = 0 // success
+	exitError    = 1 // any other failure, e.g. fetching a quote or writing output
+	exitUsage    = 2 // bad flags or arguments (also what the flag package uses)
+	exitParse    = 3 // the quote could not be read or parsed
+	exitVerify   = 4 // full verification of the quote failed
+	exitMismatch = 5 // a measurement check failed: --expected, --policy, replay, ...
+	exitTCB      = 6 // the quote verified but its TCB status is worse than --min-tcb
+	exitTimeout  = 7 // verification did not finish within --timeout
+	exitDisagree = 8 // local verification and --remote-verify disagree
+)
+
+// fatalf logs like log.Fatalf and exits with code. With --quiet only usage
+// errors are logged, since the exit code alone cannot say what was wrong
+// with the command line.
+func fatalf(code int, format string, args ...any) {
+	if quiet && code == exitUsage {
+		log.SetOutput(os.Stderr)
+	}
+	log.Printf(format, args...)
+	os.Exit(code)
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// expectedRTMRs holds the golden RTMR values given with --expected. A nil
+// entry means no value was given for that register and it is not compared.
+type expectedRTMRs [4][]byte
+
+// parseExpected parses the --expected argument. If arg names a readable file
+// its contents are parsed instead. Entries are separated by commas or
+// newlines and are either positional ("<rtmr0>,<rtmr1>,,<rtmr3>", where an
+// empty entry skips that register) or named ("rtmr2=<hex>").
+func parseExpected(arg string) (expectedRTMRs, error) {
+	var expected expectedRTMRs
+
+	if data, err := os.ReadFile(arg); err == nil {
+		arg = string(data)
+	}
+
+	entries := strings.Split(strings.ReplaceAll(strings.TrimSpace(arg), "\n", ","), ",")
+
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		index := i
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "rtmr"))
+			if err != nil {
+				return expected, fmt.Errorf("invalid register name %q", name)
+			}
+			index, entry = n, strings.TrimSpace(value)
+		}
+		if index < 0 || index >= len(expected) {
+			return expected, fmt.Errorf("RTMR index %d out of range", index)
+		}
+
+		value, err := hex.DecodeString(strings.TrimPrefix(entry, "0x"))
+		if err != nil {
+			return expected, fmt.Errorf("RTMR[%d]: invalid hex: %v", index, err)
+		}
+		if len(value) != 48 {
+			return expected, fmt.Errorf("RTMR[%d]: expected 48 bytes, got %d", index, len(value))
+		}
+		expected[index] = value
+	}
+
+	return expected, nil
+}
+
+// loadRefBundle reads the --ref-bundle file and verifies it with the
+// --ref-pubkey key, exiting unless both are given and the signature holds.
+func loadRefBundle(bundlePath, pubKey string) *rtmr.ReferenceValues {
+	if bundlePath == "" || pubKey == "" {
+		fatalf(exitUsage, "--ref-bundle and --ref-pubkey must be given together")
+	}
+	keyData, err := os.ReadFile(pubKey)
+	if err != nil {
+		keyData = []byte(pubKey)
+	}
+	key, err := rtmr.ParseReferencePublicKey(keyData)
+	if err != nil {
+		fatalf(exitUsage, "Invalid --ref-pubkey value: %v", err)
+	}
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		fatalf(exitUsage, "Failed to read --ref-bundle: %v", err)
+	}
+	values, err := rtmr.OpenReferenceBundle(data, key)
+	if err != nil {
+		fatalf(exitUsage, "Rejected --ref-bundle: %v", err)
+	}
+	logger.Info("reference bundle signature verified", "path", bundlePath)
+	return values
+}
+
+// checkExpected compares the report's RTMRs against the expected values,
+// printing PASS/FAIL per register, and reports whether all given values
+// matched.
+func checkExpected(tdReport *rtmr.TDReport, expected expectedRTMRs) bool {
+	fmt.Fprintln(diag, "\nExpected RTMR Check:")
+	fmt.Fprintln(diag, "====================")
+
+	ok := true
+	for i, actual := range tdReport.RTMRs() {
+		if expected[i] == nil {
+			fmt.Fprintf(diag, "RTMR[%d]: SKIP (no expected value)\n", i)
+			continue
+		}
+		if bytes.Equal(actual[:], expected[i]) {
+			fmt.Fprintf(diag, "RTMR[%d]: %s\n", i, verdict("PASS", true))
+			continue
+		}
+		ok = false
+		fmt.Fprintf(diag, "RTMR[%d]: %s\n", i, verdict("FAIL", false))
+		fmt.Fprintf(diag, "  expected: %s\n", formatHash(expected[i]))
+		fmt.Fprintf(diag, "  actual:   %s\n", formatHash(actual[:]))
+	}
+	return ok
+}
+
+// parseMeasurement parses a single 48-byte measurement given as hex.
+func parseMeasurement(arg string) ([]byte, error) {
+	value, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(arg), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %v", err)
+	}
+	if len(value) != 48 {
+		return nil, fmt.Errorf("expected 48 bytes, got %d", len(value))
+	}
+	return value, nil
+}
+
+// checkMrSeam compares the report's MRSEAM against the --expected-mrseam
+// value and reports whether they match.
+func checkMrSeam(tdReport *rtmr.TDReport, expected []byte) bool {
+	fmt.Fprintln(diag, "\nExpected MRSEAM Check:")
+	fmt.Fprintln(diag, "======================")
+
+	if bytes.Equal(tdReport.MrSeam[:], expected) {
+		fmt.Fprintf(diag, "MrSeam: %s\n", verdict("PASS", true))
+		return true
+	}
+	fmt.Fprintf(diag, "MrSeam: %s\n", verdict("FAIL", false))
+	fmt.Fprintf(diag, "  expected: %s\n", formatHash(expected))
+	fmt.Fprintf(diag, "  actual:   %s\n", formatHash(tdReport.MrSeam[:]))
+	return false
+}
+
+// parseSeamAttributes parses the --expected-seam-attributes value: exactly
+// the 8 bytes of the field, as hex.
+func parseSeamAttributes(arg string) ([]byte, error) {
+	value, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(arg), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %v", err)
+	}
+	if len(value) != 8 {
+		return nil, fmt.Errorf("expected 8 bytes, got %d", len(value))
+	}
+	return value, nil
+}
+
+// checkSeamAttributes compares the report's SEAMATTRIBUTES against the
+// --expected-seam-attributes value and reports whether they match.
+func checkSeamAttributes(tdReport *rtmr.TDReport, expected []byte) bool {
+	fmt.Fprintln(diag, "\nExpected SEAMATTRIBUTES Check:")
+	fmt.Fprintln(diag, "==============================")
+
+	if bytes.Equal(tdReport.SeamAttributes[:], expected) {
+		fmt.Fprintf(diag, "SeamAttributes: %s\n", verdict("PASS", true))
+		return true
+	}
+	fmt.Fprintf(diag, "SeamAttributes: %s\n", verdict("FAIL", false))
+	fmt.Fprintf(diag, "  expected: %s\n", formatHash(expected))
+	fmt.Fprintf(diag, "  actual:   %s [%s]\n", formatHash(tdReport.SeamAttributes[:]), strings.Join(tdReport.SEAMAttributes().Names(), " "))
+	return false
+}
+
+// checkNotDebug is --fail-on-debug: it reports whether the TD's DEBUG
+// attribute is clear.
+func checkNotDebug(tdReport *rtmr.TDReport) bool {
+	fmt.Fprintln(diag, "\nDebug Attribute Check:")
+	fmt.Fprintln(diag, "======================")
+
+	if !tdReport.Attributes().Debug() {
+		fmt.Fprintf(diag, "TdAttributes DEBUG: %s (not set)\n", verdict("PASS", true))
+		return true
+	}
+	fmt.Fprintf(diag, "TdAttributes DEBUG: %s (set: the host can read and modify this TD, so it is not production-safe)\n", verdict("FAIL", false))
+	return false
+}
+
+// checkMrTd compares the report's MRTD against the --expected-mrtd value and
+// reports whether they match.
+func checkMrTd(tdReport *rtmr.TDReport, expected []byte) bool {
+	fmt.Fprintln(diag, "\nExpected MRTD Check:")
+	fmt.Fprintln(diag, "====================")
+
+	if bytes.Equal(tdReport.MrTd[:], expected) {
+		fmt.Fprintf(diag, "MrTd: %s\n", verdict("PASS", true))
+		return true
+	}
+	fmt.Fprintf(diag, "MrTd: %s\n", verdict("FAIL", false))
+	fmt.Fprintf(diag, "  expected: %s\n", formatHash(expected))
+	fmt.Fprintf(diag, "  actual:   %s\n", formatHash(tdReport.MrTd[:]))
+	return false
+}
+
+// checkServTdHash compares the report's SERVTD_HASH against the
+// --expected-servtd value and reports whether they match. Quotes without
+// MRSERVICETD attest nothing about service TDs, so they always fail.
+func checkServTdHash(report *rtmr.Report, expected []byte) bool {
+	fmt.Fprintln(diag, "\nExpected SERVTD_HASH Check:")
+	fmt.Fprintln(diag, "===========================")
+
+	tdReport := &report.TDReport
+	if !report.HasServTdHash() {
+		fmt.Fprintf(diag, "ServTdHash: %s\n", verdict("FAIL", false))
+		fmt.Fprintln(diag, "  quote does not carry SERVTD_HASH")
+		return false
+	}
+	if bytes.Equal(tdReport.ServTdHash[:], expected) {
+		fmt.Fprintf(diag, "ServTdHash: %s\n", verdict("PASS", true))
+		return true
+	}
+	fmt.Fprintf(diag, "ServTdHash: %s\n", verdict("FAIL", false))
+	fmt.Fprintf(diag, "  expected: %s\n", formatHash(expected))
+	fmt.Fprintf(diag, "  actual:   %s\n", formatHash(tdReport.ServTdHash[:]))
+	return false
+}
+
+// checkMinTCBSVN compares the report's TEE_TCB_SVN against the
+// --min-tcb-svn minimum and reports whether every component is at least the
+// minimum.
+func checkMinTCBSVN(tdReport *rtmr.TDReport, min rtmr.TEETCBSVN) bool {
+	fmt.Fprintln(diag, "\nMinimum TEE_TCB_SVN Check:")
+	fmt.Fprintln(diag, "==========================")
+
+	svn := tdReport.TCBSVN()
+	if i := svn.AtLeast(min); i >= 0 {
+		fmt.Fprintf(diag, "TeeTcbSvn: %s (byte %d is %d, minimum %d)\n", verdict("FAIL", false), i, svn[i], min[i])
+		fmt.Fprintf(diag, "  minimum: %s [%s]\n", formatHash(min[:]), min)
+		fmt.Fprintf(diag, "  actual:  %s [%s]\n", formatHash(svn[:]), svn)
+		return false
+	}
+	fmt.Fprintf(diag, "TeeTcbSvn: %s (%s)\n", verdict("PASS", true), svn)
+	return true
+}
+
+// parseReportData parses the --report-data-hex argument. Values shorter than
+// the 64-byte field are zero-padded on the right, matching how a 32-byte
+// digest is placed in ReportData.
+func parseReportData(arg string) ([]byte, error) {
+	value, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(arg), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %v", err)
+	}
+	var reportData [64]byte
+	if len(value) > len(reportData) {
+		return nil, fmt.Errorf("expected at most %d bytes, got %d", len(reportData), len(value))
+	}
+	copy(reportData[:], value)
+	return reportData[:], nil
+}
+
+// checkReportData compares the report's ReportData against the expected
+// value and reports whether they match.
+func checkReportData(tdReport *rtmr.TDReport, expected []byte) bool {
+	fmt.Fprintln(diag, "\nExpected ReportData Check:")
+	fmt.Fprintln(diag, "==========================")
+
+	if bytes.Equal(tdReport.ReportData[:], expected) {
+		fmt.Fprintf(diag, "ReportData: %s\n", verdict("PASS", true))
+		return true
+	}
+	fmt.Fprintf(diag, "ReportData: %s\n", verdict("FAIL", false))
+	fmt.Fprintf(diag, "  expected: %s\n", formatHash(expected))
+	fmt.Fprintf(diag, "  actual:   %s\n", formatHash(tdReport.ReportData[:]))
+	return false
+}
+
+// reportDataBinding is the digest expected at the start of ReportData, as
+// given with --bind-algo and --bind-input, or with --nonce.
+type reportDataBinding struct {
+	algo   string
+	digest []byte
+	// title names the check in its report; empty means "ReportData Binding".
+	title string
+}
+
+// parseBinding hashes the --bind-input bytes with the --bind-algo digest.
+func parseBinding(algo, input string) (*reportDataBinding, error) {
+	value, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(input), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %v", err)
+	}
+	algo = strings.ToLower(strings.TrimSpace(algo))
+	switch algo {
+	case "sha256":
+		digest := sha256.Sum256(value)
+		return &reportDataBinding{algo: algo, digest: digest[:]}, nil
+	case "sha384":
+		digest := sha512.Sum384(value)
+		return &reportDataBinding{algo: algo, digest: digest[:]}, nil
+	}
+	return nil, fmt.Errorf("unknown digest %q (want sha256 or sha384)", algo)
+}
+
+// checkBinding compares the leading bytes of the report's ReportData against
+// the binding digest and reports whether they match. The rest of ReportData
+// is not checked; frameworks use it for other data.
+func checkBinding(tdReport *rtmr.TDReport, binding *reportDataBinding) bool {
+	title := binding.title
+	if title == "" {
+		title = "ReportData Binding"
+	}
+	fmt.Fprintf(diag, "\n%s Check:\n", title)
+	fmt.Fprintln(diag, strings.Repeat("=", len(title)+7))
+
+	actual := tdReport.ReportData[:len(binding.digest)]
+	if bytes.Equal(actual, binding.digest) {
+		fmt.Fprintf(diag, "ReportData[0:%d]: %s (%s)\n", len(binding.digest), verdict("MATCH", true), binding.algo)
+		return true
+	}
+	fmt.Fprintf(diag, "ReportData[0:%d]: %s (%s)\n", len(binding.digest), verdict("MISMATCH", false), binding.algo)
+	fmt.Fprintf(diag, "  expected: %s\n", formatHash(binding.digest))
+	fmt.Fprintf(diag, "  actual:   %s\n", formatHash(actual))
+	return false
+}
+
+// parseRequiredRTMRs parses the --require-rtmr argument, a comma-separated
+// list of RTMR indices.
+func parseRequiredRTMRs(arg string) ([]int, error) {
+	var required []int
+	for _, entry := range strings.Split(arg, ",") {
+		entry = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(entry)), "rtmr")
+		if entry == "" {
+			continue
+		}
+		index, err := strconv.Atoi(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RTMR index %q", entry)
+		}
+		if index < 0 || index > 3 {
+			return nil, fmt.Errorf("RTMR index %d out of range", index)
+		}
+		required = append(required, index)
+	}
+	if len(required) == 0 {
+		return nil, fmt.Errorf("no RTMR indices given")
+	}
+	return required, nil
+}
+
+// parseChecks parses the --check list of registered check names.
+func parseChecks(arg string) ([]string, error) {
+	registered := rtmr.Checkers()
+	var names []string
+	for _, name := range strings.Split(arg, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(registered, name) {
+			return nil, fmt.Errorf("unknown check %q (registered: %s)", name, strings.Join(registered, ", "))
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no check names given")
+	}
+	return names, nil
+}
+
+// checkRequiredRTMRs reports whether every listed RTMR has been extended,
+// printing the state of each.
+func checkRequiredRTMRs(tdReport *rtmr.TDReport, required []int) bool {
+	fmt.Fprintln(diag, "\nRequired RTMR Check:")
+	fmt.Fprintln(diag, "====================")
+
+	initialized := tdReport.Initialized()
+	ok := true
+	for _, i := range required {
+		if initialized[i] {
+			fmt.Fprintf(diag, "RTMR[%d]: %s (initialized)\n", i, verdict("PASS", true))
+			continue
+		}
+		ok = false
+		fmt.Fprintf(diag, "RTMR[%d]: %s (all zeros - never extended)\n", i, verdict("FAIL", false))
+	}
+	return ok
+}
+
+// checkHashWidth warns about each RTMR that looks like a SHA-256 digest
+// zero-padded to 48 bytes and reports whether none does.
+func checkHashWidth(tdReport *rtmr.TDReport) bool {
+	ok := true
+	for i, padded := range tdReport.SHA256Padded() {
+		if padded {
+			ok = false
+			logger.Warn("RTMR ends in 16 zero bytes: it looks like a SHA-256 digest padded to 48 bytes, check the measurement agent's hash algorithm", "rtmr", i)
+		}
+	}
+	return ok
+}
+
+// checkPolicy evaluates the report against a --policy file, printing
+// PASS/FAIL per rule, and reports whether every rule passed.
+func checkPolicy(tdReport *rtmr.TDReport, policy *rtmr.Policy) bool {
+	fmt.Fprintln(diag, "\nPolicy Check:")
+	fmt.Fprintln(diag, "=============")
+
+	ok := true
+	for _, res := range policy.Evaluate(tdReport) {
+		status := verdict("PASS", true)
+		if !res.Pass {
+			status, ok = verdict("FAIL", false), false
+		}
+		if res.Detail != "" {
+			fmt.Fprintf(diag, "%s: %s (%s)\n", res.Rule, status, res.Detail)
+		} else {
+			fmt.Fprintf(diag, "%s: %s\n", res.Rule, status)
+		}
+	}
+	return ok
+}
+
+// runChecks runs the --check checkers on report and prints each result.
+func runChecks(report *rtmr.Report, names []string) bool {
+	fmt.Fprintln(diag, "\nChecks:")
+	fmt.Fprintln(diag, "=======")
+
+	results, err := rtmr.RunCheckers(report, names)
+	if err != nil {
+		fmt.Fprintf(diag, "%s\n", err)
+		return false
+	}
+	ok := true
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Fprintf(diag, "%s: %s (%v)\n", res.Name, verdict("FAIL", false), res.Err)
+			ok = false
+		} else {
+			fmt.Fprintf(diag, "%s: %s\n", res.Name, verdict("PASS", true))
+		}
+	}
+	return ok
+}
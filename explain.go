@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// explain is --explain: walk through the verification chain link by link.
+var explain bool
+
+// explainChain narrates each link of the chain of trust behind the quote,
+// from the TD Report up to the TCB status, with the data each link uses.
+// The offline links are always checked; the TCB status needs collateral
+// and is only checked when full is set. It returns the exit code of full
+// verification, or exitOK.
+func explainChain(report *rtmr.Report, full bool) int {
+	fmt.Fprintln(diag, "\nVerification Chain:")
+	fmt.Fprintln(diag, "===================")
+
+	quote := report.Quote
+	if quote == nil {
+		fmt.Fprintf(diag, "The chain can only be followed for a QuoteV4; this is a %s.\n", report.Format)
+		if full {
+			return verifyQuote(report)
+		}
+		return exitOK
+	}
+	signed := quote.GetSignedData()
+	qeData := signed.GetCertificationData().GetQeReportCertificationData()
+
+	keyType := quote.GetHeader().GetAttestationKeyType()
+	algorithm := fmt.Sprintf("signature (unsupported attestation key type %d)", keyType)
+	if curve, hash, err := rtmr.AttestationKeyAlgorithm(keyType); err == nil {
+		algorithm = fmt.Sprintf("ECDSA %s signature over %s", curve.Params().Name, hash)
+	}
+	explainStep(1, "TD Report signed by the attestation key", rtmr.CheckSignature(quote),
+		"The quote's "+algorithm+" of the 48-byte header and\n"+
+			"the 584-byte TD Report (which holds the RTMRs) must verify with the\n"+
+			"attestation key carried in the quote.",
+		"attestation key", fmt.Sprintf("%x", signed.GetEcdsaAttestationKey()),
+		"signature", fmt.Sprintf("%x", signed.GetSignature()))
+
+	explainStep(2, "Attestation key certified by the Quoting Enclave", rtmr.CheckAttestationKeyBinding(quote),
+		"The Quoting Enclave, which generated the attestation key, commits to it in its\n"+
+			"own report: REPORTDATA[0:32] must be SHA-256(attestation key || QE auth data).",
+		"QE report data", fmt.Sprintf("%x", qeData.GetQeReport().GetReportData()),
+		"QE auth data", fmt.Sprintf("%x", qeData.GetQeAuthData().GetData()))
+
+	explainStep(3, "QE report signed by the PCK", rtmr.CheckQEReportSignature(quote),
+		"The QE report is signed by the platform's Provisioning Certification Key, whose\n"+
+			"certificate is the first of the chain in the certification data.",
+		"QE MRENCLAVE", fmt.Sprintf("%x", qeData.GetQeReport().GetMrEnclave()),
+		"QE report signature", fmt.Sprintf("%x", qeData.GetQeReportSignature()))
+
+	chain, err := rtmr.CheckPCKChainWithRoot(quote, rootCA)
+	var subjects []string
+	for i, cert := range chain {
+		subjects = append(subjects, fmt.Sprintf("certificate %d", i), cert.Subject.CommonName)
+	}
+	anchor := "the Intel SGX Root CA built into this tool"
+	if len(rootCA) != 0 {
+		anchor = "the --root-ca file " + rootCAFile
+	}
+	explainStep(4, "PCK certificate chained to the configured root CA", err,
+		"The PCK certificate must chain through its intermediate CA to the configured\n"+
+			"root CA: "+anchor+".\n"+
+			"Revocation is only checked with collateral (link 5).",
+		subjects...)
+
+	fmt.Fprintln(diag, "\n5. TCB status of the platform")
+	fmt.Fprintln(diag, "   With PCS collateral (TCB info, QE identity, CRLs), the PCK chain is checked")
+	fmt.Fprintln(diag, "   against revocation lists and the platform's TCB level is looked up.")
+	if !full {
+		fmt.Fprintln(diag, "   Result: SKIP (needs --verify to fetch collateral)")
+		return exitOK
+	}
+	result, err := verifyReport(report)
+	if result != nil {
+		fmt.Fprintf(diag, "   FMSPC: %s\n", result.FMSPC)
+		fmt.Fprintf(diag, "   TCB date: %s\n", result.TCBDate)
+		fmt.Fprintf(diag, "   Status: platform %s, TDX module %s, QE %s, overall %s\n",
+			result.TCBStatus, result.TDXModuleStatus, result.QEStatus, result.Status())
+		if len(result.AdvisoryIDs) > 0 {
+			fmt.Fprintf(diag, "   Advisories: %s\n", strings.Join(result.AdvisoryIDs, ", "))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(diag, "   Result: %s\n   Error: %v\n", verdict("FAIL", false), err)
+	} else {
+		fmt.Fprintf(diag, "   Result: %s\n", verdict("PASS", true))
+	}
+	return verifyExitCode(err)
+}
+
+// explainStep prints one link of the chain: its description, the data it
+// uses as label/value pairs, and its result.
+func explainStep(n int, title string, err error, what string, data ...string) {
+	fmt.Fprintf(diag, "\n%d. %s\n", n, title)
+	for _, line := range strings.Split(what, "\n") {
+		fmt.Fprintf(diag, "   %s\n", line)
+	}
+	for i := 0; i+1 < len(data); i += 2 {
+		fmt.Fprintf(diag, "   %s: %s\n", data[i], data[i+1])
+	}
+	if err != nil {
+		fmt.Fprintf(diag, "   Result: %s\n   Error: %v\n", verdict("FAIL", false), err)
+		return
+	}
+	fmt.Fprintf(diag, "   Result: %s\n", verdict("PASS", true))
+}
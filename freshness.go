@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// capturedAt is --captured-at: when the quote was captured, as RFC 3339.
+// Quotes carry no timestamp of their own, so this comes from the evidence
+// store that kept the quote.
+var capturedAt string
+
+// maxAge is --max-age: the oldest a quote may be, measured from its capture
+// time, before the freshness check fails. 0 means no limit.
+var maxAge time.Duration
+
+// metaSuffix is appended to a quote path to name its sidecar file.
+const metaSuffix = ".meta"
+
+// quoteMeta is the sidecar file stored next to a quote, e.g. quote.bin.meta:
+//
+//	{"capturedAt": "2026-10-16T02:59:02Z"}
+type quoteMeta struct {
+	CapturedAt string `json:"capturedAt"`
+}
+
+// quoteCapture is the resolved capture time of a quote and where it came
+// from: --captured-at or the sidecar path.
+type quoteCapture struct {
+	at     time.Time
+	source string
+}
+
+// captureTime resolves the capture time of the quote at path: --captured-at
+// if given, otherwise the capturedAt of the path's .meta sidecar. It returns
+// nil when neither is present.
+func captureTime(path string) (*quoteCapture, error) {
+	if capturedAt != "" {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(capturedAt))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --captured-at value: %v", err)
+		}
+		return &quoteCapture{at: t, source: "--captured-at"}, nil
+	}
+	if path == "" || path == "-" || isQuoteURL(path) {
+		return nil, nil
+	}
+	metaPath := path + metaSuffix
+	data, err := os.ReadFile(metaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta quoteMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", metaPath, err)
+	}
+	if meta.CapturedAt == "" {
+		return nil, fmt.Errorf("invalid %s: no capturedAt", metaPath)
+	}
+	t, err := time.Parse(time.RFC3339, meta.CapturedAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: capturedAt: %v", metaPath, err)
+	}
+	return &quoteCapture{at: t, source: metaPath}, nil
+}
+
+// checkFreshness prints the age of a quote from its capture time and
+// reports whether it is within --max-age. A capture time in the future
+// fails, since it means the timestamp or the clock is wrong. This is a
+// staleness guard for stored evidence, not cryptographic freshness: only a
+// nonce in ReportData proves when a quote was made.
+func checkFreshness(capture *quoteCapture) bool {
+	fmt.Fprintln(diag, "\nQuote Freshness Check:")
+	fmt.Fprintln(diag, "======================")
+
+	age := time.Since(capture.at).Round(time.Second)
+	fmt.Fprintf(diag, "Captured at: %s (from %s)\n", capture.at.UTC().Format(time.RFC3339), capture.source)
+	fmt.Fprintf(diag, "Age: %s\n", age)
+	logger.Info("quote age", "captured_at", capture.at, "age", age, "max", maxAge)
+	if age < 0 {
+		fmt.Fprintf(diag, "Freshness: %s (captured in the future)\n", verdict("FAIL", false))
+		return false
+	}
+	if maxAge == 0 {
+		return true
+	}
+	if age > maxAge {
+		fmt.Fprintf(diag, "Freshness: %s (older than %s)\n", verdict("FAIL", false), maxAge)
+		return false
+	}
+	fmt.Fprintf(diag, "Freshness: %s (max %s)\n", verdict("PASS", true), maxAge)
+	return true
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmrpb"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+func runGRPC(args []string) {
+	fs := newFlagSet("grpc", "[--addr host:port] [verification flags]")
+	addr := fs.String("addr", ":9090", "Address to listen on")
+	fs.StringVar(&grpcMetricsAddr, "metrics-addr", "", "Also serve Prometheus metrics over HTTP at /metrics on this address, e.g. :9091")
+	addVerifyFlags(fs)
+	parseArgs(fs, args, 0)
+	parseVerifyFlags()
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fatalf(exitError, "Failed to listen: %v", err)
+	}
+	// Leave room for the request's other fields next to the quote.
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(int(maxQuoteSize) + 64<<10))
+	rtmrpb.RegisterAttestationServer(srv, &attestationServer{})
+	reflection.Register(srv)
+
+	if grpcMetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			logger.Info("listening", "addr", grpcMetricsAddr, "protocol", "http", "path", "/metrics")
+			fatalf(exitError, "Metrics server failed: %v", http.ListenAndServe(grpcMetricsAddr, mux))
+		}()
+	}
+
+	logger.Info("listening", "addr", lis.Addr().String(), "protocol", "grpc")
+	fatalf(exitError, "Server failed: %v", srv.Serve(lis))
+}
+
+// grpcMetricsAddr is --metrics-addr of the grpc subcommand. Without it there
+// is no /metrics endpoint, and Verify records no metrics.
+var grpcMetricsAddr string
+
+// attestationServer implements the Attestation service on top of the same
+// code as the HTTP server.
+type attestationServer struct {
+	rtmrpb.UnimplementedAttestationServer
+}
+
+func (s *attestationServer) Extract(ctx context.Context, req *rtmrpb.ExtractRequest) (*rtmrpb.ExtractResponse, error) {
+	quoteData := req.GetQuote()
+	if decoded, ok := decodeBase64Quote(quoteData); ok {
+		quoteData = decoded
+	}
+	report, err := rtmr.ParseQuote(quoteData)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &rtmrpb.ExtractResponse{
+		Format:       report.Format.String(),
+		Measurements: measurementsProto(&report.TDReport),
+	}, nil
+}
+
+// Verify reports verification failures in the response, as POST /verify
+// does; only requests it cannot act on are gRPC errors.
+func (s *attestationServer) Verify(ctx context.Context, req *rtmrpb.VerifyRequest) (*rtmrpb.VerifyResponse, error) {
+	opts := verifyOptions()
+	if minTCB := req.GetOptions().GetMinTcb(); minTCB != "" {
+		tcbStatus, err := rtmr.ParseTCBStatus(minTCB)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts.MinTCB = tcbStatus
+	}
+
+	quoteData := req.GetQuote()
+	if decoded, ok := decodeBase64Quote(quoteData); ok {
+		quoteData = decoded
+	}
+
+	start := time.Now()
+	resp := verifyQuoteData(ctx, quoteData, !req.GetOptions().GetSignatureOnly(), opts)
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	if grpcMetricsAddr != "" {
+		recordVerification(resp, time.Since(start).Seconds())
+	}
+
+	out := &rtmrpb.VerifyResponse{
+		Valid:     resp.Valid,
+		Format:    resp.Format,
+		Signature: resp.Signature,
+		Error:     resp.Error,
+		Reason:    resp.Reason,
+	}
+	if resp.report != nil {
+		out.Measurements = measurementsProto(&resp.report.TDReport)
+	}
+	if t := resp.TCB; t != nil {
+		out.Tcb = &rtmrpb.TCBStatus{
+			Fmspc:       t.FMSPC,
+			Platform:    string(t.TCBStatus),
+			TcbDate:     t.TCBDate,
+			TdxModule:   string(t.TDXModuleStatus),
+			Qe:          string(t.QEStatus),
+			Overall:     string(t.Status()),
+			AdvisoryIds: t.AdvisoryIDs,
+		}
+	}
+	return out, nil
+}
+
+// measurementsProto converts the measurement set of r to its protobuf form.
+func measurementsProto(r *rtmr.TDReport) *rtmrpb.Measurements {
+	initialized := r.Initialized()
+	return &rtmrpb.Measurements{
+		Rtmr0:               r.Rtmr0[:],
+		Rtmr1:               r.Rtmr1[:],
+		Rtmr2:               r.Rtmr2[:],
+		Rtmr3:               r.Rtmr3[:],
+		Initialized:         initialized[:],
+		InitializedMask:     uint32(r.InitializedMask()),
+		MrTd:                r.MrTd[:],
+		MrConfigId:          r.MrConfigId[:],
+		MrOwner:             r.MrOwner[:],
+		MrOwnerConfig:       r.MrOwnerConfig[:],
+		ReportData:          r.ReportData[:],
+		MrSeam:              r.MrSeam[:],
+		MrSignerSeam:        r.MrSignerSeam[:],
+		ServTdHash:          r.ServTdHash[:],
+		TeeTcbSvn:           r.TeeTcbSvn[:],
+		SeamAttributes:      r.SeamAttributes[:],
+		SeamAttributesFlags: r.SEAMAttributes().Names(),
+		TdAttributes:        r.TdAttributes[:],
+		TdAttributesFlags:   r.Attributes().Names(),
+		Xfam:                r.Xfam[:],
+		XfamFeatures:        r.XFAM().Names(),
+	}
+}
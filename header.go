@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// headerOnly is --header-only: print the quote header and nothing else.
+var headerOnly bool
+
+// quoteHeader is the --json output of --header-only. The SVNs are decoded;
+// the raw byte fields are hex.
+type quoteHeader struct {
+	Version            uint32 `json:"version"`
+	AttestationKeyType uint32 `json:"attestationKeyType"`
+	TeeType            string `json:"teeType"`
+	QeSvn              uint16 `json:"qeSvn"`
+	PceSvn             uint16 `json:"pceSvn"`
+	QeVendorID         string `json:"qeVendorId"`
+	IntelQE            bool   `json:"intelQe"`
+	UserData           string `json:"userData"`
+}
+
+// printHeaderOnly prints the header of quoteData without parsing the body,
+// exiting if the header itself cannot be read.
+func printHeaderOnly(quoteData []byte) {
+	header, err := rtmr.ParseHeader(quoteData)
+	if err != nil {
+		fatalf(exitParse, "Failed to read quote header: %v", err)
+	}
+	h := quoteHeader{
+		Version:            header.GetVersion(),
+		AttestationKeyType: header.GetAttestationKeyType(),
+		TeeType:            fmt.Sprintf("0x%08x", header.GetTeeType()),
+		QeSvn:              rtmr.QESVN(header),
+		PceSvn:             rtmr.PCESVN(header),
+		QeVendorID:         hex.EncodeToString(header.GetQeVendorId()),
+		IntelQE:            bytes.Equal(header.GetQeVendorId(), rtmr.IntelQEVendorID),
+		UserData:           hex.EncodeToString(header.GetUserData()),
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(h); err != nil {
+			fatalf(exitError, "Failed to encode JSON output: %v", err)
+		}
+		return
+	}
+
+	fmt.Fprintln(out, "Quote Header:")
+	fmt.Fprintln(out, "=============")
+	fmt.Fprintf(out, "Version: %d\n", h.Version)
+	keyType := "unknown"
+	if curve, hash, err := rtmr.AttestationKeyAlgorithm(h.AttestationKeyType); err == nil {
+		keyType = fmt.Sprintf("ECDSA %s with %s", curve.Params().Name, hash)
+	}
+	fmt.Fprintf(out, "Attestation Key Type: %d (%s)\n", h.AttestationKeyType, keyType)
+	fmt.Fprintf(out, "TEE Type: %s (%s)\n", h.TeeType, teeTypeName(header.GetTeeType()))
+	fmt.Fprintf(out, "QE SVN: %d (raw %s)\n", h.QeSvn, hex.EncodeToString(header.GetQeSvn()))
+	fmt.Fprintf(out, "PCE SVN: %d (raw %s)\n", h.PceSvn, hex.EncodeToString(header.GetPceSvn()))
+	vendor := "not Intel's QE"
+	if h.IntelQE {
+		vendor = "Intel QE"
+	}
+	fmt.Fprintf(out, "QE Vendor ID: %s (%s)\n", h.QeVendorID, vendor)
+	fmt.Fprintf(out, "User Data: %s\n", h.UserData)
+}
+
+// teeTypeName names a header TEE type.
+func teeTypeName(teeType uint32) string {
+	switch teeType {
+	case 0x00000000:
+		return "SGX"
+	case 0x00000081:
+		return "TDX"
+	}
+	return "unknown"
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// rtmrLabels describes what each RTMR holds, in the text output and as
+// "meanings" in JSON and CBOR. --labels overrides the defaults.
+var rtmrLabels = rtmr.DefaultRTMRMeanings
+
+// parseLabels reads a --labels file of "rtmrN=description" lines. Blank
+// lines and lines starting with # are skipped; registers without a line
+// keep their default description.
+func parseLabels(path string) ([4]string, error) {
+	labels := rtmr.DefaultRTMRMeanings
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return labels, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return labels, fmt.Errorf("line %d: expected rtmrN=description", n)
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(key)), "rtmr"))
+		if err != nil || index < 0 || index >= len(labels) {
+			return labels, fmt.Errorf("line %d: unknown register %q (want rtmr0 to rtmr3)", n, strings.TrimSpace(key))
+		}
+		labels[index] = strings.TrimSpace(value)
+	}
+	return labels, scanner.Err()
+}
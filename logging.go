@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// logLevel is the --log-level threshold of logger.
+var logLevel = new(slog.LevelVar)
+
+// logger receives the diagnostic chatter: what was read and detected,
+// structural details of the quote and warnings. It always writes to stderr.
+// The measurements themselves go to stdout, and the reports of checks the
+// user asked for go to diag.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+func addLogLevelFlag(fs *flag.FlagSet) {
+	fs.TextVar(logLevel, "log-level", logLevel, "Minimum level of diagnostic log records on stderr: debug, info, warn or error")
+}
+
+// quiet is --quiet: print nothing and report the outcome only through the
+// exit code. Usage errors are still printed; see fatalf.
+var quiet bool
+
+func addQuietFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&quiet, "quiet", false, "Print nothing, not even errors (except usage errors); the exit code is the only result")
+}
+
+// silence discards all output for --quiet: the result, the check reports,
+// log records and fatal error messages. A --out file is still written.
+func silence() {
+	if !quiet {
+		return
+	}
+	out = io.Discard
+	diag = io.Discard
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	log.SetOutput(io.Discard)
+}
@@ -1,127 +1,1096 @@
 package main
 
 import (
-	"crypto/ecdsa"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
 	"crypto/elliptic"
-	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
+	"net/http"
 	"os"
-	"unsafe"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/google/go-tdx-guest/abi"
 	"github.com/google/go-tdx-guest/proto/tdx"
 	"github.com/google/go-tdx-guest/verify"
-	"google.golang.org/protobuf/proto"
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+	"google.golang.org/protobuf/encoding/prototext"
 )
 
-// TDReport represents the runtime TD Report structure (584 bytes)
-// This is the actual TD Report that contains the runtime RTMR values
-// Based on TDX Architecture Specification
-type TDReport struct {
-	ReportType     [4]byte   // Report type
-	Reserved1      [12]byte  // Reserved
-	CpuSvn         [16]byte  // CPU SVN
-	TeeTcbInfoHash [48]byte  // TEE TCB Info Hash
-	TeeInfoHash    [48]byte  // TEE Info Hash
-	ReportData     [64]byte  // Report data
-	Reserved2      [32]byte  // Reserved
-	MacStruct      [256]byte // MAC structure
-	TeeTcbSvn      [16]byte  // TEE TCB SVN
-	MrSeam         [48]byte  // SEAM measurement
-	MrSignerSeam   [48]byte  // SEAM signer measurement
-	SeamAttributes [8]byte   // SEAM attributes
-	TdAttributes   [8]byte   // TD attributes
-	Xfam           [8]byte   // XFAM
-	MrTd           [48]byte  // TD measurement
-	MrConfigId     [48]byte  // Config ID
-	MrOwner        [48]byte  // Owner measurement
-	MrOwnerConfig  [48]byte  // Owner config
-	Rtmr0          [48]byte  // RTMR 0 - Runtime measurement register 0
-	Rtmr1          [48]byte  // RTMR 1 - Runtime measurement register 1
-	Rtmr2          [48]byte  // RTMR 2 - Runtime measurement register 2
-	Rtmr3          [48]byte  // RTMR 3 - Runtime measurement register 3
-	ServTdHash     [48]byte  // Service TD hash
-}
+// Options shared by the subcommands. Each subcommand registers the ones it
+// accepts on its own flag set; see commands.go.
+var (
+	jsonOutput    bool
+	expectedFlag  string
+	verifyFlag    bool
+	pcsURL        = rtmr.DefaultPCSURL
+	collateralDir string
+	minTCB        string
+	reportData    string
+	bindAlgo      string
+	bindInput     string
+	fetchFlag     bool
+	eventLog      string
+	protoText     bool
+	showQE        bool
+	base64Input   bool
+	tokenInput    bool
+	gzipInput     bool
+	watch         bool
+	expectedSeam  string
+	expectedTd    string
+	seamAttrs     string
+	outPath       string
+	schema        bool
+	minTCBSVN     string
+	requireRTMR   string
+	fingerprint   bool
+	rtmrDigest    bool
+	multi         bool
+	policyFile    string
+	refBundle     string
+	refPubKey     string
+	failOnDebug   bool
+	checkNames    string
+	listChecks    bool
+	rawDump       string
+	dumpCerts     string
+	force         bool
+)
+
+// expectedServTd is --expected-servtd: the SERVTD_HASH the quote must
+// carry, as hex.
+var expectedServTd string
+
+// hashFormat is the --hash-format encoding of printed measurements.
+var hashFormat rtmr.HashFormat
+
+// pretty is --pretty: measurements in text output are printed as xxd-style
+// rows of grouped hex bytes.
+var pretty bool
+
+// nonce is --nonce: hex bytes whose SHA-256 must lead ReportData.
+var nonce string
+
+// cborOutput is --cbor: the --json structure encoded as CBOR.
+var cborOutput bool
+
+// ndjson is --ndjson: the --json structure on a single line per quote, for
+// consumers reading --multi, batch or --watch results as they come.
+var ndjson bool
+
+// dumpOffsets is --dump-offsets: print where each TD Report field lies.
+var dumpOffsets bool
+
+// maxQuoteSize is --max-quote-size: the most bytes of input read as one
+// quote file, stdin or request body. Quotes are a few KiB and --multi files
+// rarely more than a few hundred, so the default leaves plenty of room
+// while keeping hostile input from exhausting memory.
+var maxQuoteSize int64 = defaultMaxQuoteSize
+
+const defaultMaxQuoteSize = 4 << 20
+
+// strictHash is --strict-hash: an RTMR that looks like a zero-padded
+// SHA-256 digest fails extraction instead of only being warned about.
+var strictHash bool
+
+// timeout is --timeout. runCtx carries its deadline, from the start of the
+// run, to the network IO of verification; extraction is local and not
+// bounded by it.
+var (
+	timeout   time.Duration
+	runCtx    = context.Background()
+	cancelRun context.CancelFunc
+)
+
+// Collateral fetching options of verification; see addVerifyFlags.
+var (
+	collateralRetries int
+	collateralTimeout time.Duration
+	maxCollateralAge  time.Duration
+	pckCAFingerprint  []byte
+	rootCAFile        string
+	rootCA            []byte
+)
+
+// minTCBStatus is the parsed --min-tcb value.
+var minTCBStatus rtmr.TCBStatus
+
+// out receives the result: the measurements as text or JSON. It is stdout
+// unless --out names a file.
+var out io.Writer = os.Stdout
+
+// diag receives all diagnostic prose. It is stdout by default and stderr
+// when --json is set, so the JSON result can be piped cleanly.
+var diag io.Writer = os.Stdout
 
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <quote-file>\n", os.Args[0])
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if args[0] == "help" {
+			printUsage()
+			return
+		}
+		if cmd := findCommand(args[0]); cmd != nil {
+			cmd.run(args[1:])
+			return
+		}
+	}
+
+	// A bare "tool [flags] quote.bin" predates subcommands and means extract.
+	runExtract(args)
+}
+
+// runExtract implements the extract subcommand: print the RTMRs and other
+// measurements of a quote, with optional checks that set the exit code.
+func runExtract(args []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	fs.BoolVar(&jsonOutput, "json", false, "Print RTMR values as a single JSON object on stdout")
+	fs.BoolVar(&csvOutput, "csv", false, "Print a CSV header row and one row per quote (measurements, quote version, TEE type, RTMR initialized flags) on stdout")
+	fs.BoolVar(&csvCRLF, "csv-crlf", false, "End --csv lines with CRLF instead of LF")
+	fs.BoolVar(&ndjson, "ndjson", false, "Print the --json object on one line per quote (newline-delimited JSON), written as each quote is extracted")
+	fs.BoolVar(&cborOutput, "cbor", false, "Print the --json object CBOR-encoded (binary, with byte strings for measurements) on stdout")
+	fs.StringVar(&expectedFlag, "expected", "", "Expected RTMR values as a file or comma-separated hex list (rtmr0,...,rtmr3 or rtmrN=hex); exit non-zero on mismatch")
+	fs.BoolVar(&verifyFlag, "verify", false, "Fully verify the quote against the Intel PCS (fetches collateral and CRLs); exit non-zero on failure")
+	fs.BoolVar(&explain, "explain", false, "Walk through each link of the verification chain, with the data it uses and its result (the TCB status link needs --verify)")
+	addVerifyFlags(fs)
+	addExportBundleFlag(fs)
+	addTimeoutFlag(fs)
+	fs.StringVar(&reportData, "report-data-hex", "", "ReportData as hex (up to 64 bytes, zero-padded); sent with --fetch, and checked against the quote with exit non-zero on mismatch")
+	fs.StringVar(&bindAlgo, "bind-algo", "sha256", "Digest used for --bind-input: sha256 or sha384")
+	fs.StringVar(&bindInput, "bind-input", "", "Hex bytes (nonce, public key, ...) whose --bind-algo digest must lead ReportData; exit non-zero on mismatch")
+	fs.StringVar(&nonce, "nonce", "", "Freshness nonce as hex; its SHA-256 must be ReportData[0:32], exit non-zero otherwise")
+	fs.BoolVar(&fetchFlag, "fetch", false, "Request a fresh quote from configfs-tsm ("+rtmr.TSMReportPath+") instead of reading a file")
+	addDryRunFlag(fs)
+	fs.StringVar(&eventLog, "eventlog", "", "CCEL/TCG2 event log to replay against the quote's RTMRs; exit non-zero on mismatch")
+	manifestFile := fs.String("rtmr3-manifest", "", "Manifest of \"<sha384>  <path>\" lines, in extend order, of the artifacts the workload measures into RTMR3; exit non-zero if replaying it does not give RTMR3")
+	manifestStart := fs.String("rtmr3-start", "", "RTMR3 value as hex that --rtmr3-manifest is replayed from, instead of zero")
+	fs.BoolVar(&protoText, "proto-text", false, "Print the whole parsed quote as protobuf text (QuoteV4 only)")
+	addShowQEFlag(fs)
+	fs.StringVar(&refBundle, "ref-bundle", "", "Signed reference bundle (JSON, see README) whose RTMR and MRTD values are checked like --expected and --expected-mrtd, once its signature verifies with --ref-pubkey")
+	fs.StringVar(&refPubKey, "ref-pubkey", "", "Pinned ed25519 key that must have signed --ref-bundle: a PEM public key file or 32 bytes of hex")
+	fs.StringVar(&expectedSeam, "expected-mrseam", "", "Expected MRSEAM (TDX module measurement) as hex; exit non-zero on mismatch")
+	fs.StringVar(&seamAttrs, "expected-seam-attributes", "", "Expected SEAMATTRIBUTES (8 bytes) as hex, e.g. 0000000000000000 for a production TDX module; exit non-zero on mismatch")
+	fs.StringVar(&expectedTd, "expected-mrtd", "", "Expected MRTD (initial TD/firmware measurement, see predict-mrtd) as hex; exit non-zero on mismatch")
+	fs.StringVar(&expectedServTd, "expected-servtd", "", "Expected SERVTD_HASH (service TD hash, MRSERVICETD of TDX 1.5 quotes) as hex; exit non-zero on mismatch")
+	fs.StringVar(&minTCBSVN, "min-tcb-svn", "", "Minimum TEE_TCB_SVN as hex (module SVN, major version, SEAMLDR SVN, ...; zero-padded to 16 bytes); exit non-zero if any component is lower")
+	fs.StringVar(&requireRTMR, "require-rtmr", "", "Comma-separated RTMR indices that must be initialized (non-zero), e.g. 3 or 1,3; exit non-zero otherwise")
+	fs.BoolVar(&tpmStyle, "tpm-style", false, "Print MRTD and RTMR0-3 as a tpm2_pcrread sha384 bank (index : 0xHEX, RTMR[n] at index n+1) on stdout")
+	fs.BoolVar(&oneline, "oneline", false, "Print a single summary line per quote (version, TEE type, 12-digit prefixes of MRTD and RTMR0-3, offline signature result) on stdout, for logs")
+	fs.BoolVar(&fingerprint, "fingerprint", false, "Print only the SHA-256 measurement fingerprint (MRTD, MRCONFIGID, RTMR0-3) on stdout")
+	fs.BoolVar(&rtmrDigest, "rtmr-digest", false, "Print only the SHA-384 digest of RTMR0 || RTMR1 || RTMR2 || RTMR3 on stdout")
+	fs.BoolVar(&failOnDebug, "fail-on-debug", false, "Exit non-zero if the TD has the DEBUG attribute set, before any other check")
+	fs.StringVar(&policyFile, "policy", "", "YAML policy of allowed measurements to evaluate the quote against; exit non-zero on any failed rule")
+	fs.StringVar(&checkNames, "check", "", "Comma-separated registered checks (see --list-checks) to run on the quote; exit non-zero if any fails")
+	fs.BoolVar(&listChecks, "list-checks", false, "Print the names of the registered checks for --check and exit")
+	fs.StringVar(&rawDump, "raw-dump", "", "Write the TD Report region of the quote (584 bytes) to this file")
+	fs.StringVar(&dumpCerts, "dump-certs", "", "Write the PCK certificate chain of the quote's certification data to this file as PEM, and list each certificate's subject and expiry")
+	fs.BoolVar(&force, "force", false, "Overwrite the --raw-dump file if it exists")
+	fs.BoolVar(&headerOnly, "header-only", false, "Print only the 48-byte quote header (version, TEE type, SVNs, QE vendor ID), as text or with --json, without parsing the body")
+	fs.BoolVar(&multi, "multi", false, "The quote file holds several raw quotes back to back; extract each one")
+	fs.BoolVar(&base64Input, "base64", false, "The quote file is base64-encoded (detected automatically when unambiguous)")
+	fs.BoolVar(&gzipInput, "gzip", false, "The quote file is gzip-compressed (detected automatically from its magic bytes)")
+	fs.BoolVar(&tokenInput, "token", false, "The quote file is an attestation token (JWT) with the quote in a claim (detected automatically)")
+	fs.TextVar(&hashFormat, "hash-format", rtmr.HashHex, "Encoding of printed measurements, in text and JSON: hex, hex0x or base64")
+	fs.BoolVar(&pretty, "pretty", false, "Print measurements in text output as rows of grouped hex bytes with offsets, like xxd")
+	fs.BoolVar(&dumpOffsets, "dump-offsets", false, "Print every TD Report field with its byte range in the 584-byte report and its hex value, for debugging layout changes")
+	fs.BoolVar(&strictHash, "strict-hash", false, "Exit non-zero when an RTMR ends in 16 zero bytes (a SHA-256 digest padded to 48 bytes) instead of only warning")
+	fs.BoolVar(&schema, "schema", false, "Print the JSON Schema (draft-07) of the --json output and exit")
+	labelsFile := fs.String("labels", "", "File of rtmrN=description lines describing the RTMRs in the output, instead of the defaults")
+	fs.StringVar(&outPath, "out", "-", "Write the result (text or JSON) to this file, replaced atomically, or - for stdout")
+	fs.StringVar(&capturedAt, "captured-at", "", "When the quote was captured, as RFC 3339; defaults to capturedAt in the quote file's .meta sidecar. The quote's age is printed")
+	fs.DurationVar(&maxAge, "max-age", 0, "Exit non-zero if the quote was captured (see --captured-at) longer ago than this, e.g. 24h")
+	fs.StringVar(&compareTo, "compare-to", "", "Reference quote (path or http(s):// URL) whose measurements must match; lists the ones that drifted and exits non-zero on any drift")
+	fs.BoolVar(&watch, "watch", false, "Re-run extraction whenever the quote file changes, until interrupted")
+	addLogLevelFlag(fs)
+	addColorFlag(fs)
+	addMaxQuoteSizeFlag(fs)
+	addQuietFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [extract] [--json] [--expected values] [--policy file.yaml] [--require-rtmr indices] [--verify] [--report-data-hex hex] [--bind-input hex [--bind-algo alg]] [--eventlog file] [--show-qe] [--proto-text] <quote-file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [extract] --fetch [--report-data-hex hex] [flags]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Example: %s quote.bin\n", os.Args[0])
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Use - as the quote file to read from stdin, or an http(s):// URL to fetch it. Run '%s help' for other commands.\n", os.Args[0])
+		fs.PrintDefaults()
 	}
+	fs.Parse(args)
+	silence()
 
-	quoteFile := os.Args[1]
+	if schema {
+		printSchema()
+		return
+	}
 
-	fmt.Printf("Reading TDX quote from: %s\n", quoteFile)
-	fmt.Println("==============================")
+	if listChecks {
+		for _, name := range rtmr.Checkers() {
+			fmt.Fprintln(out, name)
+		}
+		return
+	}
 
-	// Read the quote file
-	quoteData, err := os.ReadFile(quoteFile)
-	if err != nil {
-		log.Fatalf("Failed to read quote file: %v", err)
+	if (fetchFlag && fs.NArg() != 0) || (!fetchFlag && fs.NArg() != 1) {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if dryRun && !fetchFlag {
+		fatalf(exitUsage, "--dry-run only applies to --fetch")
 	}
 
-	fmt.Printf("Quote file size: %d bytes\n\n", len(quoteData))
+	parseVerifyFlags()
 
-	// Try to parse as protobuf QuoteV4 first (if it's from GetAttestation)
-	var quote tdx.QuoteV4
-	if err := proto.Unmarshal(quoteData, &quote); err == nil {
-		// It's a protobuf quote
-		fmt.Println("Detected protobuf QuoteV4 format")
-		extractFromQuoteV4(&quote)
+	if *labelsFile != "" {
+		var err error
+		if rtmrLabels, err = parseLabels(*labelsFile); err != nil {
+			fatalf(exitUsage, "Invalid --labels file: %v", err)
+		}
+	}
+
+	if btoi(jsonOutput)+btoi(ndjson)+btoi(cborOutput)+btoi(csvOutput)+btoi(protoText)+btoi(fingerprint)+btoi(rtmrDigest)+btoi(tpmStyle)+btoi(oneline) > 1 {
+		fatalf(exitUsage, "--json, --ndjson, --cbor, --csv, --proto-text, --fingerprint, --rtmr-digest, --tpm-style and --oneline all write to stdout; use one of them")
+	}
+	if (jsonOutput || ndjson || cborOutput || csvOutput || fingerprint || rtmrDigest || tpmStyle || oneline || outPath != "-") && !quiet {
+		diag = os.Stderr
+	}
+	if pretty && hashFormat != rtmr.HashHex {
+		fatalf(exitUsage, "--pretty prints hex and cannot be used with --hash-format %s", hashFormat)
+	}
+	if nonce != "" && bindInput != "" {
+		fatalf(exitUsage, "--nonce and --bind-input both check the start of ReportData; use one of them")
+	}
+	if maxCollateralAge != 0 && !verifyFlag {
+		fatalf(exitUsage, "--max-collateral-age applies to verification and needs --verify")
+	}
+	if timeout != 0 && watch {
+		fatalf(exitUsage, "--timeout bounds the whole run and cannot be used with --watch")
+	}
+	if exportBundle != "" && (!verifyFlag || multi || watch) {
+		fatalf(exitUsage, "--export-bundle needs --verify and a single quote; it cannot be used with --multi or --watch")
+	}
+	if multi && rawDump != "" {
+		fatalf(exitUsage, "--raw-dump writes a single TD Report and cannot be used with --multi")
+	}
+	if multi && dumpCerts != "" {
+		fatalf(exitUsage, "--dump-certs writes a single certificate chain and cannot be used with --multi")
+	}
+	if watch && (fetchFlag || fs.Arg(0) == "-" || isQuoteURL(fs.Arg(0)) || rawDump != "") {
+		fatalf(exitUsage, "--watch needs a quote file and cannot be used with --fetch, stdin, a URL or --raw-dump")
+	}
+
+	if (capturedAt != "" || maxAge != 0) && (fetchFlag || watch) {
+		fatalf(exitUsage, "--captured-at and --max-age describe a stored quote and cannot be used with --fetch or --watch")
+	}
+
+	if headerOnly && (multi || watch || outPath != "-" || btoi(ndjson)+btoi(cborOutput)+btoi(csvOutput)+btoi(protoText)+btoi(fingerprint)+btoi(rtmrDigest)+btoi(tpmStyle)+btoi(oneline) > 0) {
+		fatalf(exitUsage, "--header-only prints text or --json for a single quote and cannot be used with --multi, --watch, --out or other output formats")
+	}
+
+	var expected expectedRTMRs
+	if expectedFlag != "" {
+		var err error
+		if expected, err = parseExpected(expectedFlag); err != nil {
+			fatalf(exitUsage, "Invalid --expected value: %v", err)
+		}
+	}
+
+	var expectedMrSeam []byte
+	if expectedSeam != "" {
+		var err error
+		if expectedMrSeam, err = parseMeasurement(expectedSeam); err != nil {
+			fatalf(exitUsage, "Invalid --expected-mrseam value: %v", err)
+		}
+	}
+
+	var expectedSeamAttributes []byte
+	if seamAttrs != "" {
+		var err error
+		if expectedSeamAttributes, err = parseSeamAttributes(seamAttrs); err != nil {
+			fatalf(exitUsage, "Invalid --expected-seam-attributes value: %v", err)
+		}
+	}
+
+	var expectedMrTd []byte
+	if expectedTd != "" {
+		var err error
+		if expectedMrTd, err = parseMeasurement(expectedTd); err != nil {
+			fatalf(exitUsage, "Invalid --expected-mrtd value: %v", err)
+		}
+	}
+
+	var expectedServTdHash []byte
+	if expectedServTd != "" {
+		var err error
+		if expectedServTdHash, err = parseMeasurement(expectedServTd); err != nil {
+			fatalf(exitUsage, "Invalid --expected-servtd value: %v", err)
+		}
+	}
+
+	if refBundle != "" || refPubKey != "" {
+		values := loadRefBundle(refBundle, refPubKey)
+		if expectedFlag != "" {
+			fatalf(exitUsage, "--ref-bundle and --expected both give expected RTMRs; use one of them")
+		}
+		expected = values.RTMRs
+		if values.MrTd != nil {
+			if expectedMrTd != nil {
+				fatalf(exitUsage, "--ref-bundle and --expected-mrtd both give an expected MRTD; use one of them")
+			}
+			expectedMrTd = values.MrTd
+		}
+	}
+
+	var manifest []rtmr.ManifestEntry
+	var manifestFrom [48]byte
+	if *manifestFile != "" {
+		data, err := os.ReadFile(*manifestFile)
+		if err == nil {
+			manifest, err = rtmr.ParseManifest(data)
+		}
+		if err != nil {
+			fatalf(exitUsage, "Invalid --rtmr3-manifest file: %v", err)
+		}
+	}
+	if *manifestStart != "" {
+		if *manifestFile == "" {
+			fatalf(exitUsage, "--rtmr3-start needs --rtmr3-manifest")
+		}
+		start, err := parseMeasurement(*manifestStart)
+		if err != nil {
+			fatalf(exitUsage, "Invalid --rtmr3-start value: %v", err)
+		}
+		copy(manifestFrom[:], start)
+	}
+
+	var minSVN *rtmr.TEETCBSVN
+	if minTCBSVN != "" {
+		svn, err := rtmr.ParseTEETCBSVN(minTCBSVN)
+		if err != nil {
+			fatalf(exitUsage, "Invalid --min-tcb-svn value: %v", err)
+		}
+		minSVN = &svn
+	}
+
+	var required []int
+	if requireRTMR != "" {
+		var err error
+		if required, err = parseRequiredRTMRs(requireRTMR); err != nil {
+			fatalf(exitUsage, "Invalid --require-rtmr value: %v", err)
+		}
+	}
+
+	var policy *rtmr.Policy
+	if policyFile != "" {
+		var err error
+		if policy, err = rtmr.LoadPolicy(policyFile); err != nil {
+			fatalf(exitUsage, "Invalid --policy file: %v", err)
+		}
+	}
+
+	var checks []string
+	if checkNames != "" {
+		var err error
+		if checks, err = parseChecks(checkNames); err != nil {
+			fatalf(exitUsage, "Invalid --check value: %v", err)
+		}
+	}
+
+	var expectedReportData []byte
+	if reportData != "" {
+		var err error
+		if expectedReportData, err = parseReportData(reportData); err != nil {
+			fatalf(exitUsage, "Invalid --report-data-hex value: %v", err)
+		}
+	}
+
+	var binding *reportDataBinding
+	if bindInput != "" {
+		var err error
+		if binding, err = parseBinding(bindAlgo, bindInput); err != nil {
+			fatalf(exitUsage, "Invalid --bind-input/--bind-algo value: %v", err)
+		}
+	}
+	if nonce != "" {
+		var err error
+		if binding, err = parseBinding("sha256", nonce); err != nil {
+			fatalf(exitUsage, "Invalid --nonce value: %v", err)
+		}
+		binding.title = "Nonce"
+	}
+
+	var capture *quoteCapture
+	if !fetchFlag && !watch {
+		var err error
+		if capture, err = captureTime(fs.Arg(0)); err != nil {
+			fatalf(exitUsage, "%v", err)
+		}
+		if capture == nil && maxAge != 0 {
+			fatalf(exitUsage, "--max-age needs --captured-at or a %s%s sidecar", fs.Arg(0), metaSuffix)
+		}
+	}
+
+	startTimeout()
+	var reference *rtmr.TDReport
+	if compareTo != "" {
+		reference = loadReference(compareTo)
+	}
+	c := extractChecks{expected: expected, required: required, reportData: expectedReportData, binding: binding, policy: policy, checks: checks, mrSeam: expectedMrSeam, seamAttributes: expectedSeamAttributes, mrTd: expectedMrTd, servTd: expectedServTdHash, minSVN: minSVN, manifest: manifest, manifestStart: manifestFrom, reference: reference, capture: capture}
+	if watch {
+		watchQuote(fs.Arg(0), c)
 		return
 	}
 
-	// Try to parse as raw quote using ABI package
-	if quoteProto, err := abi.QuoteToProto(quoteData); err == nil {
-		if q4, ok := quoteProto.(*tdx.QuoteV4); ok {
-			fmt.Println("Detected raw QuoteV4 format, converted to protobuf")
-			extractFromQuoteV4(q4)
-			return
+	var quoteData []byte
+	if fetchFlag {
+		quoteData = fetchQuote(expectedReportData)
+	} else {
+		quoteData = loadQuote(fs.Arg(0))
+	}
+
+	if headerOnly {
+		printHeaderOnly(quoteData)
+		return
+	}
+
+	if code := extractQuote(quoteData, c); code != exitOK {
+		os.Exit(code)
+	}
+}
+
+// extractQuote parses quoteData and runs extract on it, or on each quote with
+// --multi, writing the result to --out. It returns the exit code.
+func extractQuote(quoteData []byte, c extractChecks) int {
+	if outPath == "-" {
+		return extractQuoteData(quoteData, c)
+	}
+
+	var buf bytes.Buffer
+	out = &buf
+	defer func() { out = os.Stdout }()
+
+	code := extractQuoteData(quoteData, c)
+	if code == exitParse {
+		return code
+	}
+	if err := writeFileAtomic(outPath, buf.Bytes()); err != nil {
+		fatalf(exitError, "Failed to write result: %v", err)
+	}
+	logger.Info("wrote result", "path", outPath)
+	return code
+}
+
+func extractQuoteData(quoteData []byte, c extractChecks) int {
+	if csvOutput {
+		writeCSV(csvHeader())
+	}
+	if multi {
+		return extractMulti(quoteData, c)
+	}
+	report, err := rtmr.ParseQuote(quoteData)
+	if err != nil {
+		log.Printf("Failed to extract TD Report from quote: %v", err)
+		return exitParse
+	}
+	return extractReport(report, quoteData, c)
+}
+
+// extractChecks are the parsed check arguments of the extract subcommand.
+type extractChecks struct {
+	expected   expectedRTMRs
+	required   []int
+	reportData []byte
+	binding    *reportDataBinding
+	policy     *rtmr.Policy
+	checks     []string
+	mrSeam     []byte
+	// seamAttributes is the --expected-seam-attributes value.
+	seamAttributes []byte
+	mrTd           []byte
+	// servTd is the --expected-servtd value.
+	servTd []byte
+	minSVN *rtmr.TEETCBSVN
+	// manifest is the --rtmr3-manifest, replayed from manifestStart.
+	manifest      []rtmr.ManifestEntry
+	manifestStart [48]byte
+	// reference is the --compare-to quote's TD Report.
+	reference *rtmr.TDReport
+	// capture is when the quote was captured, if known.
+	capture *quoteCapture
+}
+
+// extractMulti runs extract on each quote of a buffer of raw quotes stored
+// back to back. It returns the exit code of the first quote that failed, or
+// exitOK.
+func extractMulti(quoteData []byte, c extractChecks) int {
+	quotes, leftover, splitErr := rtmr.SplitQuotes(quoteData)
+	logger.Info("split quotes", "count", len(quotes))
+
+	code := exitOK
+	offset := 0
+	for i, q := range quotes {
+		fmt.Fprintf(diag, "\n### Quote %d (offset %d, %d bytes)\n\n", i, offset, len(q))
+		offset += len(q)
+
+		report, err := rtmr.ParseQuote(q)
+		if err != nil {
+			logger.Warn("failed to extract TD Report", "quote", i, "err", err)
+			if code == exitOK {
+				code = exitParse
+			}
+			continue
+		}
+		if qcode := extractReport(report, q, c); code == exitOK {
+			code = qcode
+		}
+	}
+
+	if leftover > 0 {
+		logger.Warn("bytes left over after the last complete quote", "bytes", leftover, "err", splitErr)
+	}
+	return code
+}
+
+// extractReport prints a parsed quote and runs the requested checks on it,
+// stopping at the first failure. It returns the exit code.
+func extractReport(report *rtmr.Report, quoteData []byte, c extractChecks) int {
+	logger.Info("detected quote format", "format", report.Format.String())
+	switch report.Format {
+	case rtmr.FormatProtoV4, rtmr.FormatRawV4:
+		validateQuoteStructure(report.Quote)
+	case rtmr.FormatRawV5:
+		validateQuoteV5Structure(report)
+	case rtmr.FormatRaw:
+		warnRawQuote(quoteData)
+	}
+	if report.CertData != nil {
+		if _, err := report.CertData.CertChain(); err != nil {
+			logger.Warn("quote certification data cannot be verified", "err", err)
+		}
+	}
+
+	if showQE {
+		printQEReport(report)
+	}
+
+	if protoText {
+		printProtoText(report)
+	}
+
+	switch {
+	case csvOutput:
+		writeCSV(csvRow(report))
+	case oneline:
+		printOneline(report)
+	default:
+		printRTMRValues(report)
+	}
+
+	if dumpOffsets {
+		printOffsets(&report.TDReport)
+	}
+
+	if rawDump != "" {
+		writeRawDump(report, rawDump)
+	}
+
+	if dumpCerts != "" {
+		writeCertDump(report, dumpCerts)
+	}
+
+	if failOnDebug && !checkNotDebug(&report.TDReport) {
+		return exitMismatch
+	}
+
+	if !checkHashWidth(&report.TDReport) && strictHash {
+		return exitMismatch
+	}
+
+	if explain {
+		if code := explainChain(report, verifyFlag); code != exitOK {
+			return code
+		}
+	} else if verifyFlag {
+		if code := verifyQuote(report); code != exitOK {
+			return code
+		}
+	}
+
+	if c.capture != nil && !checkFreshness(c.capture) {
+		return exitMismatch
+	}
+
+	if (expectedFlag != "" || refBundle != "") && !checkExpected(&report.TDReport, c.expected) {
+		return exitMismatch
+	}
+
+	if c.mrSeam != nil && !checkMrSeam(&report.TDReport, c.mrSeam) {
+		return exitMismatch
+	}
+
+	if c.seamAttributes != nil && !checkSeamAttributes(&report.TDReport, c.seamAttributes) {
+		return exitMismatch
+	}
+
+	if c.mrTd != nil && !checkMrTd(&report.TDReport, c.mrTd) {
+		return exitMismatch
+	}
+
+	if c.servTd != nil && !checkServTdHash(report, c.servTd) {
+		return exitMismatch
+	}
+
+	if c.minSVN != nil && !checkMinTCBSVN(&report.TDReport, *c.minSVN) {
+		return exitMismatch
+	}
+
+	if c.policy != nil && !checkPolicy(&report.TDReport, c.policy) {
+		return exitMismatch
+	}
+
+	if c.checks != nil && !runChecks(report, c.checks) {
+		return exitMismatch
+	}
+
+	if c.required != nil && !checkRequiredRTMRs(&report.TDReport, c.required) {
+		return exitMismatch
+	}
+
+	if eventLog != "" && !checkEventLog(&report.TDReport, eventLog) {
+		return exitMismatch
+	}
+
+	if c.reference != nil && !checkReference(&report.TDReport, c.reference) {
+		return exitMismatch
+	}
+
+	if c.manifest != nil && !checkManifest(&report.TDReport, c.manifest, c.manifestStart) {
+		return exitMismatch
+	}
+
+	if c.reportData != nil && !checkReportData(&report.TDReport, c.reportData) {
+		return exitMismatch
+	}
+
+	if c.binding != nil && !checkBinding(&report.TDReport, c.binding) {
+		return exitMismatch
+	}
+	return exitOK
+}
+
+// writeCertDump writes the PCK certificate chain of report to path as
+// concatenated PEM blocks, replaced atomically, and prints the subject and
+// expiry of each certificate. It exits if the quote carries no chain.
+func writeCertDump(report *rtmr.Report, path string) {
+	if report.CertData == nil {
+		fatalf(exitParse, "The quote has no certification data to dump")
+	}
+	chain, err := report.CertData.CertChain()
+	if err != nil {
+		fatalf(exitParse, "Cannot dump certificates: %v", err)
+	}
+	certs, err := rtmr.ParseCertificates(chain)
+	if err != nil {
+		fatalf(exitParse, "Cannot dump certificates: %v", err)
+	}
+	if len(certs) == 0 {
+		fatalf(exitParse, "Cannot dump certificates: no PCK certificate in certification data")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(diag, "\nPCK Certificate Chain:")
+	fmt.Fprintln(diag, "======================")
+	for i, cert := range certs {
+		pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		left := time.Until(cert.NotAfter)
+		fmt.Fprintf(diag, "[%d] Subject: %s\n", i, cert.Subject)
+		fmt.Fprintf(diag, "    Not After: %s (%s)\n", cert.NotAfter.UTC().Format(time.RFC3339), certExpiry(left))
+		if left > 0 && left < 30*24*time.Hour {
+			logger.Warn("certificate expires soon", "index", i, "subject", cert.Subject.String(), "not_after", cert.NotAfter)
+		}
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		fatalf(exitError, "Failed to write certificates: %v", err)
+	}
+	logger.Info("wrote PCK certificate chain", "path", path, "certificates", len(certs))
+}
+
+// certExpiry describes how long until a certificate expires, in days.
+func certExpiry(left time.Duration) string {
+	days := int(left.Hours() / 24)
+	switch {
+	case left <= 0:
+		return fmt.Sprintf("expired %d days ago", -days)
+	case days == 1:
+		return "expires in 1 day"
+	default:
+		return fmt.Sprintf("expires in %d days", days)
+	}
+}
+
+// writeRawDump writes the TD Report region of the quote to path, exiting on
+// failure. An existing file is only replaced with --force.
+func writeRawDump(report *rtmr.Report, path string) {
+	b, err := report.TDReportBytes()
+	if err != nil {
+		fatalf(exitParse, "Failed to encode TD Report: %v", err)
+	}
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, mode, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		fatalf(exitUsage, "%s already exists; use --force to overwrite it", path)
+	}
+	if err != nil {
+		fatalf(exitError, "Failed to create %s: %v", path, err)
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		fatalf(exitError, "Failed to write %s: %v", path, err)
+	}
+	if err := f.Close(); err != nil {
+		fatalf(exitError, "Failed to write %s: %v", path, err)
+	}
+	logger.Info("wrote TD Report", "path", path, "bytes", len(b))
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// loadQuote reads the quote at path (or stdin for "-", or a URL), exiting
+// on error.
+func loadQuote(path string) []byte {
+	quoteData, err := loadQuoteData(path)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fatalf(exitTimeout, "Failed to load quote: %v", err)
+	}
+	if err != nil {
+		fatalf(exitParse, "Failed to load quote: %v", err)
+	}
+	return quoteData
+}
+
+// loadQuoteData reads the quote at path (or stdin for "-", or an http(s)
+// URL) and unwraps an attestation token, JSON evidence object or base64
+// encoding.
+func loadQuoteData(path string) ([]byte, error) {
+	if isQuoteURL(path) {
+		quoteData, err := readQuoteURL(path)
+		if err != nil {
+			return nil, fmt.Errorf("fetching quote: %w", err)
+		}
+		logger.Info("fetched quote", "url", path, "bytes", len(quoteData))
+		return unwrapQuote(quoteData)
+	}
+
+	quoteData, err := readQuote(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading quote file: %v", err)
+	}
+	logger.Info("read quote", "path", path, "bytes", len(quoteData))
+	return unwrapQuote(quoteData)
+}
+
+// unwrapQuote decompresses gzip input and unwraps an attestation token,
+// JSON evidence object or base64 encoding, then checks that the result
+// looks like a quote.
+func unwrapQuote(quoteData []byte) ([]byte, error) {
+	var err error
+	if gzipInput || bytes.HasPrefix(quoteData, gzipMagic) {
+		if quoteData, err = gunzip(quoteData); err != nil {
+			return nil, fmt.Errorf("decompressing gzip input: %v", err)
+		}
+		logger.Info("decompressed gzip input", "bytes", len(quoteData))
+	}
+
+	switch {
+	case tokenInput || rtmr.LooksLikeToken(quoteData):
+		quote, err := rtmr.QuoteFromToken(quoteData)
+		if err != nil {
+			return nil, fmt.Errorf("extracting quote from attestation token: %v", err)
+		}
+		logger.Info("extracted quote from attestation token", "bytes", len(quote))
+		logger.Warn("the attestation token's signature is not checked; verify the quote itself")
+		quoteData = quote
+	case rtmr.LooksLikeJSONQuote(quoteData):
+		quote, err := rtmr.QuoteFromJSON(quoteData)
+		if err != nil {
+			return nil, fmt.Errorf("extracting quote from JSON evidence: %v", err)
+		}
+		logger.Info("extracted quote from JSON evidence", "bytes", len(quote))
+		quoteData = quote
+	case base64Input:
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(quoteData)), ""))
+		if err != nil {
+			return nil, fmt.Errorf("quote file is not valid base64: %v", err)
+		}
+		logger.Info("decoded base64 input", "bytes", len(decoded))
+		quoteData = decoded
+	default:
+		if decoded, ok := decodeBase64Quote(quoteData); ok {
+			logger.Info("detected base64 input", "bytes", len(decoded))
+			quoteData = decoded
 		}
 	}
 
-	// If ABI parsing failed, try manual raw quote parsing
-	fmt.Println("Detected raw quote format, attempting manual parsing...")
-	extractFromRawQuote(quoteData)
+	if err := checkQuoteShape(quoteData); err != nil {
+		return nil, err
+	}
+	return quoteData, nil
 }
 
-func extractFromQuoteV4(quote *tdx.QuoteV4) {
-	// First validate the quote structure
-	validateQuoteStructure(quote)
-	
-	tdQuoteBody := quote.GetTdQuoteBody()
-	if tdQuoteBody == nil {
-		log.Fatal("No TD Quote Body found in quote")
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gunzip decompresses a gzip stream of at most maxQuoteSize bytes, so a
+// gzip bomb cannot exhaust memory.
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
 	}
+	defer zr.Close()
+	return readLimited(zr, "decompressed data")
+}
+
+// minQuoteSize is the smallest plausible quote: a header and a TD Report.
+// With --header-only the header alone is enough.
+const (
+	minQuoteSize  = 48 + 584
+	minHeaderSize = 48
+)
 
-	// Convert the protobuf TDQuoteBody to our runtime TD Report structure
-	tdReport := &TDReport{}
-	
-	// Copy the RTMR values from the protobuf structure
-	rtmrs := tdQuoteBody.GetRtmrs()
-	if len(rtmrs) >= 4 {
-		copy(tdReport.Rtmr0[:], rtmrs[0])
-		copy(tdReport.Rtmr1[:], rtmrs[1])
-		copy(tdReport.Rtmr2[:], rtmrs[2])
-		copy(tdReport.Rtmr3[:], rtmrs[3])
+// checkQuoteShape rejects input that cannot be a quote before the parsers
+// try it, with one message naming the likely problem. A quote must start
+// with a raw header (a small version and an SGX or TDX TEE type) or with
+// the header field of a protobuf QuoteV4, and be long enough for a TD
+// Report.
+func checkQuoteShape(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("the file is empty")
+	}
+	if len(data) >= 8 {
+		version := binary.LittleEndian.Uint16(data[0:2])
+		teeType := binary.LittleEndian.Uint32(data[4:8])
+		if version >= 1 && version <= 0xff && (teeType == 0 || teeType == 0x81) {
+			if headerOnly && len(data) < minHeaderSize {
+				return fmt.Errorf("truncated quote: %d bytes, a quote header is %d", len(data), minHeaderSize)
+			}
+			if !headerOnly && len(data) < minQuoteSize {
+				return fmt.Errorf("truncated quote: %d bytes, a quote is at least %d", len(data), minQuoteSize)
+			}
+			return nil
+		}
+	}
+	if data[0] == 0x0a {
+		return nil
 	}
-	
-	// Copy other important measurements
-	copy(tdReport.MrTd[:], tdQuoteBody.GetMrTd())
-	copy(tdReport.MrConfigId[:], tdQuoteBody.GetMrConfigId())
-	copy(tdReport.MrOwner[:], tdQuoteBody.GetMrOwner())
-	copy(tdReport.MrOwnerConfig[:], tdQuoteBody.GetMrOwnerConfig())
 
-	printRTMRValues(tdReport)
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(text, "-----BEGIN"):
+		return fmt.Errorf("this is a PEM file (a certificate or key?), not a quote")
+	case text != "" && strings.Trim(strings.ToLower(text), "0123456789abcdef\n\r ") == "":
+		return fmt.Errorf("this looks like hex text; decode it to binary first (e.g. xxd -r -p)")
+	case utf8.ValidString(text) && !strings.ContainsFunc(text, isBinaryRune):
+		return fmt.Errorf("this is text, not a quote (expected a raw or protobuf quote, base64, JSON evidence or an attestation token)")
+	}
+	return fmt.Errorf("wrong format: the %d bytes do not start like a TDX quote header or a protobuf quote", len(data))
 }
 
-func extractFromRawQuote(quoteData []byte) {
+// isBinaryRune reports whether r is a control character other than
+// whitespace, which text files do not contain.
+func isBinaryRune(r rune) bool {
+	return unicode.IsControl(r) && !unicode.IsSpace(r)
+}
+
+// fetchQuote requests a quote over reportData from configfs-tsm, exiting on
+// error.
+func fetchQuote(reportData []byte) []byte {
+	var requestData [64]byte
+	copy(requestData[:], reportData)
+	if dryRun {
+		printDryRun(requestData)
+		os.Exit(exitOK)
+	}
+
+	logger.Info("fetching quote", "path", rtmr.TSMReportPath)
+	quoteData, err := rtmr.FetchQuote(requestData)
+	if err != nil {
+		fatalf(exitError, "Failed to fetch quote: %v", err)
+	}
+	logger.Info("fetched quote", "bytes", len(quoteData))
+	return quoteData
+}
+
+// printDryRun is --dry-run: it prints the configfs-tsm operations a fetch
+// would perform and the ReportData it would send, touching nothing.
+func printDryRun(reportData [64]byte) {
+	fmt.Fprintln(os.Stdout, "Dry run: no quote is requested. A fetch would:")
+	for i, op := range rtmr.TSMOperations(reportData) {
+		fmt.Fprintf(os.Stdout, "  %d. %s\n", i+1, op)
+	}
+	fmt.Fprintf(os.Stdout, "ReportData: %x\n", reportData)
+}
+
+// readQuote returns the quote bytes from path, or from stdin when path is "-".
+// A single raw V4 or V5 quote on stdin is read up to its declared length
+// only, so stdin can be a stream that stays open.
+func readQuote(path string) ([]byte, error) {
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		// Reject a large file up front; readLimited still bounds files
+		// whose size is unknown (pipes, /proc) or growing.
+		if info, err := f.Stat(); err == nil && info.Size() > maxQuoteSize {
+			return nil, fmt.Errorf("the file is %d bytes, more than --max-quote-size (%d)", info.Size(), maxQuoteSize)
+		}
+		return readLimited(f, "the file")
+	}
+	return readStdin(os.Stdin)
+}
+
+// readStdin reads a quote from r, standing in for stdin. A raw quote is read
+// with rtmr.ReadQuote, so exactly one quote is consumed. --header-only
+// reads everything instead, like a file, since a quote truncated after the
+// header is still valid input there.
+func readStdin(r io.Reader) ([]byte, error) {
+	// Bound stdin before peeking, so that the raw quote fast path is
+	// limited like everything else
+	limited := &io.LimitedReader{R: r, N: maxQuoteSize + 1}
+	stdin := bufio.NewReader(limited)
+	if b, err := stdin.Peek(2); err == nil && !multi && !base64Input && !tokenInput && !headerOnly {
+		if version := binary.LittleEndian.Uint16(b); version == 4 || version == 5 {
+			data, err := rtmr.ReadQuote(stdin)
+			if err == nil && int64(len(data)) > maxQuoteSize || errors.Is(err, rtmr.ErrTooShort) && limited.N == 0 {
+				return nil, fmt.Errorf("the input exceeds --max-quote-size (%d bytes)", maxQuoteSize)
+			}
+			return data, err
+		}
+	}
+	data, err := readLimited(stdin, "the input")
+	if err != nil {
+		return nil, fmt.Errorf("reading stdin: %v", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no quote data on stdin")
+	}
+	return data, nil
+}
+
+// readLimited reads r to the end, failing as soon as it yields more than
+// maxQuoteSize bytes rather than buffering the rest. what names the input
+// in the error.
+func readLimited(r io.Reader, what string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxQuoteSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxQuoteSize {
+		return nil, fmt.Errorf("%s exceeds --max-quote-size (%d bytes)", what, maxQuoteSize)
+	}
+	return data, nil
+}
+
+// isQuoteURL reports whether source is an http:// or https:// URL rather
+// than a path.
+func isQuoteURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// readQuoteURL fetches a quote with GET, bounded by --timeout and
+// --max-quote-size.
+func readQuoteURL(url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if resp.ContentLength > maxQuoteSize {
+		return nil, fmt.Errorf("the response is %d bytes, more than --max-quote-size (%d)", resp.ContentLength, maxQuoteSize)
+	}
+	return readLimited(resp.Body, "the response")
+}
+
+// decodeBase64Quote decodes data if it is standard base64 of a quote. The
+// encoding must round-trip exactly (ignoring whitespace and line breaks) and
+// the result must start like a quote, either a raw header with version 3-5
+// or a protobuf QuoteV4 (field 1, the header). This keeps a binary quote
+// that happens to be valid base64 from being misdetected.
+func decodeBase64Quote(data []byte) ([]byte, bool) {
+	text := strings.Join(strings.Fields(string(data)), "")
+	decoded, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(decoded) < 2 || base64.StdEncoding.EncodeToString(decoded) != text {
+		return nil, false
+	}
+	if version := binary.LittleEndian.Uint16(decoded[0:2]); version >= 3 && version <= 5 {
+		return decoded, true
+	}
+	if decoded[0] == 0x0a {
+		return decoded, true
+	}
+	return nil, false
+}
+
+// warnRawQuote reports why the structured decoders rejected a quote that is
+// being read at fixed offsets instead.
+func warnRawQuote(quoteData []byte) {
 	// Use the verify library to parse the raw quote
-	// This will validate the quote structure and extract the TD Report
+	// This will validate the quote structure and report what is wrong with it
 	opts := verify.Options{
 		GetCollateral:    false, // Don't fetch collateral for simple extraction
 		CheckRevocations: false, // Don't check CRL for simple extraction
@@ -130,234 +1099,445 @@ func extractFromRawQuote(quoteData []byte) {
 	// Parse and verify the quote structure (but not signatures/collateral)
 	err := verify.RawTdxQuote(quoteData, &opts)
 	if err != nil {
-		// If verification fails, try to extract anyway for debugging
-		fmt.Printf("Warning: Quote verification failed: %v\n", err)
-		fmt.Println("Attempting to extract RTMR values anyway...\n")
+		// If verification fails, the values are still shown for debugging
+		logger.Warn("quote failed structural validation, extracting RTMR values anyway", "err", err)
 	}
+}
 
-	// For raw quote parsing, we need to manually extract the runtime TD Report
-	// This contains the actual runtime RTMR values
-	tdReport, err := extractTDReportFromRawQuote(quoteData)
-	if err != nil {
-		log.Fatalf("Failed to extract TD Report from raw quote: %v", err)
+// printProtoText prints the full quote in multiline protobuf text format,
+// which is field-labeled and so diffs well in review.
+func printProtoText(report *rtmr.Report) {
+	if report.Quote == nil {
+		logger.Warn("--proto-text needs a QuoteV4; this quote has no protobuf form", "format", report.Format.String())
+		return
 	}
-
-	printRTMRValues(tdReport)
+	fmt.Fprintln(out, "Quote (protobuf text):")
+	fmt.Fprintln(out, "======================")
+	fmt.Fprintln(out, prototext.MarshalOptions{Multiline: true}.Format(report.Quote))
 }
 
-func extractTDReportFromRawQuote(quoteData []byte) (*TDReport, error) {
-	// This extracts the runtime TD Report from the TDX quote
-	// TDX Quote v4 structure:
-	// - Header (48 bytes)
-	// - TD Report (584 bytes) <- This is what we want (the runtime TD Report)
-	// - Signature and certificates follow...
+// printQEReport shows the Quoting Enclave's certification data, which is
+// what verification checks at the QE level before it looks at the TD.
+func printQEReport(report *rtmr.Report) {
+	fmt.Fprintln(diag, "\nQuoting Enclave Report:")
+	fmt.Fprintln(diag, "=======================")
 
-	if len(quoteData) < 632 { // 48 + 584
-		return nil, fmt.Errorf("quote too short: %d bytes", len(quoteData))
+	if c := report.CertData; c != nil {
+		fmt.Fprintf(diag, "Certification Data Type: %d (%s)\n", c.Type, rtmr.CertDataTypeName(c.Type))
+		if c.Type == rtmr.CertDataQEReport {
+			fmt.Fprintf(diag, "  Nested Type: %d (%s)\n", c.InnerType, rtmr.CertDataTypeName(c.InnerType))
+		}
+		if _, err := c.CertChain(); err != nil {
+			fmt.Fprintf(diag, "%v\n", err)
+		}
 	}
 
-	// Skip header (48 bytes) and extract the actual TD Report (584 bytes)
-	tdReportBytes := quoteData[48:632]
+	if h := report.Header; h != nil {
+		fmt.Fprintf(diag, "Header QE SVN: %d (raw %s)\n", rtmr.QESVN(h), hex.EncodeToString(h.GetQeSvn()))
+		fmt.Fprintf(diag, "Header PCE SVN: %d (raw %s)\n", rtmr.PCESVN(h), hex.EncodeToString(h.GetPceSvn()))
+	}
+
+	qe := report.QE
+	if qe == nil {
+		fmt.Fprintf(diag, "No QE certification data available (%s)\n", report.Format)
+		if report.CertData != nil && report.CertData.PCKCertChain != nil {
+			fmt.Fprintf(diag, "PCK Certificate Chain: %d certificates\n", rtmr.CountCertificates(report.CertData.PCKCertChain))
+		}
+		return
+	}
+	fmt.Fprintf(diag, "MRSIGNER: %s\n", formatHash(qe.MrSigner))
+	fmt.Fprintf(diag, "MRENCLAVE: %s\n", formatHash(qe.MrEnclave))
+	fmt.Fprintf(diag, "ISVPRODID: %d\n", qe.IsvProdID)
+	fmt.Fprintf(diag, "ISVSVN: %d\n", qe.IsvSvn)
+	fmt.Fprintf(diag, "CPUSVN: %s [%s]\n", hex.EncodeToString(qe.CpuSvn[:]), qe.CpuSvn)
+	fmt.Fprintf(diag, "QE Report Signature: %s\n", hex.EncodeToString(qe.Signature))
+	fmt.Fprintf(diag, "PCK Certificate Chain: %d certificates\n", qe.CertCount())
+	for _, fp := range qe.CAFingerprints() {
+		fmt.Fprintf(diag, "  CA SHA-256: %x\n", fp)
+	}
+}
+
+// verifyQuote runs full verification against the Intel PCS and reports the
+// outcome as an exit code. This is separate from the offline structural check
+// in validateQuoteStructure, which only checks the quote against its own key.
+func verifyQuote(report *rtmr.Report) int {
+	_, err := verifyReport(report)
+	return verifyExitCode(err)
+}
+
+// verifyReport runs full verification, logging the TCB status and writing
+// the --export-bundle file.
+func verifyReport(report *rtmr.Report) (*rtmr.VerifyResult, error) {
+	if collateralDir != "" {
+		logger.Info("verifying quote", "collateral_dir", collateralDir)
+	} else {
+		logger.Info("verifying quote", "pcs_url", pcsURL)
+	}
 
-	// Parse the raw TD Report bytes into our structure
-	// This gives us the runtime RTMR values
-	if len(tdReportBytes) != 584 {
-		return nil, fmt.Errorf("invalid TD Report size: %d bytes, expected 584", len(tdReportBytes))
+	opts := verifyOptions()
+	if exportBundle != "" {
+		opts.Collateral = rtmr.Collateral{}
 	}
+	fetchedAt := time.Now().UTC()
+	result, err := report.VerifyContext(runCtx, opts)
+	if exportBundle != "" {
+		if err := writeBundle(exportBundle, report, opts.Collateral, fetchedAt, err); err != nil {
+			fatalf(exitError, "Failed to write bundle %s: %v", exportBundle, err)
+		}
+		logger.Info("wrote verification bundle", "path", exportBundle, "files", len(opts.Collateral)+1)
+	}
+	if result != nil {
+		printTCBStatus(result)
+	}
+	return result, err
+}
 
-	// Cast the bytes directly to our TDReport structure
-	// This preserves the exact runtime RTMR values
-	tdReport := (*TDReport)(unsafe.Pointer(&tdReportBytes[0]))
+// verifyExitCode logs the outcome of verifyReport and returns its exit code.
+func verifyExitCode(err error) int {
+	if err != nil {
+		logger.Warn("verification failed", "err", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			logger.Warn("--timeout reached", "timeout", timeout)
+			return exitTimeout
+		}
+		if errors.Is(err, rtmr.ErrTCBStatus) {
+			return exitTCB
+		}
+		return exitVerify
+	}
+	logger.Info("verification passed: quote is signed by a genuine, unrevoked TDX platform")
+	return exitOK
+}
 
-	return tdReport, nil
+// printTCBStatus logs the TCB levels matched during verification.
+func printTCBStatus(result *rtmr.VerifyResult) {
+	logger.Info("TCB status",
+		"fmspc", result.FMSPC,
+		"platform", string(result.TCBStatus),
+		"tcb_date", result.TCBDate,
+		"tdx_module", string(result.TDXModuleStatus),
+		"qe", string(result.QEStatus),
+		"overall", string(result.Status()),
+		"min_accepted", string(minTCBStatus),
+		"advisories", strings.Join(result.AdvisoryIDs, ","),
+		"tcb_info_issued", result.TCBInfoIssueDate.Format(time.RFC3339))
+	if maxCollateralAge > 0 {
+		age := time.Since(result.TCBInfoIssueDate).Round(time.Second)
+		if age > maxCollateralAge {
+			logger.Warn("collateral age check: FAIL", "age", age, "max", maxCollateralAge)
+		} else {
+			logger.Info("collateral age check: PASS", "age", age, "max", maxCollateralAge)
+		}
+	}
 }
 
-func printRTMRValues(tdReport *TDReport) {
-	fmt.Println("Runtime TD Report RTMR Values:")
-	fmt.Println("==============================")
+func printRTMRValues(report *rtmr.Report) {
+	tdReport := &report.TDReport
+	if fingerprint {
+		sum := tdReport.Fingerprint()
+		fmt.Fprintln(out, formatHash(sum[:]))
+		return
+	}
+
+	if rtmrDigest {
+		sum := tdReport.RTMRDigest()
+		fmt.Fprintln(out, formatHash(sum[:]))
+		return
+	}
+
+	if jsonOutput || ndjson {
+		printRTMRJSON(tdReport)
+		return
+	}
+
+	if cborOutput {
+		printRTMRCBOR(tdReport)
+		return
+	}
+
+	if tpmStyle {
+		printTPMStyle(tdReport)
+		return
+	}
+
+	fmt.Fprintln(out, "Runtime TD Report RTMR Values:")
+	fmt.Fprintln(out, "==============================")
 
 	// Display all runtime RTMR values from the actual TD Report
-	rtmrs := [4][48]byte{tdReport.Rtmr0, tdReport.Rtmr1, tdReport.Rtmr2, tdReport.Rtmr3}
-	
-	for i, rtmr := range rtmrs {
-		// Check if RTMR is all zeros (uninitialized)
-		allZeros := true
-		for _, b := range rtmr {
-			if b != 0 {
-				allZeros = false
-				break
-			}
-		}
+	initialized := tdReport.Initialized()
 
-		if allZeros {
-			fmt.Printf("RTMR[%d]: <all zeros - uninitialized>\n", i)
+	for i, value := range tdReport.RTMRs() {
+		// Check if RTMR is all zeros (uninitialized)
+		if !initialized[i] {
+			fmt.Fprintf(out, "RTMR[%d]: <all zeros - uninitialized>\n", i)
 		} else {
-			fmt.Printf("RTMR[%d]: %x\n", i, rtmr[:])
+			fmt.Fprintf(out, "RTMR[%d]: %s\n", i, formatHash(value[:]))
 		}
 	}
 
 	// Also show MrTd from the runtime TD Report
-	fmt.Printf("\nMrTd (Trust Domain Measurement): %x\n", tdReport.MrTd[:])
-	fmt.Printf("MrConfigId: %x\n", tdReport.MrConfigId[:])
-	fmt.Printf("MrOwner: %x\n", tdReport.MrOwner[:])
-	fmt.Printf("MrOwnerConfig: %x\n", tdReport.MrOwnerConfig[:])
+	fmt.Fprintf(out, "\nMrTd (Trust Domain Measurement): %s\n", formatHash(tdReport.MrTd[:]))
+	fmt.Fprintf(out, "MrConfigId: %s\n", formatHash(tdReport.MrConfigId[:]))
+	fmt.Fprintf(out, "MrOwner: %s\n", formatHash(tdReport.MrOwner[:]))
+	fmt.Fprintf(out, "MrOwnerConfig: %s\n", formatHash(tdReport.MrOwnerConfig[:]))
+	fmt.Fprintf(out, "ReportData: %s\n", formatHash(tdReport.ReportData[:]))
+	fmt.Fprintf(out, "MrSeam (TDX module measurement): %s\n", formatHash(tdReport.MrSeam[:]))
+	fmt.Fprintf(out, "MrSignerSeam: %s\n", formatHash(tdReport.MrSignerSeam[:]))
+	if report.HasServTdHash() {
+		fmt.Fprintf(out, "ServTdHash (service TD hash): %s\n", formatHash(tdReport.ServTdHash[:]))
+	} else {
+		fmt.Fprintln(out, "ServTdHash (service TD hash): not present")
+	}
+	fmt.Fprintf(out, "TeeTcbSvn: %s [%s]\n", formatHash(tdReport.TeeTcbSvn[:]), tdReport.TCBSVN())
+	if seam := tdReport.SEAMAttributes(); seam != 0 {
+		fmt.Fprintf(out, "SeamAttributes: %s [%s]\n", formatHash(tdReport.SeamAttributes[:]), strings.Join(seam.Names(), " "))
+		logger.Warn("SEAMATTRIBUTES is not zero: the TDX module is not a production module", "bits", strings.Join(seam.Names(), " "))
+	} else {
+		fmt.Fprintf(out, "SeamAttributes: %s [none]\n", formatHash(tdReport.SeamAttributes[:]))
+	}
 
-	fmt.Println("\nRTMR Meanings:")
-	fmt.Println("RTMR[0]: Static/dynamic configuration data")
-	fmt.Println("RTMR[1]: OS kernel, boot parameters, initrd")
-	fmt.Println("RTMR[2]: Additional boot components, ACPI tables")
-	fmt.Println("RTMR[3]: Application-specific measurements")
+	attributes := tdReport.Attributes()
+	fmt.Fprintf(out, "\nTdAttributes: %s [%s]\n", formatHash(tdReport.TdAttributes[:]), strings.Join(attributes.Names(), " "))
+	if attributes.Debug() {
+		logger.Warn("DEBUG attribute is set: the host can inspect this TD, treat the quote as untrusted")
+	}
+	fmt.Fprintf(out, "Xfam: %s [%s]\n", formatHash(tdReport.Xfam[:]), strings.Join(tdReport.XFAM().Names(), " "))
+
+	fmt.Fprintln(out, "\nRTMR Meanings:")
+	for i, label := range rtmrLabels {
+		fmt.Fprintf(out, "RTMR[%d]: %s\n", i, label)
+	}
 
-	fmt.Println("\nNote: These are the RUNTIME RTMR values from the actual TD Report")
+	fmt.Fprintln(out, "\nNote: These are the RUNTIME RTMR values from the actual TD Report")
+}
+
+// printOffsets prints each TD Report field with its byte range, end
+// exclusive, and its raw hex value.
+func printOffsets(tdReport *rtmr.TDReport) {
+	fmt.Fprintln(diag, "\nTD Report Layout:")
+	fmt.Fprintln(diag, "=================")
+	for _, f := range rtmr.Layout() {
+		value, _ := tdReport.Field(f.Name)
+		fmt.Fprintf(diag, "%-14s 0x%03x-0x%03x %3d  %x\n", f.Name, f.Offset, f.End(), f.Size, value)
+	}
+}
+
+// printSchema prints the JSON Schema of the --json output.
+func printSchema() {
+	b, err := rtmr.MeasurementsSchema(hashFormat)
+	if err != nil {
+		fatalf(exitError, "Failed to generate JSON Schema: %v", err)
+	}
+	fmt.Fprintln(out, string(b))
+}
+
+// formatHash encodes a measurement for printing in the --hash-format, or
+// as prettyHex rows with --pretty.
+func formatHash(b []byte) string {
+	if pretty {
+		return prettyHex(b)
+	}
+	return hashFormat.Encode(b)
+}
+
+// Layout of --pretty rows: prettyRowBytes bytes per row, in groups of
+// prettyGroupBytes, as xxd prints them.
+const (
+	prettyRowBytes   = 16
+	prettyGroupBytes = 2
+)
+
+// prettyHex formats b as indented rows of grouped hex bytes, each led by its
+// offset. The result starts with a newline so that the rows follow the
+// label they are printed after.
+func prettyHex(b []byte) string {
+	var sb strings.Builder
+	for row := 0; row < len(b); row += prettyRowBytes {
+		fmt.Fprintf(&sb, "\n    %02x:", row)
+		end := min(row+prettyRowBytes, len(b))
+		for g := row; g < end; g += prettyGroupBytes {
+			fmt.Fprintf(&sb, " %x", b[g:min(g+prettyGroupBytes, end)])
+		}
+	}
+	return sb.String()
+}
+
+// printRTMRJSON writes the measurements as JSON, indented, or on a single
+// line with --ndjson. Each object is written with one Write call, which
+// stdout does not buffer, so a reader sees every line as soon as it is done.
+func printRTMRJSON(tdReport *rtmr.TDReport) {
+	enc := json.NewEncoder(out)
+	if !ndjson {
+		enc.SetIndent("", "  ")
+	}
+	m := tdReport.MeasurementsIn(hashFormat)
+	m.SetMeanings(rtmrLabels)
+	if err := enc.Encode(m); err != nil {
+		fatalf(exitError, "Failed to encode JSON output: %v", err)
+	}
+}
+
+// printRTMRCBOR writes the measurements as CBOR. With --multi the outputs
+// follow each other as a CBOR sequence (RFC 8742).
+func printRTMRCBOR(tdReport *rtmr.TDReport) {
+	m := tdReport.MeasurementsIn(rtmr.HashHex)
+	m.SetMeanings(rtmrLabels)
+	data, err := m.CBOR()
+	if err != nil {
+		fatalf(exitError, "Failed to encode CBOR output: %v", err)
+	}
+	if _, err := out.Write(data); err != nil {
+		fatalf(exitError, "Failed to write CBOR output: %v", err)
+	}
 }
 
 func validateQuoteStructure(quote *tdx.QuoteV4) {
-	fmt.Println("\nQuote Structure Validation:")
-	fmt.Println("===========================")
-	
 	// Check header
 	header := quote.GetHeader()
 	if header != nil {
-		fmt.Printf("Quote Version: %d\n", header.GetVersion())
-		fmt.Printf("Attestation Key Type: %d\n", header.GetAttestationKeyType())
-		fmt.Printf("TEE Type: 0x%08x\n", header.GetTeeType())
-		fmt.Printf("QE SVN: %x\n", header.GetQeSvn())
-		fmt.Printf("PCE SVN: %x\n", header.GetPceSvn())
+		printQuoteHeader(header)
 	} else {
-		fmt.Println("❌ No header found")
+		logger.Warn("no header found")
 		return
 	}
-	
+
 	// Check signed data
 	signedData := quote.GetSignedData()
 	if signedData != nil {
 		signature := signedData.GetSignature()
 		publicKey := signedData.GetEcdsaAttestationKey()
-		
-		fmt.Printf("Signature present: %t (%d bytes)\n", len(signature) > 0, len(signature))
-		fmt.Printf("Public key present: %t (%d bytes)\n", len(publicKey) > 0, len(publicKey))
-		
-		if len(signature) == 64 && len(publicKey) == 64 {
-			fmt.Println("✅ ECDSA P-256 signature format detected")
-			
-			// Try to validate signature structure (offline check)
-			validateECDSASignature(quote, signature, publicKey)
-				
+
+		logger.Debug("signed data", "signature_bytes", len(signature), "public_key_bytes", len(publicKey))
+
+		curve, hash, err := rtmr.AttestationKeyAlgorithm(quote.GetHeader().GetAttestationKeyType())
+		if err != nil {
+			logger.Warn("cannot check signature", "error", err)
 		} else {
-			fmt.Printf("❌ Unexpected signature/key sizes: sig=%d, key=%d\n", len(signature), len(publicKey))
+			logger.Debug("ECDSA signature format detected", "curve", curve.Params().Name, "hash", hash)
+
+			// Try to validate signature structure (offline check)
+			validateECDSASignature(quote, signature, publicKey, curve, hash)
 		}
-		
+
 		// Show signature and public key
-		if len(signature) > 0 {
-			fmt.Printf("Signature: %s\n", hex.EncodeToString(signature))
-		}
-		if len(publicKey) > 0 {
-			fmt.Printf("Public Key: %s\n", hex.EncodeToString(publicKey))
-		}
-		
+		logger.Debug("signature", "signature", hex.EncodeToString(signature), "public_key", hex.EncodeToString(publicKey))
+
 	} else {
-		fmt.Println("❌ No signed data found")
+		logger.Warn("no signed data found")
+	}
+}
+
+func printQuoteHeader(header *tdx.Header) {
+	logger.Debug("quote header",
+		"version", header.GetVersion(),
+		"attestation_key_type", header.GetAttestationKeyType(),
+		"tee_type", fmt.Sprintf("0x%08x", header.GetTeeType()),
+		"qe_svn", rtmr.QESVN(header),
+		"qe_svn_raw", hex.EncodeToString(header.GetQeSvn()),
+		"pce_svn", rtmr.PCESVN(header),
+		"pce_svn_raw", hex.EncodeToString(header.GetPceSvn()),
+		"qe_vendor_id", hex.EncodeToString(header.GetQeVendorId()),
+		"intel_qe", bytes.Equal(header.GetQeVendorId(), rtmr.IntelQEVendorID),
+		"user_data", hex.EncodeToString(header.GetUserData()))
+}
+
+func validateQuoteV5Structure(report *rtmr.Report) {
+	printQuoteHeader(report.Header)
+	switch report.BodyType {
+	case rtmr.BodyTypeTDX10:
+		logger.Debug("quote body", "body_type", report.BodyType, "tdx", "1.0")
+	case rtmr.BodyTypeTDX15:
+		logger.Debug("quote body", "body_type", report.BodyType, "tdx", "1.5")
 	}
-	
-	fmt.Println()
+	logger.Info("offline signature check is only implemented for QuoteV4")
 }
 
-func validateECDSASignature(quote *tdx.QuoteV4, signature, publicKey []byte) {
-	fmt.Println("\nSignature Validation (Offline Check):")
-	fmt.Println("=====================================")
-	
-	// Parse ECDSA signature (r, s values)
-	if len(signature) != 64 {
-		fmt.Printf("❌ Invalid signature length: %d (expected 64)\n", len(signature))
+func validateECDSASignature(quote *tdx.QuoteV4, signature, publicKey []byte, curve elliptic.Curve, hash crypto.Hash) {
+	// Signature is (r, s) and key is (x, y), each coordinate the curve size
+	size := (curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		logger.Warn("invalid signature length", "bytes", len(signature), "expected", 2*size)
 		return
 	}
-	
-	r := new(big.Int).SetBytes(signature[:32])
-	s := new(big.Int).SetBytes(signature[32:])
-	
-	fmt.Printf("Signature R: %s\n", hex.EncodeToString(signature[:32]))
-	fmt.Printf("Signature S: %s\n", hex.EncodeToString(signature[32:]))
-	
+
+	logger.Debug("signature components", "r", hex.EncodeToString(signature[:size]), "s", hex.EncodeToString(signature[size:]))
+
 	// Parse public key (x, y coordinates)
-	if len(publicKey) != 64 {
-		fmt.Printf("❌ Invalid public key length: %d (expected 64)\n", len(publicKey))
+	if len(publicKey) != 2*size {
+		logger.Warn("invalid public key length", "bytes", len(publicKey), "expected", 2*size)
 		return
 	}
-	
-	x := new(big.Int).SetBytes(publicKey[:32])
-	y := new(big.Int).SetBytes(publicKey[32:])
-	
-	fmt.Printf("Public Key X: %s\n", hex.EncodeToString(publicKey[:32]))
-	fmt.Printf("Public Key Y: %s\n", hex.EncodeToString(publicKey[32:]))
-	
-	// Validate public key is on P-256 curve
-	if !elliptic.P256().IsOnCurve(x, y) {
-		fmt.Println("❌ Public key is not on P-256 curve")
+
+	x := new(big.Int).SetBytes(publicKey[:size])
+	y := new(big.Int).SetBytes(publicKey[size:])
+
+	logger.Debug("public key", "x", hex.EncodeToString(publicKey[:size]), "y", hex.EncodeToString(publicKey[size:]))
+
+	// Validate public key is on the curve
+	if !curve.IsOnCurve(x, y) {
+		logger.Warn("public key is not on the curve", "curve", curve.Params().Name)
 		return
 	}
-	fmt.Println("✅ Public key is valid P-256 point")
-	
-	// Create ECDSA public key
-	ecdsaPubKey := &ecdsa.PublicKey{
-		Curve: elliptic.P256(),
-		X:     x,
-		Y:     y,
-	}
-	
+	logger.Debug("public key is a valid point", "curve", curve.Params().Name)
+
 	// Create the signed data (header + TD report)
 	signedPayload := createSignedPayload(quote)
 	if signedPayload == nil {
-		fmt.Println("❌ Could not create signed payload")
+		logger.Warn("could not create signed payload")
 		return
 	}
-	
+
 	// Hash the signed data
-	hash := sha256.Sum256(signedPayload)
-	fmt.Printf("Signed data hash: %s\n", hex.EncodeToString(hash[:]))
-	
+	h := hash.New()
+	h.Write(signedPayload)
+	logger.Debug("signed data hash", "hash", hash, "digest", hex.EncodeToString(h.Sum(nil)))
+
 	// Verify signature
-	valid := ecdsa.Verify(ecdsaPubKey, hash[:], r, s)
-	if valid {
-		fmt.Println("✅ Signature verification PASSED - Quote structure is valid!")
+	if err := rtmr.CheckSignature(quote); err == nil {
+		logger.Info("signature verification passed: quote structure is valid")
 	} else {
-		fmt.Println("❌ Signature verification FAILED")
-		fmt.Println("   This could mean:")
-		fmt.Println("   - Incorrect signed data construction")
-		fmt.Println("   - Quote has been tampered with")
-		fmt.Println("   - Different signing algorithm used")
+		// This could mean the quote has been tampered with, or that a
+		// different signing algorithm was used
+		logger.Warn("signature verification failed", "err", err)
 	}
-}
 
-func createSignedPayload(quote *tdx.QuoteV4) []byte {
-	// The signed payload typically includes the header and TD report
-	// This is a simplified version - exact format depends on TDX spec
-	
-	header := quote.GetHeader()
-	tdQuoteBody := quote.GetTdQuoteBody()
-	
-	if header == nil || tdQuoteBody == nil {
-		return nil
+	// The QE report is signed by the PCK key, which ties the attestation
+	// key to the certified platform without fetching collateral
+	if err := rtmr.CheckQEReportSignature(quote); err == nil {
+		logger.Info("QE report signature check: PASS (signed by the PCK leaf certificate key)")
+	} else {
+		logger.Warn("QE report signature check: FAIL", "err", err)
 	}
-	
-	// Convert to ABI bytes for proper formatting
-	headerBytes, err := abi.HeaderToAbiBytes(header)
-	if err != nil {
-		fmt.Printf("Warning: Could not convert header to ABI bytes: %v\n", err)
-		return nil
+
+	// The QE report in turn commits to the attestation key
+	if err := rtmr.CheckAttestationKeyBinding(quote); err == nil {
+		logger.Info("attestation key binding check: PASS (QE report data is SHA-256 of the key and QE auth data)")
+	} else {
+		logger.Warn("attestation key binding check: FAIL", "err", err)
+	}
+
+	// Together with the PCK chain, those links must certify the very key
+	// the signature was checked with, or the check above proves nothing
+	// about the platform
+	switch err := rtmr.CheckCertifiedKeyWithRoot(quote, publicKey, rootCA); {
+	case err == nil:
+		logger.Info("certified key check: PASS (the key that signed the quote is the one certified through the PCK chain to the configured root CA)")
+	case errors.Is(err, rtmr.ErrAttestationKeyMismatch):
+		logger.Warn("certified key check: FAIL: the key that signed the quote is not the attestation key the PCK-signed QE report certifies", "err", err)
+	default:
+		logger.Warn("certified key check: FAIL", "err", err)
 	}
-	
-	tdQuoteBodyBytes, err := abi.TdQuoteBodyToAbiBytes(tdQuoteBody)
+}
+
+func createSignedPayload(quote *tdx.QuoteV4) []byte {
+	// The signed payload is the header followed by the TD quote body
+	signedData, err := rtmr.SignedPayload(quote)
 	if err != nil {
-		fmt.Printf("Warning: Could not convert TD quote body to ABI bytes: %v\n", err)
+		logger.Warn("could not build signed payload", "err", err)
 		return nil
 	}
-	
-	// Concatenate header + TD report (this is what gets signed)
-	signedData := make([]byte, 0, len(headerBytes)+len(tdQuoteBodyBytes))
-	signedData = append(signedData, headerBytes...)
-	signedData = append(signedData, tdQuoteBodyBytes...)
-	
-	fmt.Printf("Signed payload length: %d bytes\n", len(signedData))
-	
+
+	logger.Debug("signed payload", "bytes", len(signedData))
+
 	return signedData
-}
\ No newline at end of file
+}
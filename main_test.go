@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestReadStdinMatchesPath checks that a quote read from stdin is the same
+// as the one read from a file, including the --max-quote-size bound and a
+// truncated quote under --header-only.
+func TestReadStdinMatchesPath(t *testing.T) {
+	raw, err := os.ReadFile("rtmr/testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(h bool, size int64) { headerOnly, maxQuoteSize = h, size }(headerOnly, maxQuoteSize)
+
+	for _, tc := range []struct {
+		name       string
+		data       []byte
+		headerOnly bool
+		maxSize    int64
+		wantErr    bool
+	}{
+		{"header only, truncated body", raw[:100], true, defaultMaxQuoteSize, false},
+		{"too large", raw, false, 1000, true},
+		{"too large, header only", raw, true, 1000, true},
+	} {
+		headerOnly, maxQuoteSize = tc.headerOnly, tc.maxSize
+		path := filepath.Join(t.TempDir(), "quote.dat")
+		if err := os.WriteFile(path, tc.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		fromPath, pathErr := readQuote(path)
+		fromStdin, stdinErr := readStdin(bytes.NewReader(tc.data))
+		if (pathErr != nil) != tc.wantErr || (stdinErr != nil) != tc.wantErr {
+			t.Errorf("%s: path error = %v, stdin error = %v, want error %v", tc.name, pathErr, stdinErr, tc.wantErr)
+			continue
+		}
+		if !bytes.Equal(fromPath, fromStdin) {
+			t.Errorf("%s: stdin read %d bytes, path %d", tc.name, len(fromStdin), len(fromPath))
+		}
+	}
+}
+
+// TestCheckServTdHashV4 checks that --expected-servtd fails for a V4 quote,
+// which carries no SERVTD_HASH, even when the expected value is zero.
+func TestCheckServTdHashV4(t *testing.T) {
+	raw, err := os.ReadFile("rtmr/testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := rtmr.ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(w io.Writer) { diag = w }(diag)
+	var buf bytes.Buffer
+	diag = &buf
+
+	if checkServTdHash(report, make([]byte, 48)) {
+		t.Error("checkServTdHash passed for a V4 quote")
+	}
+	if !strings.Contains(buf.String(), "quote does not carry SERVTD_HASH") {
+		t.Errorf("checkServTdHash output = %q", buf.String())
+	}
+}
+
+// TestMeasurementsProto checks that the gRPC measurements carry the same
+// values as the --json output.
+func TestMeasurementsProto(t *testing.T) {
+	raw, err := os.ReadFile("rtmr/testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := rtmr.ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := report.Measurements()
+	got := measurementsProto(&report.TDReport)
+	for _, f := range []struct {
+		name      string
+		got, want string
+	}{
+		{"ServTdHash", hex.EncodeToString(got.ServTdHash), want.ServTdHash},
+		{"SeamAttributes", hex.EncodeToString(got.SeamAttributes), want.SeamAttributes},
+		{"SeamAttributesFlags", strings.Join(got.SeamAttributesFlags, " "), strings.Join(want.SeamAttributesFlags, " ")},
+		{"InitializedMask", fmt.Sprint(got.InitializedMask), fmt.Sprint(want.InitializedMask)},
+		{"MrTd", hex.EncodeToString(got.MrTd), want.MrTd},
+	} {
+		if f.got != f.want {
+			t.Errorf("%s = %q, want %q", f.name, f.got, f.want)
+		}
+	}
+}
+
+// TestHandleVerifyBodyError checks that a request whose body cannot be read
+// is counted as a parse failure without adding a duration sample.
+func TestHandleVerifyBodyError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(quotesProcessed, verificationFailures, verificationDuration)
+	sample := func() (processed, parseFailures float64, durations uint64) {
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range families {
+			for _, m := range mf.GetMetric() {
+				switch mf.GetName() {
+				case "tdx_rtmr_quotes_processed_total":
+					processed = m.GetCounter().GetValue()
+				case "tdx_rtmr_verification_failures_total":
+					if m.GetLabel()[0].GetValue() == reasonParse {
+						parseFailures = m.GetCounter().GetValue()
+					}
+				case "tdx_rtmr_verification_duration_seconds":
+					durations = m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return
+	}
+
+	defer func(size int64) { maxQuoteSize = size }(maxQuoteSize)
+	maxQuoteSize = 16
+	processed, parseFailures, durations := sample()
+	w := httptest.NewRecorder()
+	handleVerify(w, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(make([]byte, 64))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	p, f, d := sample()
+	if p != processed+1 || f != parseFailures+1 || d != durations {
+		t.Errorf("processed %v -> %v, parse failures %v -> %v, duration samples %d -> %d; want +1, +1, unchanged", processed, p, parseFailures, f, durations, d)
+	}
+}
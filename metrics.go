@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Failure reasons reported in verifyResponse.Reason and as the reason label
+// of verificationFailures.
+const (
+	reasonParse             = "parse_error"
+	reasonUnsupportedFormat = "unsupported_format"
+	reasonSignature         = "signature_fail"
+	reasonCollateralFetch   = "collateral_fetch_fail"
+	reasonTCBOutOfDate      = "tcb_out_of_date"
+	reasonVerification      = "verification_fail"
+)
+
+var (
+	quotesProcessed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tdx_rtmr_quotes_processed_total",
+		Help: "Quotes received on /verify.",
+	})
+	verificationSuccesses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tdx_rtmr_verification_successes_total",
+		Help: "Quotes that passed verification.",
+	})
+	verificationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tdx_rtmr_verification_failures_total",
+		Help: "Quotes that failed verification, by reason.",
+	}, []string{"reason"})
+	verificationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tdx_rtmr_verification_duration_seconds",
+		Help:    "Time taken to process a quote on /verify, including any collateral fetches.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(quotesProcessed, verificationSuccesses, verificationFailures, verificationDuration)
+	// Start every reason at zero so alerts on rate() see the series from the
+	// first scrape.
+	for _, reason := range []string{reasonParse, reasonUnsupportedFormat, reasonSignature, reasonCollateralFetch, reasonTCBOutOfDate, reasonVerification} {
+		verificationFailures.WithLabelValues(reason)
+	}
+}
+
+// recordVerification updates the metrics for one processed quote that took
+// seconds to verify.
+func recordVerification(resp *verifyResponse, seconds float64) {
+	countVerification(resp)
+	verificationDuration.Observe(seconds)
+}
+
+// countVerification updates the counters for one processed quote without
+// adding a duration sample. It is used for requests rejected before
+// verification started, whose zero durations would skew the histogram.
+func countVerification(resp *verifyResponse) {
+	quotesProcessed.Inc()
+	if resp.Valid {
+		verificationSuccesses.Inc()
+		return
+	}
+	verificationFailures.WithLabelValues(resp.Reason).Inc()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// oneline is --oneline: print one grep-able summary line per quote.
+var oneline bool
+
+// onelinePrefix is how many bytes of each measurement --oneline prints.
+const onelinePrefix = 6
+
+// printOneline prints report as a single line of key=value fields, e.g.
+//
+//	tdx v4 tee=0x81 mrtd=6363b8043668.. rtmr0=2927da70461c.. ... rtmr3=000000000000.. valid=true
+//
+// Measurements are cut to a 12-digit hex prefix, regardless of
+// --hash-format, so the keys stay searchable in high-volume logs. valid is
+// the offline signature check of a QuoteV4 (see rtmr.CheckSignature) and
+// "unknown" for formats it does not apply to; full verification is not
+// part of it.
+func printOneline(report *rtmr.Report) {
+	fields := []string{"tdx"}
+	if h := report.Header; h != nil {
+		fields = append(fields, fmt.Sprintf("v%d", h.GetVersion()), fmt.Sprintf("tee=0x%x", h.GetTeeType()))
+	}
+	short := func(name string, value []byte) string {
+		return fmt.Sprintf("%s=%s..", name, hex.EncodeToString(value[:onelinePrefix]))
+	}
+	fields = append(fields, short("mrtd", report.MrTd[:]))
+	for i, value := range report.RTMRs() {
+		fields = append(fields, short(fmt.Sprintf("rtmr%d", i), value[:]))
+	}
+
+	valid := "unknown"
+	if report.Quote != nil {
+		valid = fmt.Sprint(rtmr.CheckSignature(report.Quote) == nil)
+	}
+	fields = append(fields, "valid="+valid)
+	fmt.Fprintln(out, strings.Join(fields, " "))
+}
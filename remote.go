@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// Remote verification options of the verify subcommand: --remote-verify and
+// the schema of its request and response. The defaults match the POST
+// /verify endpoint of "tdx-gcp-rtmr serve".
+var (
+	remoteVerifyURL    string
+	remoteRequestField string
+	remoteVerdictField string
+	remotePassValue    string
+)
+
+func addRemoteVerifyFlags(fs *flag.FlagSet) {
+	fs.StringVar(&remoteVerifyURL, "remote-verify", "", "Also POST the quote to this remote attestation service and report whether its verdict agrees with local verification")
+	fs.StringVar(&remoteRequestField, "remote-request-field", "", "Send the quote as base64 in this field of a JSON object instead of as the raw request body")
+	fs.StringVar(&remoteVerdictField, "remote-verdict-field", "valid", "Dotted path of the verdict in the remote JSON response, e.g. result.status")
+	fs.StringVar(&remotePassValue, "remote-pass-value", "true", "Verdict value (compared without regard to case) that means the remote service accepted the quote")
+}
+
+// remoteVerdict posts quoteData to --remote-verify and returns the verdict
+// value found at --remote-verdict-field, as text, and whether it is
+// --remote-pass-value. Responses are bounded by --max-quote-size.
+func remoteVerdict(quoteData []byte) (string, bool, error) {
+	body, contentType := quoteData, "application/octet-stream"
+	if remoteRequestField != "" {
+		var err error
+		body, err = json.Marshal(map[string]string{remoteRequestField: base64.StdEncoding.EncodeToString(quoteData)})
+		if err != nil {
+			return "", false, err
+		}
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(runCtx, http.MethodPost, remoteVerifyURL, bytes.NewReader(body))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	data, err := readLimited(resp.Body, "the remote response")
+	if err != nil {
+		return "", false, err
+	}
+
+	// A service may report a rejected quote with an error status and a
+	// verdict, so the status only matters when there is no verdict.
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return "", false, fmt.Errorf("POST %s: %s, response is not JSON: %v", remoteVerifyURL, resp.Status, err)
+	}
+	value, ok := lookupField(decoded, remoteVerdictField)
+	if !ok {
+		return "", false, fmt.Errorf("POST %s: %s, response has no %q field", remoteVerifyURL, resp.Status, remoteVerdictField)
+	}
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case bool, float64:
+		text = fmt.Sprint(v)
+	default:
+		return "", false, fmt.Errorf("POST %s: verdict field %q is not a string, boolean or number", remoteVerifyURL, remoteVerdictField)
+	}
+	return text, strings.EqualFold(text, remotePassValue), nil
+}
+
+// lookupField follows a dotted path of object keys through decoded JSON.
+func lookupField(v any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// verifyWithRemote verifies report locally and quoteData with the
+// --remote-verify service and reports whether the two verdicts agree. It
+// returns exitDisagree when they do not, otherwise the exit code of local
+// verification.
+func verifyWithRemote(report *rtmr.Report, quoteData []byte) int {
+	_, localErr := verifyReport(report)
+	code := verifyExitCode(localErr)
+
+	logger.Info("verifying quote remotely", "url", remoteVerifyURL)
+	remoteValue, remotePass, err := remoteVerdict(quoteData)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fatalf(exitTimeout, "Remote verification did not finish within --timeout: %v", err)
+	}
+	if err != nil {
+		fatalf(exitError, "Remote verification failed: %v", err)
+	}
+
+	fmt.Fprintln(diag, "\nRemote Verification Comparison:")
+	fmt.Fprintln(diag, "===============================")
+	fmt.Fprintf(diag, "Remote: %s\n", remoteVerifyURL)
+	fmt.Fprintf(diag, "Local verdict: %s\n", passFail(localErr == nil))
+	if localErr != nil {
+		fmt.Fprintf(diag, "  %v\n", localErr)
+	}
+	fmt.Fprintf(diag, "Remote verdict: %s (%s = %s)\n", passFail(remotePass), remoteVerdictField, remoteValue)
+	if remotePass != (localErr == nil) {
+		fmt.Fprintf(diag, "Verdicts: %s\n", verdict("DISAGREE", false))
+		logger.Warn("local and remote verification disagree", "local", localErr == nil, "remote", remotePass)
+		return exitDisagree
+	}
+	fmt.Fprintf(diag, "Verdicts: %s\n", verdict("AGREE", true))
+	return code
+}
+
+// passFail is the verdict text of a pass/fail result.
+func passFail(ok bool) string {
+	if ok {
+		return verdict("PASS", true)
+	}
+	return verdict("FAIL", false)
+}
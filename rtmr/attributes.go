@@ -0,0 +1,118 @@
+package rtmr
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"strconv"
+)
+
+// TDAttributes is the TD_ATTRIBUTES field of a TD Report, as a
+// little-endian bit field (Intel TDX module ABI spec, "TD_ATTRIBUTES").
+type TDAttributes uint64
+
+// TD_ATTRIBUTES bits.
+const (
+	TDAttrDebug         TDAttributes = 1 << 0
+	TDAttrSeptVEDisable TDAttributes = 1 << 28
+	TDAttrMigratable    TDAttributes = 1 << 29
+	TDAttrPKS           TDAttributes = 1 << 30
+	TDAttrKL            TDAttributes = 1 << 31
+	TDAttrPerfmon       TDAttributes = 1 << 63
+)
+
+var tdAttributeNames = map[TDAttributes]string{
+	TDAttrDebug:         "DEBUG",
+	TDAttrSeptVEDisable: "SEPT_VE_DISABLE",
+	TDAttrMigratable:    "MIGRATABLE",
+	TDAttrPKS:           "PKS",
+	TDAttrKL:            "KL",
+	TDAttrPerfmon:       "PERFMON",
+}
+
+// Debug reports whether the TD is debuggable. The host can read and modify
+// a debug TD's memory and registers, so its quote should not be trusted.
+func (a TDAttributes) Debug() bool {
+	return a&TDAttrDebug != 0
+}
+
+// Names returns the names of the set bits in bit order. Bits without a name
+// are reported as "BIT<n>".
+func (a TDAttributes) Names() []string {
+	return bitNames(uint64(a), func(bit uint64) string { return tdAttributeNames[TDAttributes(bit)] })
+}
+
+// XFAM is the XFAM field of a TD Report: the extended features (XCR0 and
+// IA32_XSS bits) the TD is allowed to use.
+type XFAM uint64
+
+// xfamNames are the XSAVE state components by bit position.
+var xfamNames = []string{
+	0:  "X87",
+	1:  "SSE",
+	2:  "AVX",
+	3:  "MPX_BNDREGS",
+	4:  "MPX_BNDCSR",
+	5:  "AVX512_OPMASK",
+	6:  "AVX512_ZMM_HI256",
+	7:  "AVX512_HI16_ZMM",
+	8:  "PT",
+	9:  "PKRU",
+	10: "PASID",
+	11: "CET_U",
+	12: "CET_S",
+	13: "HDC",
+	14: "UINTR",
+	15: "LBR",
+	16: "HWP",
+	17: "AMX_TILECFG",
+	18: "AMX_TILEDATA",
+}
+
+// Names returns the names of the enabled features in bit order.
+func (x XFAM) Names() []string {
+	return bitNames(uint64(x), func(bit uint64) string {
+		if i := bits.TrailingZeros64(bit); i < len(xfamNames) {
+			return xfamNames[i]
+		}
+		return ""
+	})
+}
+
+// SEAMAttributes is the SEAMATTRIBUTES field of a TD Report: attributes of
+// the TDX module itself rather than of the TD. No bits are defined for
+// production modules, which report all zeros.
+type SEAMAttributes uint64
+
+// Names returns the set bits as "BIT<n>", in bit order.
+func (a SEAMAttributes) Names() []string {
+	return bitNames(uint64(a), func(uint64) string { return "" })
+}
+
+func bitNames(v uint64, name func(bit uint64) string) []string {
+	names := []string{}
+	for v != 0 {
+		bit := v & -v
+		v &^= bit
+		if n := name(bit); n != "" {
+			names = append(names, n)
+		} else {
+			names = append(names, "BIT"+strconv.Itoa(bits.TrailingZeros64(bit)))
+		}
+	}
+	return names
+}
+
+// Attributes returns the decoded TD_ATTRIBUTES field.
+func (r *TDReport) Attributes() TDAttributes {
+	return TDAttributes(binary.LittleEndian.Uint64(r.TdAttributes[:]))
+}
+
+// SEAMAttributes returns the decoded SEAMATTRIBUTES field.
+func (r *TDReport) SEAMAttributes() SEAMAttributes {
+	return SEAMAttributes(binary.LittleEndian.Uint64(r.SeamAttributes[:]))
+}
+
+// XFAM returns the decoded XFAM field.
+func (r *TDReport) XFAM() XFAM {
+	return XFAM(binary.LittleEndian.Uint64(r.Xfam[:]))
+}
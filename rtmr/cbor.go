@@ -0,0 +1,40 @@
+package rtmr
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR encodes m, which must be in HashHex, as a CBOR map with the same
+// keys and structure as its JSON encoding, except that byte values are CBOR
+// byte strings instead of encoded text. Map keys are sorted as in RFC 8949
+// core deterministic encoding.
+func (m Measurements) CBOR() ([]byte, error) {
+	v := reflect.ValueOf(m)
+	t := v.Type()
+
+	fields := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		// Strings in Measurements are all hex-encoded bytes (see
+		// MeasurementsSchema).
+		if s, ok := v.Field(i).Interface().(string); ok {
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = b
+			continue
+		}
+		fields[name] = v.Field(i).Interface()
+	}
+
+	em, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+	return em.Marshal(fields)
+}
@@ -0,0 +1,39 @@
+package rtmr
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestMeasurementsCBOR(t *testing.T) {
+	var r TDReport
+	r.Rtmr0[0], r.Rtmr0[47] = 0x12, 0x34
+	r.TeeTcbSvn[0] = 3
+
+	data, err := r.MeasurementsIn(HashHex).CBOR()
+	if err != nil {
+		t.Fatalf("CBOR() error = %v", err)
+	}
+
+	var got map[string]any
+	if err := cbor.Unmarshal(data, &got); err != nil {
+		t.Fatalf("cbor.Unmarshal() error = %v", err)
+	}
+	rtmr0, ok := got["rtmr0"].([]byte)
+	if !ok {
+		t.Fatalf("rtmr0 is %T, want a byte string", got["rtmr0"])
+	}
+	if !bytes.Equal(rtmr0, r.Rtmr0[:]) {
+		t.Errorf("rtmr0 = %x, want %x", rtmr0, r.Rtmr0)
+	}
+	decoded, ok := got["teeTcbSvnDecoded"].(map[any]any)
+	if !ok || decoded["tdxModuleSvn"] != uint64(3) {
+		t.Errorf("teeTcbSvnDecoded = %v, want tdxModuleSvn 3", got["teeTcbSvnDecoded"])
+	}
+	if want := reflect.TypeOf(Measurements{}).NumField(); len(got) != want {
+		t.Errorf("CBOR map has %d keys, want one per Measurements field (%d)", len(got), want)
+	}
+}
@@ -0,0 +1,154 @@
+package rtmr
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/go-tdx-guest/proto/tdx"
+)
+
+// Certification data types of the quote's signed data (Intel DCAP quote
+// format). TDX quotes normally carry the QE report (type 6), which nests
+// the PCK certificate chain (type 5).
+const (
+	CertDataPPIDCleartext    = 1
+	CertDataPPIDRSA2048      = 2
+	CertDataPPIDRSA3072      = 3
+	CertDataPCKCleartext     = 4
+	CertDataPCKCertChain     = 5
+	CertDataQEReport         = 6
+	CertDataPlatformManifest = 7
+)
+
+var certDataTypeNames = map[uint16]string{
+	CertDataPPIDCleartext:    "PPID in cleartext",
+	CertDataPPIDRSA2048:      "PPID encrypted with RSA-2048-OAEP",
+	CertDataPPIDRSA3072:      "PPID encrypted with RSA-3072-OAEP",
+	CertDataPCKCleartext:     "PCK leaf certificate",
+	CertDataPCKCertChain:     "PCK certificate chain",
+	CertDataQEReport:         "QE report certification data",
+	CertDataPlatformManifest: "platform manifest",
+}
+
+// CertDataTypeName describes a certification data type.
+func CertDataTypeName(t uint16) string {
+	if name, ok := certDataTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// certDataHeaderSize is the type (2 bytes) and size (4 bytes) that lead
+// certification data, and qeCertDataFixedSize the QE report and its
+// signature that lead type 6 data.
+const (
+	certDataHeaderSize  = 6
+	qeCertDataFixedSize = 384 + 64
+)
+
+// CertificationData is the certification data of a quote: its type and,
+// when it is or nests a PCK certificate chain, the PEM chain.
+type CertificationData struct {
+	Type uint16
+	// InnerType is the type of the data nested in QE report certification
+	// data (type 6), normally 5; it is 0 for other types.
+	InnerType uint16
+	// PCKCertChain is the PEM-encoded PCK chain (PCK, intermediate, root),
+	// or nil when the data carries none.
+	PCKCertChain []byte
+}
+
+// CertChain returns the PEM PCK certificate chain, or an error naming the
+// type when the quote carries another kind of certification data (a PPID
+// for instance), with which the chain must be fetched from the PCS.
+func (c *CertificationData) CertChain() ([]byte, error) {
+	if c.PCKCertChain != nil {
+		return c.PCKCertChain, nil
+	}
+	t := c.Type
+	if t == CertDataQEReport {
+		t = c.InnerType
+	}
+	return nil, fmt.Errorf("unsupported cert data type %d (%s); only a PCK certificate chain (type 5) is supported", t, CertDataTypeName(t))
+}
+
+// certificationDataFromProto summarizes the certification data of a parsed
+// QuoteV4. The abi package only accepts type 6 nesting type 5, but a
+// protobuf quote can hold anything.
+func certificationDataFromProto(quote *tdx.QuoteV4) *CertificationData {
+	certData := quote.GetSignedData().GetCertificationData()
+	if certData == nil {
+		return nil
+	}
+	c := &CertificationData{Type: uint16(certData.GetCertificateDataType())}
+	if c.Type == CertDataQEReport {
+		chain := certData.GetQeReportCertificationData().GetPckCertificateChainData()
+		c.InnerType = uint16(chain.GetCertificateDataType())
+		if c.InnerType == CertDataPCKCertChain {
+			c.PCKCertChain = chain.GetPckCertChain()
+		}
+	}
+	return c
+}
+
+// parseCertificationData decodes the certification data of the raw V4 or
+// V5 quote in data. The signed data holds the quote signature and the
+// attestation key (64 bytes each), then the certification data.
+func parseCertificationData(data []byte) (*CertificationData, error) {
+	sizeOffset, err := signedDataSizeOffset(data)
+	if err != nil {
+		return nil, err
+	}
+	start := sizeOffset + signedDataSizeLen
+	if len(data) < start {
+		return nil, fmt.Errorf("quote truncated before the signed data")
+	}
+	size := int(binary.LittleEndian.Uint32(data[sizeOffset:]))
+	if len(data)-start < size {
+		return nil, fmt.Errorf("signed data is %d bytes, expected %d", len(data)-start, size)
+	}
+	signed := data[start : start+size]
+	if len(signed) < 128 {
+		return nil, fmt.Errorf("signed data too short for certification data: %d bytes", len(signed))
+	}
+
+	t, body, err := splitCertData(signed[128:])
+	if err != nil {
+		return nil, err
+	}
+	c := &CertificationData{Type: t}
+	switch t {
+	case CertDataPCKCertChain:
+		c.PCKCertChain = body
+	case CertDataQEReport:
+		if len(body) < qeCertDataFixedSize+2 {
+			return nil, fmt.Errorf("QE report certification data too short: %d bytes", len(body))
+		}
+		authEnd := qeCertDataFixedSize + 2 + int(binary.LittleEndian.Uint16(body[qeCertDataFixedSize:]))
+		if len(body) < authEnd {
+			return nil, fmt.Errorf("QE authentication data runs past the certification data")
+		}
+		inner, innerBody, err := splitCertData(body[authEnd:])
+		if err != nil {
+			return nil, fmt.Errorf("QE report certification data: %v", err)
+		}
+		c.InnerType = inner
+		if inner == CertDataPCKCertChain {
+			c.PCKCertChain = innerBody
+		}
+	}
+	return c, nil
+}
+
+// splitCertData splits certification data into its type and its data.
+func splitCertData(b []byte) (uint16, []byte, error) {
+	if len(b) < certDataHeaderSize {
+		return 0, nil, fmt.Errorf("certification data too short: %d bytes", len(b))
+	}
+	t := binary.LittleEndian.Uint16(b[0:2])
+	size := int(binary.LittleEndian.Uint32(b[2:6]))
+	if len(b)-certDataHeaderSize < size {
+		return 0, nil, fmt.Errorf("certification data type %d declares %d bytes, only %d present", t, size, len(b)-certDataHeaderSize)
+	}
+	return t, b[certDataHeaderSize : certDataHeaderSize+size], nil
+}
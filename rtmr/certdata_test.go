@@ -0,0 +1,63 @@
+package rtmr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCertificationData(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromProto := report.CertData
+	fromRaw, err := parseCertificationData(raw)
+	if err != nil {
+		t.Fatalf("parseCertificationData() error = %v", err)
+	}
+	for _, c := range []*CertificationData{fromProto, fromRaw} {
+		if c == nil || c.Type != CertDataQEReport || c.InnerType != CertDataPCKCertChain {
+			t.Fatalf("certification data = %+v, want type 6 nesting type 5", c)
+		}
+		chain, err := c.CertChain()
+		if err != nil || CountCertificates(chain) != 3 {
+			t.Errorf("CertChain() = %d certificates, %v; want 3", CountCertificates(chain), err)
+		}
+	}
+	if !bytes.Equal(fromRaw.PCKCertChain, fromProto.PCKCertChain) {
+		t.Error("raw and protobuf PCK chains differ")
+	}
+	certs, err := ParseCertificates(fromRaw.PCKCertChain)
+	if err != nil || len(certs) != 3 {
+		t.Fatalf("ParseCertificates() = %d certificates, %v; want 3", len(certs), err)
+	}
+	if !strings.Contains(certs[0].Subject.CommonName, "PCK Certificate") {
+		t.Errorf("leaf subject = %q, want the PCK certificate first", certs[0].Subject)
+	}
+
+	// A quote with PPID certification data instead of the QE report is not
+	// understood by the abi package, but its type must still be reported.
+	typeOffset := tdReportEnd + signedDataSizeLen + 128
+	ppid := bytes.Clone(raw)
+	binary.LittleEndian.PutUint16(ppid[typeOffset:], CertDataPPIDRSA3072)
+	report, err = ParseQuote(ppid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.CertData == nil || report.CertData.Type != CertDataPPIDRSA3072 {
+		t.Fatalf("CertData = %+v, want type 3", report.CertData)
+	}
+	if _, err := report.CertData.CertChain(); err == nil || !strings.Contains(err.Error(), "unsupported cert data type 3") {
+		t.Errorf("CertChain() error = %v, want unsupported cert data type 3", err)
+	}
+	if _, err := report.Verify(VerifyOptions{CollateralDir: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "unsupported cert data type 3") {
+		t.Errorf("Verify() error = %v, want unsupported cert data type 3", err)
+	}
+}
@@ -0,0 +1,112 @@
+package rtmr
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MeasurementChecker is an additional check on a parsed quote, for policy
+// that does not fit a Policy file. Check returns nil when the quote passes
+// and an error describing the failure otherwise.
+type MeasurementChecker interface {
+	Check(r *Report) error
+}
+
+// CheckerFunc adapts an ordinary function to a MeasurementChecker.
+type CheckerFunc func(r *Report) error
+
+// Check calls f(r).
+func (f CheckerFunc) Check(r *Report) error {
+	return f(r)
+}
+
+var (
+	checkersMu sync.RWMutex
+	checkers   = map[string]MeasurementChecker{}
+)
+
+// RegisterChecker makes a checker available by name, like database/sql
+// drivers: a program importing this package registers its own checks,
+// usually from an init function, and selects them with RunCheckers. It
+// panics if the name is empty, already registered, or checker is nil.
+func RegisterChecker(name string, checker MeasurementChecker) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	if name == "" || checker == nil {
+		panic("rtmr: RegisterChecker with an empty name or nil checker")
+	}
+	if _, dup := checkers[name]; dup {
+		panic("rtmr: RegisterChecker called twice for " + name)
+	}
+	checkers[name] = checker
+}
+
+// Checkers returns the names of the registered checkers, sorted.
+func Checkers() []string {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckResult is the outcome of one named checker; Err is nil on pass.
+type CheckResult struct {
+	Name string
+	Err  error
+}
+
+// RunCheckers runs the named checkers on r, in the given order. It fails
+// before running any of them if a name is not registered.
+func RunCheckers(r *Report, names []string) ([]CheckResult, error) {
+	checkersMu.RLock()
+	selected := make([]MeasurementChecker, len(names))
+	for i, name := range names {
+		if selected[i] = checkers[name]; selected[i] == nil {
+			checkersMu.RUnlock()
+			return nil, fmt.Errorf("unknown check %q (registered: %v)", name, Checkers())
+		}
+	}
+	checkersMu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	for i, c := range selected {
+		results[i] = CheckResult{Name: names[i], Err: c.Check(r)}
+	}
+	return results, nil
+}
+
+// The built-in checkers.
+func init() {
+	RegisterChecker("no-debug", CheckerFunc(func(r *Report) error {
+		if r.TDReport.Attributes().Debug() {
+			return errors.New("TD_ATTRIBUTES has DEBUG set")
+		}
+		return nil
+	}))
+	RegisterChecker("sept-ve-disable", CheckerFunc(func(r *Report) error {
+		if r.TDReport.Attributes()&TDAttrSeptVEDisable == 0 {
+			return errors.New("TD_ATTRIBUTES does not have SEPT_VE_DISABLE set")
+		}
+		return nil
+	}))
+	RegisterChecker("sha384-rtmrs", CheckerFunc(func(r *Report) error {
+		for i, padded := range r.TDReport.SHA256Padded() {
+			if padded {
+				return fmt.Errorf("RTMR%d looks like a SHA-256 digest padded to 48 bytes", i)
+			}
+		}
+		return nil
+	}))
+	RegisterChecker("signature", CheckerFunc(func(r *Report) error {
+		if r.Quote == nil {
+			return fmt.Errorf("offline signature check needs a QuoteV4, got %s", r.Format)
+		}
+		return CheckSignature(r.Quote)
+	}))
+}
@@ -0,0 +1,42 @@
+package rtmr
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestRunCheckers(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterChecker("test-zero-rtmr3", CheckerFunc(func(r *Report) error {
+		if r.Rtmr3 != ([48]byte{}) {
+			return errors.New("RTMR3 is extended")
+		}
+		return nil
+	}))
+	if !slices.Contains(Checkers(), "test-zero-rtmr3") {
+		t.Fatalf("Checkers() = %v, missing the registered check", Checkers())
+	}
+
+	results, err := RunCheckers(report, []string{"no-debug", "signature", "test-zero-rtmr3", "sept-ve-disable"})
+	if err != nil {
+		t.Fatalf("RunCheckers() error = %v", err)
+	}
+	for i, wantPass := range []bool{true, true, true, false} {
+		if (results[i].Err == nil) != wantPass {
+			t.Errorf("check %s: err = %v, want pass %v", results[i].Name, results[i].Err, wantPass)
+		}
+	}
+
+	if _, err := RunCheckers(report, []string{"no-such-check"}); err == nil {
+		t.Error("RunCheckers() accepted an unregistered check")
+	}
+}
@@ -0,0 +1,132 @@
+package rtmr
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files read from a collateral directory (VerifyOptions.CollateralDir). The
+// .json and .der files are the bodies of the Intel PCS responses named in the
+// comments; the *_issuer_chain.pem files hold the PEM certificate chain the
+// PCS returns in a response header, URL-decoded.
+const (
+	// TCB info: GET /tdx/certification/v4/tcb?fmspc=<fmspc>, and its
+	// TCB-Info-Issuer-Chain header.
+	CollateralTCBInfo      = "tcb_info.json"
+	CollateralTCBInfoChain = "tcb_info_issuer_chain.pem"
+
+	// QE identity: GET /tdx/certification/v4/qe/identity, and its
+	// SGX-Enclave-Identity-Issuer-Chain header.
+	CollateralQEIdentity      = "qe_identity.json"
+	CollateralQEIdentityChain = "qe_identity_issuer_chain.pem"
+
+	// PCK CRL: GET /sgx/certification/v4/pckcrl?ca=platform&encoding=der,
+	// and its SGX-PCK-CRL-Issuer-Chain header.
+	CollateralPCKCRL      = "pck_crl.der"
+	CollateralPCKCRLChain = "pck_crl_issuer_chain.pem"
+
+	// Root CA CRL: https://certificates.trustedservices.intel.com/IntelSGXRootCA.der
+	CollateralRootCRL = "root_crl.der"
+)
+
+// Response headers carrying the issuer chains, as the verify package looks
+// them up.
+const (
+	tcbInfoIssuerChainHeader    = "Tcb-Info-Issuer-Chain"
+	qeIdentityIssuerChainHeader = "Sgx-Enclave-Identity-Issuer-Chain"
+	pckCRLIssuerChainHeader     = "Sgx-Pck-Crl-Issuer-Chain"
+)
+
+// Collateral holds collateral files by their CollateralDir name, as recorded
+// during verification when VerifyOptions.Collateral is set. Written to a
+// directory, it can be used as a CollateralDir to repeat the verification.
+type Collateral map[string][]byte
+
+// add records a collateral response under its file names. Responses for
+// URLs that do not map to a collateral file are ignored.
+func (c Collateral) add(rawURL string, header map[string][]string, body []byte) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	name, chain, chainHeader, _ := collateralFiles(u)
+	if name == "" {
+		return
+	}
+	c[name] = body
+	if chain == "" {
+		return
+	}
+	for key, values := range header {
+		if !strings.EqualFold(key, chainHeader) || len(values) == 0 {
+			continue
+		}
+		if pem, err := url.QueryUnescape(values[0]); err == nil {
+			c[chain] = []byte(pem)
+		}
+	}
+}
+
+// collateralFiles maps a collateral request to the file holding its body,
+// the file holding its issuer chain and the response header that carries
+// the chain, with a description for errors. The chain names are empty for
+// the root CA CRL, and all names are empty for an unknown request.
+func collateralFiles(u *url.URL) (body, chain, header, what string) {
+	switch {
+	case strings.HasSuffix(u.Path, "/tcb"):
+		return CollateralTCBInfo, CollateralTCBInfoChain, tcbInfoIssuerChainHeader,
+			"TCB info for FMSPC " + u.Query().Get("fmspc")
+	case strings.HasSuffix(u.Path, "/qe/identity"):
+		return CollateralQEIdentity, CollateralQEIdentityChain, qeIdentityIssuerChainHeader, "QE identity"
+	case strings.HasSuffix(u.Path, "/pckcrl"):
+		return CollateralPCKCRL, CollateralPCKCRLChain, pckCRLIssuerChainHeader,
+			"PCK CRL for CA " + u.Query().Get("ca")
+	case strings.HasSuffix(u.Path, ".der"):
+		return CollateralRootCRL, "", "", "root CA CRL"
+	}
+	return "", "", "", ""
+}
+
+// dirGetter serves collateral requests from local files instead of the Intel
+// PCS, for hosts that cannot reach it.
+type dirGetter struct {
+	dir string
+}
+
+func (g *dirGetter) Get(rawURL string) (map[string][]string, []byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, chain, header, what := collateralFiles(u)
+	if body == "" {
+		return nil, nil, fmt.Errorf("no collateral file for %s", rawURL)
+	}
+
+	data, err := g.read(body, what, rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if chain == "" {
+		return nil, data, nil
+	}
+	pem, err := g.read(chain, what+" issuer chain", rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The verify package expects the chain URL-encoded, as the PCS sends it.
+	return map[string][]string{header: {url.QueryEscape(string(pem))}}, data, nil
+}
+
+func (g *dirGetter) read(name, what, rawURL string) ([]byte, error) {
+	path := filepath.Join(g.dir, name)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("missing collateral file %s (%s, mirrored from %s)", path, what, rawURL)
+	}
+	return data, err
+}
@@ -0,0 +1,39 @@
+package rtmr
+
+import "bytes"
+
+// FieldDiff is one field compared by Diff.
+type FieldDiff struct {
+	Name    string
+	A, B    []byte
+	Differs bool
+}
+
+// Diff compares the security-relevant fields of two TD Reports: the RTMRs,
+// MRTD, MRCONFIGID, MROWNER, MROWNERCONFIG, SERVTD_HASH, TD_ATTRIBUTES and
+// XFAM.
+func Diff(a, b *TDReport) []FieldDiff {
+	fields := []struct {
+		name string
+		get  func(*TDReport) []byte
+	}{
+		{"RTMR[0]", func(r *TDReport) []byte { return r.Rtmr0[:] }},
+		{"RTMR[1]", func(r *TDReport) []byte { return r.Rtmr1[:] }},
+		{"RTMR[2]", func(r *TDReport) []byte { return r.Rtmr2[:] }},
+		{"RTMR[3]", func(r *TDReport) []byte { return r.Rtmr3[:] }},
+		{"MrTd", func(r *TDReport) []byte { return r.MrTd[:] }},
+		{"MrConfigId", func(r *TDReport) []byte { return r.MrConfigId[:] }},
+		{"MrOwner", func(r *TDReport) []byte { return r.MrOwner[:] }},
+		{"MrOwnerConfig", func(r *TDReport) []byte { return r.MrOwnerConfig[:] }},
+		{"ServTdHash", func(r *TDReport) []byte { return r.ServTdHash[:] }},
+		{"TdAttributes", func(r *TDReport) []byte { return r.TdAttributes[:] }},
+		{"Xfam", func(r *TDReport) []byte { return r.Xfam[:] }},
+	}
+
+	diffs := make([]FieldDiff, len(fields))
+	for i, f := range fields {
+		av, bv := f.get(a), f.get(b)
+		diffs[i] = FieldDiff{Name: f.name, A: av, B: bv, Differs: !bytes.Equal(av, bv)}
+	}
+	return diffs
+}
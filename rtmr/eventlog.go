@@ -0,0 +1,219 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// TCG event log constants (TCG PC Client Platform Firmware Profile, crypto
+// agile format) as used by the TDX CCEL.
+const (
+	algSHA384        = 0x000C
+	evNoAction       = 0x00000003
+	specIDSignature  = "Spec ID Event03\x00"
+	pcrEventHdrSize  = 32 // pcrIndex, eventType, SHA-1 digest, eventSize
+	pcrEvent2HdrSize = 12 // pcrIndex, eventType, digest count
+)
+
+// Event is one measured event from a CCEL/TCG2 event log.
+type Event struct {
+	// Index is the position of the event in the log, counting the Spec ID
+	// header event as 0.
+	Index int
+	// MRIndex is the CC measurement register index from the log: 0 is MRTD
+	// and 1-4 are RTMR0-3.
+	MRIndex uint32
+	Type    uint32
+	// Digest is the SHA-384 digest extended into the register.
+	Digest []byte
+	Data   []byte
+}
+
+// RTMR returns the RTMR the event was extended into, or -1 for MRTD.
+func (e Event) RTMR() int {
+	return int(e.MRIndex) - 1
+}
+
+// ParseEventLog decodes a crypto-agile CCEL/TCG2 event log. It returns the
+// events that carry a SHA-384 digest; EV_NO_ACTION events are not extended
+// into any register and are skipped. Trailing 0xFF or zero padding, as left
+// in the CCEL area after the last event, ends the log.
+func ParseEventLog(data []byte) ([]Event, error) {
+	digestSizes, offset, err := parseSpecIDEvent(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for index := 1; offset < len(data); index++ {
+		if isPadding(data[offset:]) {
+			break
+		}
+		event, n, err := parsePCREvent2(data[offset:], digestSizes)
+		if err != nil {
+			return nil, fmt.Errorf("event %d at offset %d: %v", index, offset, err)
+		}
+		offset += n
+		if event.Type == evNoAction || event.Digest == nil {
+			continue
+		}
+		event.Index = index
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// parseSpecIDEvent decodes the SHA-1 format header event and returns the
+// digest size of each algorithm used by the rest of the log, and the offset
+// of the first crypto-agile event.
+func parseSpecIDEvent(data []byte) (map[uint16]int, int, error) {
+	if len(data) < pcrEventHdrSize {
+		return nil, 0, errors.New("event log too short for header event")
+	}
+	eventSize := int(binary.LittleEndian.Uint32(data[28:32]))
+	end := pcrEventHdrSize + eventSize
+	if eventSize < 28 || end > len(data) {
+		return nil, 0, fmt.Errorf("invalid header event size %d", eventSize)
+	}
+	spec := data[pcrEventHdrSize:end]
+	if string(spec[:16]) != specIDSignature {
+		return nil, 0, errors.New("event log does not start with a Spec ID Event03 header (not a crypto-agile log)")
+	}
+
+	numAlgs := int(binary.LittleEndian.Uint32(spec[24:28]))
+	if 28+4*numAlgs > len(spec) {
+		return nil, 0, fmt.Errorf("header event lists %d algorithms but is only %d bytes", numAlgs, len(spec))
+	}
+	digestSizes := make(map[uint16]int, numAlgs)
+	for i := 0; i < numAlgs; i++ {
+		alg := spec[28+4*i:]
+		digestSizes[binary.LittleEndian.Uint16(alg[0:2])] = int(binary.LittleEndian.Uint16(alg[2:4]))
+	}
+	if digestSizes[algSHA384] != sha512.Size384 {
+		return nil, 0, errors.New("event log has no SHA-384 digests")
+	}
+	return digestSizes, end, nil
+}
+
+// parsePCREvent2 decodes one crypto-agile event and returns it with the
+// number of bytes it occupies.
+func parsePCREvent2(b []byte, digestSizes map[uint16]int) (Event, int, error) {
+	if len(b) < pcrEvent2HdrSize {
+		return Event{}, 0, errors.New("truncated event header")
+	}
+	event := Event{
+		MRIndex: binary.LittleEndian.Uint32(b[0:4]),
+		Type:    binary.LittleEndian.Uint32(b[4:8]),
+	}
+	count := int(binary.LittleEndian.Uint32(b[8:12]))
+
+	offset := pcrEvent2HdrSize
+	for i := 0; i < count; i++ {
+		if offset+2 > len(b) {
+			return Event{}, 0, errors.New("truncated digest list")
+		}
+		alg := binary.LittleEndian.Uint16(b[offset : offset+2])
+		size, ok := digestSizes[alg]
+		if !ok {
+			return Event{}, 0, fmt.Errorf("unknown digest algorithm 0x%04x", alg)
+		}
+		offset += 2
+		if offset+size > len(b) {
+			return Event{}, 0, errors.New("truncated digest")
+		}
+		if alg == algSHA384 {
+			event.Digest = b[offset : offset+size]
+		}
+		offset += size
+	}
+
+	if offset+4 > len(b) {
+		return Event{}, 0, errors.New("truncated event size")
+	}
+	eventSize := int(binary.LittleEndian.Uint32(b[offset : offset+4]))
+	offset += 4
+	if eventSize > len(b)-offset {
+		return Event{}, 0, fmt.Errorf("event data size %d exceeds log", eventSize)
+	}
+	event.Data = b[offset : offset+eventSize]
+	return event, offset + eventSize, nil
+}
+
+// isPadding reports whether the rest of the log is filler.
+func isPadding(b []byte) bool {
+	return len(bytes.Trim(b, "\xff")) == 0 || isAllZeros(b)
+}
+
+// ReplayRTMR folds event digests into a register the way the TDX module
+// extends an RTMR: starting from zero, value = SHA384(value || digest).
+func ReplayRTMR(events [][]byte) [48]byte {
+	return ExtendRTMR([48]byte{}, events)
+}
+
+// ExtendRTMR is ReplayRTMR starting from a given register value, for
+// replaying only the extensions made after a known point.
+func ExtendRTMR(value [48]byte, events [][]byte) [48]byte {
+	for _, digest := range events {
+		value = sha512.Sum384(append(value[:], digest...))
+	}
+	return value
+}
+
+// ReplayResult is the outcome of replaying one RTMR from an event log.
+type ReplayResult struct {
+	// Events is the number of log events extended into the register.
+	Events   int
+	Replayed [48]byte
+	Match    bool
+	// FirstBadEvent is the log index of the first event that breaks the
+	// chain, or -1 when the replay matches. If a prefix of the events
+	// reproduces the register, it is the event right after that prefix;
+	// if none does, the chain never agrees and it is the register's first
+	// event.
+	FirstBadEvent int
+}
+
+// ReplayEventLog replays events per RTMR and compares each result to the
+// corresponding register in r.
+func (r *TDReport) ReplayEventLog(events []Event) [4]ReplayResult {
+	var perRTMR [4][]Event
+	for _, e := range events {
+		if i := e.RTMR(); i >= 0 && i < len(perRTMR) {
+			perRTMR[i] = append(perRTMR[i], e)
+		}
+	}
+
+	var results [4]ReplayResult
+	for i, actual := range r.RTMRs() {
+		res := ReplayResult{Events: len(perRTMR[i]), FirstBadEvent: -1}
+
+		// Track the last prefix whose replay equals the register so a
+		// divergence can be pinned to a specific event.
+		var value [48]byte
+		matchedPrefix := -1
+		if value == actual {
+			matchedPrefix = 0
+		}
+		for n, e := range perRTMR[i] {
+			value = sha512.Sum384(append(value[:], e.Digest...))
+			if value == actual {
+				matchedPrefix = n + 1
+			}
+		}
+		res.Replayed = value
+		res.Match = value == actual
+
+		if !res.Match && len(perRTMR[i]) > 0 {
+			if matchedPrefix >= 0 && matchedPrefix < len(perRTMR[i]) {
+				res.FirstBadEvent = perRTMR[i][matchedPrefix].Index
+			} else {
+				res.FirstBadEvent = perRTMR[i][0].Index
+			}
+		}
+		results[i] = res
+	}
+	return results
+}
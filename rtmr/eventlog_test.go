@@ -0,0 +1,198 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+const algSHA1 = 0x0004
+
+// logEvent is an event of a hand-built log.
+type logEvent struct {
+	mrIndex, eventType uint32
+	data               string
+	// withSHA1 adds a SHA-1 digest before the SHA-384 one.
+	withSHA1 bool
+}
+
+// buildEventLog returns a crypto-agile log: a Spec ID header listing SHA-1
+// and SHA-384, then the events with SHA-384 digests of their data, then
+// 0xFF padding. It also returns the offset at which each event ends.
+func buildEventLog(events []logEvent) ([]byte, []int) {
+	le32 := func(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+	le16 := func(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+
+	var spec bytes.Buffer
+	spec.WriteString(specIDSignature)
+	spec.Write(le32(0))            // platform class
+	spec.Write([]byte{0, 2, 0, 2}) // spec version minor, major, errata, uintn size
+	spec.Write(le32(2))            // number of algorithms
+	spec.Write(le16(algSHA1))
+	spec.Write(le16(sha1.Size))
+	spec.Write(le16(algSHA384))
+	spec.Write(le16(sha512.Size384))
+	spec.WriteByte(0) // vendor info size
+
+	var log bytes.Buffer
+	log.Write(le32(0))
+	log.Write(le32(evNoAction))
+	log.Write(make([]byte, sha1.Size))
+	log.Write(le32(uint32(spec.Len())))
+	log.Write(spec.Bytes())
+
+	var ends []int
+	for _, e := range events {
+		log.Write(le32(e.mrIndex))
+		log.Write(le32(e.eventType))
+		if e.withSHA1 {
+			log.Write(le32(2))
+			d := sha1.Sum([]byte(e.data))
+			log.Write(le16(algSHA1))
+			log.Write(d[:])
+		} else {
+			log.Write(le32(1))
+		}
+		d := sha512.Sum384([]byte(e.data))
+		log.Write(le16(algSHA384))
+		log.Write(d[:])
+		log.Write(le32(uint32(len(e.data))))
+		log.WriteString(e.data)
+		ends = append(ends, log.Len())
+	}
+	log.Write(bytes.Repeat([]byte{0xff}, 64))
+	return log.Bytes(), ends
+}
+
+func digest384(s string) []byte {
+	d := sha512.Sum384([]byte(s))
+	return d[:]
+}
+
+var testLogEvents = []logEvent{
+	{mrIndex: 0, eventType: 0x80000008, data: "TDVF"},                 // 1: MRTD
+	{mrIndex: 1, eventType: 0x80000001, data: "SecureBoot=1"},         // 2: RTMR0
+	{mrIndex: 0, eventType: evNoAction, data: "no action"},            // 3: skipped
+	{mrIndex: 1, eventType: 0x80000002, data: "BootOrder"},            // 4: RTMR0
+	{mrIndex: 2, eventType: 0x0000000d, data: "shim", withSHA1: true}, // 5: RTMR1
+	{mrIndex: 2, eventType: 0x0000000d, data: "grub"},                 // 6: RTMR1
+	{mrIndex: 3, eventType: 0x0000000d, data: "cmdline"},              // 7: RTMR2
+}
+
+func TestParseEventLog(t *testing.T) {
+	log, _ := buildEventLog(testLogEvents)
+	events, err := ParseEventLog(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIndex := []int{1, 2, 4, 5, 6, 7}
+	if len(events) != len(wantIndex) {
+		t.Fatalf("ParseEventLog returned %d events, want %d", len(events), len(wantIndex))
+	}
+	for i, e := range events {
+		src := testLogEvents[wantIndex[i]-1]
+		if e.Index != wantIndex[i] || e.MRIndex != src.mrIndex || e.Type != src.eventType ||
+			string(e.Data) != src.data || !bytes.Equal(e.Digest, digest384(src.data)) {
+			t.Errorf("event %d = {%d %d %#x %q %x}, want index %d from %+v", i, e.Index, e.MRIndex, e.Type, e.Data, e.Digest, wantIndex[i], src)
+		}
+		if e.RTMR() != int(src.mrIndex)-1 {
+			t.Errorf("event %d: RTMR() = %d, want %d", i, e.RTMR(), int(src.mrIndex)-1)
+		}
+	}
+}
+
+func TestReplayEventLog(t *testing.T) {
+	log, _ := buildEventLog(testLogEvents)
+	events, err := ParseEventLog(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report TDReport
+	// RTMR0 matches the whole log.
+	report.Rtmr0 = ReplayRTMR([][]byte{digest384("SecureBoot=1"), digest384("BootOrder")})
+	// RTMR1 only saw "shim": the "grub" event (index 6) is the first bad one.
+	report.Rtmr1 = ReplayRTMR([][]byte{digest384("shim")})
+	// RTMR2 matches no prefix: the chain breaks at its first event (7).
+	report.Rtmr2 = ReplayRTMR([][]byte{digest384("other cmdline")})
+	// RTMR3 has no events and was never extended.
+
+	results := report.ReplayEventLog(events)
+	want := [4]struct {
+		events   int
+		match    bool
+		firstBad int
+	}{{2, true, -1}, {2, false, 6}, {1, false, 7}, {0, true, -1}}
+	for i, res := range results {
+		if res.Events != want[i].events || res.Match != want[i].match || res.FirstBadEvent != want[i].firstBad {
+			t.Errorf("RTMR%d: events %d, match %v, first bad %d; want %+v", i, res.Events, res.Match, res.FirstBadEvent, want[i])
+		}
+	}
+	if results[0].Replayed != report.Rtmr0 {
+		t.Errorf("RTMR0 replayed to %x, want %x", results[0].Replayed, report.Rtmr0)
+	}
+	if got := ExtendRTMR(ReplayRTMR([][]byte{digest384("SecureBoot=1")}), [][]byte{digest384("BootOrder")}); got != report.Rtmr0 {
+		t.Errorf("ExtendRTMR from a prefix = %x, want %x", got, report.Rtmr0)
+	}
+}
+
+func TestParseEventLogTruncated(t *testing.T) {
+	log, ends := buildEventLog(testLogEvents)
+	boundary := map[int]bool{}
+	for _, end := range ends {
+		boundary[end] = true
+	}
+	headerEnd := ends[0] - (pcrEvent2HdrSize + 2 + sha512.Size384 + 4 + len("TDVF"))
+	boundary[headerEnd] = true
+
+	// Every cut inside the header or an event must fail, not panic; cuts
+	// at event boundaries are shorter valid logs. So is a cut that leaves
+	// only zero bytes of an event, which reads as zero padding.
+	last := 0
+	for n := 0; n < ends[len(ends)-1]; n++ {
+		events, err := ParseEventLog(log[:n])
+		if boundary[n] {
+			if err != nil {
+				t.Errorf("log cut at event boundary %d: %v", n, err)
+			}
+			last = n
+			continue
+		}
+		if last > 0 && isAllZeros(log[last:n]) {
+			continue
+		}
+		if err == nil {
+			t.Errorf("log cut at %d parsed into %d events, want an error", n, len(events))
+		}
+	}
+}
+
+func TestParseEventLogCorrupt(t *testing.T) {
+	log, ends := buildEventLog(testLogEvents)
+	headerEnd := ends[0] - (pcrEvent2HdrSize + 2 + sha512.Size384 + 4 + len("TDVF"))
+	for _, tc := range []struct {
+		name    string
+		corrupt func(b []byte)
+		wantErr string
+	}{
+		{"signature", func(b []byte) { b[pcrEventHdrSize] = 'X' }, "Spec ID Event03"},
+		{"header event size", func(b []byte) { binary.LittleEndian.PutUint32(b[28:], 0xffffffff) }, "header event size"},
+		{"algorithm count", func(b []byte) { binary.LittleEndian.PutUint32(b[pcrEventHdrSize+24:], 0x7fffffff) }, "algorithms"},
+		{"no SHA-384", func(b []byte) { binary.LittleEndian.PutUint16(b[pcrEventHdrSize+32:], 0x000b) }, "no SHA-384"},
+		{"unknown digest algorithm", func(b []byte) { binary.LittleEndian.PutUint16(b[headerEnd+12:], 0x0012) }, "unknown digest algorithm"},
+		{"digest count", func(b []byte) { binary.LittleEndian.PutUint32(b[headerEnd+8:], 0xffffffff) }, "event 1 at offset"},
+		{"event data size", func(b []byte) {
+			binary.LittleEndian.PutUint32(b[headerEnd+pcrEvent2HdrSize+2+sha512.Size384:], 0xffffffff)
+		}, "exceeds log"},
+	} {
+		b := bytes.Clone(log)
+		tc.corrupt(b)
+		_, err := ParseEventLog(b)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: ParseEventLog error = %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
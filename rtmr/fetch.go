@@ -0,0 +1,122 @@
+package rtmr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults for fetching collateral from the PCS.
+const (
+	// DefaultCollateralTimeout bounds each collateral request.
+	DefaultCollateralTimeout = 30 * time.Second
+
+	// retryBaseDelay is the delay before the first retry; it doubles with
+	// each further attempt, up to maxRetryDelay.
+	retryBaseDelay = 1 * time.Second
+	// maxRetryDelay caps both backoff and Retry-After.
+	maxRetryDelay = 2 * time.Minute
+)
+
+// retryGetter fetches collateral over HTTPS, retrying transient failures
+// (network errors, timeouts, 429 and 5xx responses) with exponential
+// backoff. A Retry-After header on the response replaces the backoff delay.
+// Other 4xx responses, such as 404 for an unknown FMSPC, are not retried.
+// Cancelling ctx aborts the request in flight and any further retries.
+type retryGetter struct {
+	ctx     context.Context
+	client  *http.Client
+	retries int
+	sleep   func(time.Duration)
+}
+
+func newRetryGetter(ctx context.Context, retries int, timeout time.Duration) *retryGetter {
+	if timeout <= 0 {
+		timeout = DefaultCollateralTimeout
+	}
+	return &retryGetter{
+		ctx:     ctx,
+		client:  &http.Client{Timeout: timeout},
+		retries: retries,
+		sleep: func(d time.Duration) {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+			}
+		},
+	}
+}
+
+func (g *retryGetter) Get(url string) (map[string][]string, []byte, error) {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		header, body, retryAfter, err := g.get(url)
+		if err == nil {
+			return header, body, nil
+		}
+		if retryAfter < 0 || attempt >= g.retries || g.ctx.Err() != nil {
+			if attempt > 0 {
+				return nil, nil, fmt.Errorf("%v (after %d attempts)", err, attempt+1)
+			}
+			return nil, nil, err
+		}
+
+		wait := delay
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		g.sleep(min(wait, maxRetryDelay))
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// get makes a single request. On failure, retryAfter is negative if the
+// error is permanent, zero if it is transient, and the server's Retry-After
+// delay if it sent one.
+func (g *retryGetter) get(url string) (header map[string][]string, body []byte, retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(g.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, -1, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		err := fmt.Errorf("failed to retrieve %s, status code received %d", url, resp.StatusCode)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return nil, nil, -1, err
+		}
+		return nil, nil, parseRetryAfter(resp.Header.Get("Retry-After")), err
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return resp.Header, body, 0, nil
+}
+
+// parseRetryAfter decodes a Retry-After header, given either as seconds or
+// as an HTTP date. It returns zero when the header is absent or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package rtmr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGetter returns a retryGetter whose sleeps are recorded instead of
+// waited out.
+func testGetter(ctx context.Context, retries int) (*retryGetter, *[]time.Duration) {
+	g := newRetryGetter(ctx, retries, time.Second)
+	var sleeps []time.Duration
+	g.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return g, &sleeps
+}
+
+// response is a canned HTTP response: a status code and an optional
+// Retry-After value.
+type response struct {
+	status     int
+	retryAfter string
+}
+
+// replay serves the given responses in turn, one per request, and counts
+// the requests.
+func replay(t *testing.T, responses ...response) (*httptest.Server, *int) {
+	t.Helper()
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n >= len(responses) {
+			t.Errorf("unexpected request %d", n+1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp := responses[n]
+		n++
+		if resp.retryAfter != "" {
+			w.Header().Set("Retry-After", resp.retryAfter)
+		}
+		if resp.status != http.StatusOK {
+			w.WriteHeader(resp.status)
+			return
+		}
+		w.Write([]byte("collateral"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &n
+}
+
+func TestRetryGetter(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		retries    int
+		responses  []response
+		wantErr    string
+		wantSleeps []time.Duration
+	}{
+		{"5xx then success", 3, []response{{503, ""}, {500, ""}, {200, ""}}, "",
+			[]time.Duration{retryBaseDelay, 2 * retryBaseDelay}},
+		{"429 with Retry-After seconds", 3, []response{{429, "7"}, {200, ""}}, "",
+			[]time.Duration{7 * time.Second}},
+		{"Retry-After capped", 3, []response{{503, "3600"}, {200, ""}}, "",
+			[]time.Duration{maxRetryDelay}},
+		{"404 not retried", 3, []response{{404, "5"}}, "status code received 404",
+			nil},
+		{"retry cap", 2, []response{{503, ""}, {503, ""}, {503, ""}}, "after 3 attempts",
+			[]time.Duration{retryBaseDelay, 2 * retryBaseDelay}},
+		{"no retries", 0, []response{{503, ""}}, "status code received 503",
+			nil},
+	} {
+		srv, n := replay(t, tc.responses...)
+		g, sleeps := testGetter(context.Background(), tc.retries)
+		_, body, err := g.Get(srv.URL + "/tcb?fmspc=00")
+		if tc.wantErr == "" {
+			if err != nil || string(body) != "collateral" {
+				t.Errorf("%s: Get = %q, %v", tc.name, body, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: Get error = %v, want %q", tc.name, err, tc.wantErr)
+		}
+		if *n != len(tc.responses) {
+			t.Errorf("%s: %d requests, want %d", tc.name, *n, len(tc.responses))
+		}
+		if len(*sleeps) != len(tc.wantSleeps) {
+			t.Errorf("%s: slept %v, want %v", tc.name, *sleeps, tc.wantSleeps)
+			continue
+		}
+		for i, d := range tc.wantSleeps {
+			if (*sleeps)[i] != d {
+				t.Errorf("%s: slept %v, want %v", tc.name, *sleeps, tc.wantSleeps)
+				break
+			}
+		}
+	}
+}
+
+func TestRetryGetterRetryAfterDate(t *testing.T) {
+	date := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	srv, _ := replay(t, response{503, date}, response{200, ""})
+	g, sleeps := testGetter(context.Background(), 1)
+	if _, _, err := g.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	// HTTP dates have a resolution of one second.
+	if len(*sleeps) != 1 || (*sleeps)[0] < 28*time.Second || (*sleeps)[0] > 30*time.Second {
+		t.Errorf("slept %v, want about 30s", *sleeps)
+	}
+}
+
+func TestRetryGetterCancel(t *testing.T) {
+	srv, n := replay(t, response{503, ""}, response{200, ""})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, _ := testGetter(ctx, 3)
+	// The context is cancelled while backing off.
+	g.sleep = func(time.Duration) { cancel() }
+	_, _, err := g.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Errorf("Get error = %v, want context canceled", err)
+	}
+	if *n != 1 {
+		t.Errorf("%d requests, want 1 (no request after cancellation)", *n)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	} {
+		if got := parseRetryAfter(tc.value); got != tc.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
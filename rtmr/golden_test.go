@@ -0,0 +1,96 @@
+package rtmr
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata from the current output")
+
+// goldenFixtures maps each quote fixture in testdata to its golden
+// measurements file. Encodings of the same quote share one golden file, so
+// the raw and protobuf paths must agree.
+var goldenFixtures = []struct {
+	quote, golden string
+	format        Format
+}{
+	{"tdx_prod_quote_SPR_E4.dat", "tdx_prod_quote_SPR_E4.golden.json", FormatRawV4},
+	{"tdx_prod_quote_SPR_E4.pb", "tdx_prod_quote_SPR_E4.golden.json", FormatProtoV4},
+}
+
+func TestGoldenMeasurements(t *testing.T) {
+	// With -update, each golden file is written from its first fixture
+	// only and still checked against the others.
+	written := make(map[string]bool)
+	for _, f := range goldenFixtures {
+		t.Run(f.quote, func(t *testing.T) {
+			raw, err := os.ReadFile("testdata/" + f.quote)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := ParseQuote(raw)
+			if err != nil {
+				t.Fatalf("ParseQuote() error = %v", err)
+			}
+			if report.Format != f.format {
+				t.Errorf("ParseQuote() format = %s, want %s", report.Format, f.format)
+			}
+
+			got, err := json.MarshalIndent(report.Measurements(), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			path := "testdata/" + f.golden
+			if *update && !written[path] {
+				written[path] = true
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("measurements differ from %s:\n got: %s\nwant: %s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestMeasurementsDeterministic(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func() (jsonData, cborData []byte) {
+		report, err := ParseQuote(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := report.Measurements()
+		if jsonData, err = json.Marshal(m); err != nil {
+			t.Fatal(err)
+		}
+		if cborData, err = m.CBOR(); err != nil {
+			t.Fatal(err)
+		}
+		return jsonData, cborData
+	}
+	// Map iteration order is randomized per range statement, so repeated
+	// runs would catch a map leaking into the encoding.
+	wantJSON, wantCBOR := encode()
+	for range 20 {
+		gotJSON, gotCBOR := encode()
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Fatalf("JSON differs between runs:\n got: %s\nwant: %s", gotJSON, wantJSON)
+		}
+		if !bytes.Equal(gotCBOR, wantCBOR) {
+			t.Fatalf("CBOR differs between runs:\n got: %x\nwant: %x", gotCBOR, wantCBOR)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package rtmr
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// HashFormat selects how measurement bytes are encoded as text.
+type HashFormat int
+
+const (
+	// HashHex is lowercase hex, the default.
+	HashHex HashFormat = iota
+	// HashHex0x is lowercase hex with a 0x prefix.
+	HashHex0x
+	// HashBase64 is standard, padded base64.
+	HashBase64
+)
+
+var hashFormatNames = []string{
+	HashHex:    "hex",
+	HashHex0x:  "hex0x",
+	HashBase64: "base64",
+}
+
+// ParseHashFormat parses a format name: hex, hex0x or base64.
+func ParseHashFormat(s string) (HashFormat, error) {
+	for f, name := range hashFormatNames {
+		if strings.EqualFold(s, name) {
+			return HashFormat(f), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown hash format %q (want %s)", s, strings.Join(hashFormatNames, ", "))
+}
+
+func (f HashFormat) String() string {
+	if f < 0 || int(f) >= len(hashFormatNames) {
+		return fmt.Sprintf("HashFormat(%d)", int(f))
+	}
+	return hashFormatNames[f]
+}
+
+// MarshalText and UnmarshalText let a HashFormat be used as a flag value.
+func (f HashFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *HashFormat) UnmarshalText(text []byte) error {
+	parsed, err := ParseHashFormat(string(text))
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
+
+// Encode returns b in format f.
+func (f HashFormat) Encode(b []byte) string {
+	switch f {
+	case HashHex0x:
+		return "0x" + hex.EncodeToString(b)
+	case HashBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	default:
+		return hex.EncodeToString(b)
+	}
+}
+
+// pattern is a JSON Schema pattern matching the output of Encode.
+func (f HashFormat) pattern() string {
+	switch f {
+	case HashHex0x:
+		return "^0x[0-9a-f]*$"
+	case HashBase64:
+		return "^[A-Za-z0-9+/]*={0,2}$"
+	default:
+		return "^[0-9a-f]*$"
+	}
+}
@@ -0,0 +1,94 @@
+package rtmr
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ManifestEntry is one artifact a workload measures into RTMR3: the file
+// and the SHA-384 digest of its contents, which is what gets extended.
+type ManifestEntry struct {
+	Path   string
+	Digest [48]byte
+}
+
+// ParseManifest decodes a measurement manifest in sha384sum format, one
+// "<hex digest>  <path>" line per artifact, in extend order. Blank lines and
+// lines starting with # are skipped, so the output of
+// "sha384sum file1 file2 ..." can be used as is.
+func ParseManifest(data []byte) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		digest, path, ok := strings.Cut(line, " ")
+		// sha384sum marks binary-mode reads with a * before the path.
+		path = strings.TrimPrefix(strings.TrimSpace(path), "*")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("line %d: expected \"<sha384 hex>  <path>\"", n)
+		}
+		raw, err := hex.DecodeString(strings.TrimPrefix(digest, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid hex digest: %v", n, err)
+		}
+		if len(raw) != sha512.Size384 {
+			return nil, fmt.Errorf("line %d: digest is %d bytes, expected %d (SHA-384)", n, len(raw), sha512.Size384)
+		}
+		entry := ManifestEntry{Path: path}
+		copy(entry.Digest[:], raw)
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("manifest lists no artifacts")
+	}
+	return entries, nil
+}
+
+// ManifestResult is the outcome of replaying a manifest into an RTMR.
+type ManifestResult struct {
+	Replayed [48]byte
+	Match    bool
+	// FirstBadEntry is the index of the first manifest entry that breaks
+	// the chain, or -1 when the replay matches. As for ReplayResult, it is
+	// the entry right after the longest prefix that reproduces the
+	// register, or entry 0 if no prefix does.
+	FirstBadEntry int
+}
+
+// ReplayManifest extends the entries' digests, in order, into start (zero
+// for a register the workload measures from boot) and compares the result
+// to actual.
+func ReplayManifest(start, actual [48]byte, entries []ManifestEntry) ManifestResult {
+	res := ManifestResult{FirstBadEntry: -1}
+	matchedPrefix := -1
+	if start == actual {
+		matchedPrefix = 0
+	}
+	value := start
+	for n, e := range entries {
+		value = ExtendRTMR(value, [][]byte{e.Digest[:]})
+		if value == actual {
+			matchedPrefix = n + 1
+		}
+	}
+	res.Replayed = value
+	res.Match = value == actual
+
+	if !res.Match {
+		res.FirstBadEntry = 0
+		if matchedPrefix > 0 && matchedPrefix < len(entries) {
+			res.FirstBadEntry = matchedPrefix
+		}
+	}
+	return res
+}
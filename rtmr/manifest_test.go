@@ -0,0 +1,41 @@
+package rtmr
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"testing"
+)
+
+func TestReplayManifest(t *testing.T) {
+	a, b, c := sha512.Sum384([]byte("a")), sha512.Sum384([]byte("b")), sha512.Sum384([]byte("c"))
+	entries, err := ParseManifest([]byte(fmt.Sprintf("# workload\n%x  /app/a\n%x *b\n\n%x  /etc/c\n", a, b, c)))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if len(entries) != 3 || entries[1].Path != "b" || entries[2].Digest != c {
+		t.Fatalf("ParseManifest() = %+v", entries)
+	}
+
+	var start [48]byte
+	start[0] = 1
+	full := ExtendRTMR(start, [][]byte{a[:], b[:], c[:]})
+	if res := ReplayManifest(start, full, entries); !res.Match || res.FirstBadEntry != -1 {
+		t.Errorf("ReplayManifest(full) = %+v, want a match", res)
+	}
+	if res := ReplayManifest([48]byte{}, full, entries); res.Match || res.FirstBadEntry != 0 {
+		t.Errorf("ReplayManifest(wrong start) = %+v, want divergence at 0", res)
+	}
+	// The workload stopped after two artifacts: the third one diverges.
+	partial := ExtendRTMR(start, [][]byte{a[:], b[:]})
+	if res := ReplayManifest(start, partial, entries); res.Match || res.FirstBadEntry != 2 {
+		t.Errorf("ReplayManifest(partial) = %+v, want divergence at 2", res)
+	}
+}
+
+func TestParseManifestInvalid(t *testing.T) {
+	for _, data := range []string{"", "# only a comment\n", "abcd  file\n", "zz  file\n", fmt.Sprintf("%x\n", [48]byte{})} {
+		if _, err := ParseManifest([]byte(data)); err == nil {
+			t.Errorf("ParseManifest(%q) accepted an invalid manifest", data)
+		}
+	}
+}
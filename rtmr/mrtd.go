@@ -0,0 +1,194 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TDVF metadata (Intel TDX Virtual Firmware Design Guide, section 11). The
+// firmware image carries a descriptor listing the memory sections the VMM
+// must add to the TD before it starts, and which of them are measured.
+const (
+	tdvfSignature       = "TDVF"
+	tdvfDescriptorSize  = 16
+	tdvfSectionSize     = 32
+	tdvfAttrMRExtend    = 1 << 0 // contents are extended into MRTD
+	tdvfAttrPageAug     = 1 << 1 // added after launch with PAGE.AUG, not measured
+	tdxPageSize         = 4096
+	tdxExtendChunkSize  = 256
+	tdxMeasureBlockSize = 128
+
+	// The OVMF GUID table sits just below the last 0x20 bytes of the image
+	// and is walked backwards from its footer entry.
+	ovmfTableFooterOffset = 0x20
+)
+
+var (
+	ovmfTableFooterGUID = mustGUID("96b582de-1fb2-45f7-baea-a366c55a082d")
+	tdxMetadataGUID     = mustGUID("e47a6535-984a-4798-865e-4685a7bf8ec2")
+)
+
+// TDVFSection is one entry of the TDVF metadata: a range of the TD's
+// memory, initialized from RawDataSize bytes of the image at DataOffset
+// (the rest zero).
+type TDVFSection struct {
+	DataOffset     uint32
+	RawDataSize    uint32
+	MemoryAddress  uint64
+	MemoryDataSize uint64
+	Type           uint32
+	Attributes     uint32
+}
+
+// Measured reports whether the section's contents are extended into MRTD
+// (TDH.MR.EXTEND), and not only its page addresses.
+func (s TDVFSection) Measured() bool {
+	return s.Attributes&tdvfAttrMRExtend != 0
+}
+
+// ParseTDVFMetadata finds the TDVF metadata of a firmware image through the
+// OVMF GUID table and returns its sections.
+func ParseTDVFMetadata(firmware []byte) ([]TDVFSection, error) {
+	entry, err := ovmfTableEntry(firmware, tdxMetadataGUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry) < 4 {
+		return nil, fmt.Errorf("TDX metadata offset entry is %d bytes, expected 4", len(entry))
+	}
+	back := int(binary.LittleEndian.Uint32(entry))
+	if back <= 0 || back > len(firmware) {
+		return nil, fmt.Errorf("TDX metadata offset 0x%x is outside the %d-byte image", back, len(firmware))
+	}
+	// The offset points either at the descriptor or at the GUID before it,
+	// depending on the firmware build.
+	start := len(firmware) - back
+	if !bytes.HasPrefix(firmware[start:], []byte(tdvfSignature)) {
+		start += 16
+	}
+	if start+tdvfDescriptorSize > len(firmware) || !bytes.HasPrefix(firmware[start:], []byte(tdvfSignature)) {
+		return nil, fmt.Errorf("no TDVF descriptor at the TDX metadata offset")
+	}
+
+	d := firmware[start:]
+	count := int(binary.LittleEndian.Uint32(d[12:16]))
+	if start+tdvfDescriptorSize+count*tdvfSectionSize > len(firmware) {
+		return nil, fmt.Errorf("TDVF descriptor lists %d sections, more than fit in the image", count)
+	}
+	sections := make([]TDVFSection, count)
+	for i := range sections {
+		b := d[tdvfDescriptorSize+i*tdvfSectionSize:]
+		sections[i] = TDVFSection{
+			DataOffset:     binary.LittleEndian.Uint32(b[0:4]),
+			RawDataSize:    binary.LittleEndian.Uint32(b[4:8]),
+			MemoryAddress:  binary.LittleEndian.Uint64(b[8:16]),
+			MemoryDataSize: binary.LittleEndian.Uint64(b[16:24]),
+			Type:           binary.LittleEndian.Uint32(b[24:28]),
+			Attributes:     binary.LittleEndian.Uint32(b[28:32]),
+		}
+		s := sections[i]
+		if uint64(s.DataOffset)+uint64(s.RawDataSize) > uint64(len(firmware)) {
+			return nil, fmt.Errorf("section %d data runs past the end of the image", i)
+		}
+		if s.MemoryAddress%tdxPageSize != 0 || s.MemoryDataSize%tdxPageSize != 0 {
+			return nil, fmt.Errorf("section %d memory range is not page aligned", i)
+		}
+		if uint64(s.RawDataSize) > s.MemoryDataSize {
+			return nil, fmt.Errorf("section %d has more data than memory", i)
+		}
+	}
+	return sections, nil
+}
+
+// PredictMRTD computes the MRTD that launching a TD with the firmware image
+// produces. Each section page is added (TDH.MEM.PAGE.ADD) in section and
+// address order, and the 256-byte chunks of a measured page are extended
+// (TDH.MR.EXTEND) right after the page is added, which is the order KVM
+// uses. Sections added after launch (PAGE.AUG) are not measured.
+//
+// Only the firmware is measured: the TD HOB and temporary memory sections
+// are added as zero pages. A VMM that measures other pages, or orders them
+// differently, yields a different MRTD.
+func PredictMRTD(firmware []byte) ([48]byte, error) {
+	sections, err := ParseTDVFMetadata(firmware)
+	if err != nil {
+		return [48]byte{}, err
+	}
+
+	h := sha512.New384()
+	block := make([]byte, tdxMeasureBlockSize)
+	record := func(op string, gpa uint64) {
+		clear(block)
+		copy(block, op)
+		binary.LittleEndian.PutUint64(block[16:24], gpa)
+		h.Write(block)
+	}
+
+	for _, s := range sections {
+		if s.Attributes&tdvfAttrPageAug != 0 {
+			continue
+		}
+		data := make([]byte, s.MemoryDataSize)
+		copy(data, firmware[s.DataOffset:s.DataOffset+s.RawDataSize])
+		for page := uint64(0); page < s.MemoryDataSize; page += tdxPageSize {
+			record("MEM.PAGE.ADD", s.MemoryAddress+page)
+			if !s.Measured() {
+				continue
+			}
+			for chunk := page; chunk < page+tdxPageSize; chunk += tdxExtendChunkSize {
+				record("MR.EXTEND", s.MemoryAddress+chunk)
+				h.Write(data[chunk : chunk+tdxExtendChunkSize])
+			}
+		}
+	}
+
+	var mrtd [48]byte
+	h.Sum(mrtd[:0])
+	return mrtd, nil
+}
+
+// ovmfTableEntry returns the data of the OVMF GUID table entry with the
+// given GUID. Entries are laid out backwards from the footer: data, then a
+// 2-byte length covering the whole entry, then the GUID.
+func ovmfTableEntry(firmware []byte, guid [16]byte) ([]byte, error) {
+	end := len(firmware) - ovmfTableFooterOffset
+	if end < 18 || !bytes.Equal(firmware[end-16:end], ovmfTableFooterGUID[:]) {
+		return nil, fmt.Errorf("no OVMF GUID table at the end of the image (not a TDVF/OVMF firmware?)")
+	}
+	tableLen := int(binary.LittleEndian.Uint16(firmware[end-18 : end-16]))
+	if tableLen < 18 || tableLen > end {
+		return nil, fmt.Errorf("invalid OVMF GUID table length %d", tableLen)
+	}
+	start := end - tableLen
+	pos := end - 18
+	for pos-18 >= start {
+		entryGUID := firmware[pos-16 : pos]
+		entryLen := int(binary.LittleEndian.Uint16(firmware[pos-18 : pos-16]))
+		if entryLen < 18 || pos-entryLen < start {
+			return nil, fmt.Errorf("invalid OVMF GUID table entry length %d", entryLen)
+		}
+		if bytes.Equal(entryGUID, guid[:]) {
+			return firmware[pos-entryLen : pos-18], nil
+		}
+		pos -= entryLen
+	}
+	return nil, fmt.Errorf("no TDX metadata in the OVMF GUID table (firmware built without TDX support?)")
+}
+
+// mustGUID encodes a GUID string in the mixed-endian byte order of EFI_GUID.
+func mustGUID(s string) [16]byte {
+	raw, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(raw) != 16 {
+		panic("invalid GUID " + s)
+	}
+	var g [16]byte
+	binary.LittleEndian.PutUint32(g[0:4], binary.BigEndian.Uint32(raw[0:4]))
+	binary.LittleEndian.PutUint16(g[4:6], binary.BigEndian.Uint16(raw[4:6]))
+	binary.LittleEndian.PutUint16(g[6:8], binary.BigEndian.Uint16(raw[6:8]))
+	copy(g[8:], raw[8:])
+	return g
+}
@@ -0,0 +1,96 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"testing"
+)
+
+// testFirmware builds a minimal TDVF image: one measured 4 KiB firmware
+// section, one unmeasured TD HOB page and one PAGE.AUG section, with the
+// metadata found through an OVMF GUID table.
+func testFirmware() []byte {
+	fw := make([]byte, 0x3000)
+	for i := range 0x1000 {
+		fw[i] = byte(i)
+	}
+
+	le := binary.LittleEndian
+	d := fw[0x1000:]
+	copy(d, tdvfSignature)
+	le.PutUint32(d[4:], tdvfDescriptorSize+3*tdvfSectionSize)
+	le.PutUint32(d[8:], 1)
+	le.PutUint32(d[12:], 3)
+	section := func(i int, dataOffset, rawSize uint32, addr, size uint64, typ, attr uint32) {
+		b := d[tdvfDescriptorSize+i*tdvfSectionSize:]
+		le.PutUint32(b[0:], dataOffset)
+		le.PutUint32(b[4:], rawSize)
+		le.PutUint64(b[8:], addr)
+		le.PutUint64(b[16:], size)
+		le.PutUint32(b[24:], typ)
+		le.PutUint32(b[28:], attr)
+	}
+	section(0, 0, 0x1000, 0xfff00000, 0x1000, 0, tdvfAttrMRExtend)
+	section(1, 0, 0, 0x809000, 0x1000, 2, 0)
+	section(2, 0, 0, 0x100000000, 0x2000, 4, tdvfAttrPageAug)
+
+	// GUID table: the metadata offset entry, then the footer.
+	end := len(fw) - ovmfTableFooterOffset
+	footer := fw[end-18:]
+	le.PutUint16(footer, 18+22)
+	copy(footer[2:], ovmfTableFooterGUID[:])
+	entry := fw[end-18-22:]
+	le.PutUint32(entry, uint32(len(fw)-0x1000))
+	le.PutUint16(entry[4:], 22)
+	copy(entry[6:], tdxMetadataGUID[:])
+	return fw
+}
+
+func TestParseTDVFMetadata(t *testing.T) {
+	sections, err := ParseTDVFMetadata(testFirmware())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 3 {
+		t.Fatalf("got %d sections, want 3", len(sections))
+	}
+	if s := sections[0]; s.MemoryAddress != 0xfff00000 || s.RawDataSize != 0x1000 || !s.Measured() {
+		t.Errorf("section 0 = %+v", s)
+	}
+	if sections[1].Measured() {
+		t.Error("TD HOB section is measured")
+	}
+
+	if _, err := ParseTDVFMetadata(make([]byte, 0x3000)); err == nil {
+		t.Error("ParseTDVFMetadata succeeded on an image without a GUID table")
+	}
+}
+
+func TestPredictMRTD(t *testing.T) {
+	fw := testFirmware()
+
+	// Spell out the TDX module's measurement of the same image.
+	h := sha512.New384()
+	op := func(name string, gpa uint64) {
+		b := make([]byte, 128)
+		copy(b, name)
+		binary.LittleEndian.PutUint64(b[16:], gpa)
+		h.Write(b)
+	}
+	op("MEM.PAGE.ADD", 0xfff00000)
+	for chunk := 0; chunk < 0x1000; chunk += 256 {
+		op("MR.EXTEND", 0xfff00000+uint64(chunk))
+		h.Write(fw[chunk : chunk+256])
+	}
+	op("MEM.PAGE.ADD", 0x809000)
+	want := h.Sum(nil)
+
+	got, err := PredictMRTD(fw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got[:], want) {
+		t.Errorf("PredictMRTD = %x, want %x", got, want)
+	}
+}
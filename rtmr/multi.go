@@ -0,0 +1,65 @@
+package rtmr
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// signedDataSizeLen is the size of the little-endian length field that
+// precedes the signed data in V4 and V5 quotes.
+const signedDataSizeLen = 4
+
+// signedDataSizeOffset returns the offset of the signed-data size field of
+// the raw V4 or V5 quote at the start of data. data must hold the header
+// and, for V5, the body descriptor.
+func signedDataSizeOffset(data []byte) (int, error) {
+	switch version := rawQuoteVersion(data); version {
+	case 4:
+		return tdReportEnd, nil
+	case quoteVersion5:
+		if len(data) < quoteV5BodyStart {
+			return 0, fmt.Errorf("%w: QuoteV5 is %d bytes", ErrTooShort, len(data))
+		}
+		bodySize := binary.LittleEndian.Uint32(data[quoteHeaderSize+2 : quoteV5BodyStart])
+		if bodySize != tdReportSize && bodySize != tdReportV15Size {
+			return 0, fmt.Errorf("%w: QuoteV5 body size %d is not a TD Report size", ErrUnsupportedVersion, bodySize)
+		}
+		return quoteV5BodyStart + int(bodySize), nil
+	default:
+		return 0, fmt.Errorf("%w %d", ErrUnsupportedVersion, version)
+	}
+}
+
+// QuoteLength returns the total length of the raw V4 or V5 quote at the
+// start of data, computed from its header and signed-data size field.
+func QuoteLength(data []byte) (int, error) {
+	sizeOffset, err := signedDataSizeOffset(data)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(data) < sizeOffset+signedDataSizeLen {
+		return 0, fmt.Errorf("%w for signed data size: %d bytes, need %d", ErrTooShort, len(data), sizeOffset+signedDataSizeLen)
+	}
+	signedDataSize := binary.LittleEndian.Uint32(data[sizeOffset : sizeOffset+signedDataSizeLen])
+	length := sizeOffset + signedDataSizeLen + int(signedDataSize)
+	if length > len(data) {
+		return 0, fmt.Errorf("%w: quote declares %d bytes but only %d remain", ErrTooShort, length, len(data))
+	}
+	return length, nil
+}
+
+// SplitQuotes splits a buffer of raw quotes stored back to back. It returns
+// the complete quotes and the number of trailing bytes that do not form a
+// complete quote, along with the reason they were left over.
+func SplitQuotes(data []byte) (quotes [][]byte, leftover int, err error) {
+	for len(data) > 0 {
+		n, err := QuoteLength(data)
+		if err != nil {
+			return quotes, len(data), err
+		}
+		quotes = append(quotes, data[:n])
+		data = data[n:]
+	}
+	return quotes, 0, nil
+}
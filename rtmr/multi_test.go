@@ -0,0 +1,88 @@
+package rtmr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+)
+
+// fixtureQuote returns the raw testdata quote without the padding the
+// fixture file has after it.
+func fixtureQuote(t *testing.T) []byte {
+	t.Helper()
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := QuoteLength(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw[:n]
+}
+
+func TestSplitQuotes(t *testing.T) {
+	quote := fixtureQuote(t)
+
+	// Two complete quotes and the first 1000 bytes of a third.
+	data := bytes.Join([][]byte{quote, quote, quote[:1000]}, nil)
+	quotes, leftover, err := SplitQuotes(data)
+	if len(quotes) != 2 || !bytes.Equal(quotes[0], quote) || !bytes.Equal(quotes[1], quote) {
+		t.Errorf("SplitQuotes returned %d quotes, want the 2 complete ones", len(quotes))
+	}
+	if leftover != 1000 || !errors.Is(err, ErrTooShort) {
+		t.Errorf("SplitQuotes leftover = %d, error = %v; want 1000 bytes and ErrTooShort", leftover, err)
+	}
+
+	// A partial quote that has its signed-data size but not the data.
+	_, leftover, err = SplitQuotes(append(bytes.Clone(quote), quote[:tdReportEnd+signedDataSizeLen+10]...))
+	if leftover != tdReportEnd+signedDataSizeLen+10 || !errors.Is(err, ErrTooShort) {
+		t.Errorf("SplitQuotes leftover = %d, error = %v; want the partial quote and ErrTooShort", leftover, err)
+	}
+
+	// Trailing bytes that are not a quote at all.
+	_, leftover, err = SplitQuotes(append(bytes.Clone(quote), make([]byte, 64)...))
+	if leftover != 64 || !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("SplitQuotes leftover = %d, error = %v; want 64 bytes and ErrUnsupportedVersion", leftover, err)
+	}
+
+	quotes, leftover, err = SplitQuotes(bytes.Repeat(quote, 3))
+	if len(quotes) != 3 || leftover != 0 || err != nil {
+		t.Errorf("SplitQuotes of 3 quotes = %d quotes, %d leftover, %v", len(quotes), leftover, err)
+	}
+}
+
+func TestQuoteLengthV5(t *testing.T) {
+	quote := fixtureQuote(t)
+	signedData := quote[tdReportEnd:]
+	for _, tc := range []struct {
+		bodyType uint16
+		bodySize uint32
+	}{
+		{BodyTypeTDX10, tdReportSize},
+		{BodyTypeTDX15, tdReportV15Size},
+	} {
+		v5 := bytes.Clone(quote[:quoteHeaderSize])
+		binary.LittleEndian.PutUint16(v5[0:2], quoteVersion5)
+		v5 = binary.LittleEndian.AppendUint16(v5, tc.bodyType)
+		v5 = binary.LittleEndian.AppendUint32(v5, tc.bodySize)
+		v5 = append(v5, quote[tdReportStart:tdReportEnd]...)
+		v5 = append(v5, make([]byte, int(tc.bodySize)-tdReportSize)...)
+		v5 = append(v5, signedData...)
+
+		want := quoteV5BodyStart + int(tc.bodySize) + len(signedData)
+		if n, err := QuoteLength(append(bytes.Clone(v5), "trailer"...)); n != want || err != nil {
+			t.Errorf("QuoteLength(V5 body type %d) = %d, %v; want %d", tc.bodyType, n, err, want)
+		}
+		if _, err := QuoteLength(v5[:len(v5)-1]); !errors.Is(err, ErrTooShort) {
+			t.Errorf("QuoteLength(truncated V5 body type %d) error = %v, want ErrTooShort", tc.bodyType, err)
+		}
+
+		binary.LittleEndian.PutUint32(v5[quoteHeaderSize+2:], tc.bodySize+1)
+		if _, err := QuoteLength(v5); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("QuoteLength(V5 with body size %d) error = %v, want ErrUnsupportedVersion", tc.bodySize+1, err)
+		}
+	}
+}
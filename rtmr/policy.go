@@ -0,0 +1,218 @@
+package rtmr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy lists the measurements a TD may have. Each measurement field is a
+// list of allowed hex values, so several images can be accepted at once
+// (e.g. during a rolling update); an omitted field is not checked. An entry
+// ending in * pins only the bytes before it (a prefix match), and an entry
+// "@file" stands for the values listed in file, one per line, relative to
+// the policy file.
+//
+//	mrTd: [<hex>, "@mrtd-builds.txt"]
+//	mrConfigId: [<hex>]
+//	rtmr0: [<hex>, "<hex prefix>*"]
+//	rtmr1: [<hex>]
+//	rtmr2: [<hex>]
+//	rtmr3: [<hex>]
+//	tdAttributes:
+//	  require: [SEPT_VE_DISABLE]
+//	  forbid: [DEBUG]
+type Policy struct {
+	MrTd         []string          `yaml:"mrTd"`
+	MrConfigId   []string          `yaml:"mrConfigId"`
+	Rtmr0        []string          `yaml:"rtmr0"`
+	Rtmr1        []string          `yaml:"rtmr1"`
+	Rtmr2        []string          `yaml:"rtmr2"`
+	Rtmr3        []string          `yaml:"rtmr3"`
+	TdAttributes *AttributesPolicy `yaml:"tdAttributes"`
+}
+
+// AttributesPolicy constrains TD_ATTRIBUTES bits by name (see
+// TDAttributes.Names).
+type AttributesPolicy struct {
+	Require []string `yaml:"require"`
+	Forbid  []string `yaml:"forbid"`
+}
+
+// PolicyResult is the outcome of one policy rule.
+type PolicyResult struct {
+	Rule   string
+	Pass   bool
+	Detail string
+}
+
+// LoadPolicy reads and validates a YAML policy file. @file entries are
+// read relative to the policy file's directory.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePolicy(data, filepath.Dir(path))
+}
+
+// ParsePolicy parses and validates a YAML policy. Unknown keys are rejected
+// so a misspelled field does not silently disable a rule. @file entries are
+// read relative to the working directory.
+func ParsePolicy(data []byte) (*Policy, error) {
+	return parsePolicy(data, ".")
+}
+
+func parsePolicy(data []byte, dir string) (*Policy, error) {
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parsing policy: %v", err)
+	}
+	for _, field := range []*[]string{&p.MrTd, &p.MrConfigId, &p.Rtmr0, &p.Rtmr1, &p.Rtmr2, &p.Rtmr3} {
+		expanded, err := expandAllowed(*field, dir)
+		if err != nil {
+			return nil, err
+		}
+		*field = expanded
+	}
+	for _, rule := range p.measurementRules(&TDReport{}) {
+		for _, value := range rule.allowed {
+			if _, err := parseAllowed(value); err != nil {
+				return nil, fmt.Errorf("%s: %v", rule.name, err)
+			}
+		}
+	}
+	if p.TdAttributes != nil {
+		for _, name := range append(append([]string{}, p.TdAttributes.Require...), p.TdAttributes.Forbid...) {
+			if _, ok := tdAttributeByName(name); !ok {
+				return nil, fmt.Errorf("tdAttributes: unknown attribute %q", name)
+			}
+		}
+	}
+	return &p, nil
+}
+
+type measurementRule struct {
+	name    string
+	allowed []string
+	actual  []byte
+}
+
+func (p *Policy) measurementRules(r *TDReport) []measurementRule {
+	return []measurementRule{
+		{"mrTd", p.MrTd, r.MrTd[:]},
+		{"mrConfigId", p.MrConfigId, r.MrConfigId[:]},
+		{"rtmr0", p.Rtmr0, r.Rtmr0[:]},
+		{"rtmr1", p.Rtmr1, r.Rtmr1[:]},
+		{"rtmr2", p.Rtmr2, r.Rtmr2[:]},
+		{"rtmr3", p.Rtmr3, r.Rtmr3[:]},
+	}
+}
+
+// Evaluate checks r against every rule in the policy, in a fixed order.
+func (p *Policy) Evaluate(r *TDReport) []PolicyResult {
+	var results []PolicyResult
+	for _, rule := range p.measurementRules(r) {
+		if len(rule.allowed) == 0 {
+			continue
+		}
+		res := PolicyResult{Rule: rule.name, Detail: fmt.Sprintf("%x not in the %d allowed values", rule.actual, len(rule.allowed))}
+		for i, value := range rule.allowed {
+			if allowed, _ := parseAllowed(value); allowed.matches(rule.actual) {
+				res.Pass, res.Detail = true, fmt.Sprintf("matches allowed value %d of %d: %s", i+1, len(rule.allowed), value)
+				break
+			}
+		}
+		results = append(results, res)
+	}
+
+	if p.TdAttributes != nil {
+		attributes := r.Attributes()
+		for _, name := range p.TdAttributes.Require {
+			bit, _ := tdAttributeByName(name)
+			results = append(results, PolicyResult{Rule: "tdAttributes require " + tdAttributeNames[bit], Pass: attributes&bit != 0})
+		}
+		for _, name := range p.TdAttributes.Forbid {
+			bit, _ := tdAttributeByName(name)
+			results = append(results, PolicyResult{Rule: "tdAttributes forbid " + tdAttributeNames[bit], Pass: attributes&bit == 0})
+		}
+	}
+	return results
+}
+
+// expandAllowed replaces each "@file" entry of a measurement list with the
+// values in the file: one per line, blank lines and # comments skipped.
+func expandAllowed(values []string, dir string) ([]string, error) {
+	var expanded []string
+	for _, value := range values {
+		name, ok := strings.CutPrefix(strings.TrimSpace(value), "@")
+		if !ok {
+			expanded = append(expanded, value)
+			continue
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading allowed values: %v", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				expanded = append(expanded, line)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// allowedValue is one parsed entry of a measurement list: a whole
+// measurement, or with prefix set only its first bytes.
+type allowedValue struct {
+	value  []byte
+	prefix bool
+}
+
+func (a allowedValue) matches(actual []byte) bool {
+	if a.prefix {
+		return bytes.HasPrefix(actual, a.value)
+	}
+	return bytes.Equal(actual, a.value)
+}
+
+// parseAllowed decodes a measurement list entry: a 48-byte measurement as
+// hex, with an optional 0x prefix, or fewer bytes followed by * for a
+// prefix match.
+func parseAllowed(s string) (allowedValue, error) {
+	text, prefix := strings.CutSuffix(strings.TrimSpace(s), "*")
+	b, err := hex.DecodeString(strings.TrimPrefix(text, "0x"))
+	if err != nil {
+		return allowedValue{}, fmt.Errorf("invalid hex %q: %v", s, err)
+	}
+	switch {
+	case prefix && (len(b) == 0 || len(b) > measurementSize):
+		return allowedValue{}, fmt.Errorf("prefix %q must be 1 to %d bytes", s, measurementSize)
+	case !prefix && len(b) != measurementSize:
+		return allowedValue{}, fmt.Errorf("expected %d bytes, got %d (end a shorter prefix with *)", measurementSize, len(b))
+	}
+	return allowedValue{value: b, prefix: prefix}, nil
+}
+
+// tdAttributeByName returns the bit for an attribute name as printed by
+// TDAttributes.Names.
+func tdAttributeByName(name string) (TDAttributes, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for bit, n := range tdAttributeNames {
+		if n == name {
+			return bit, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package rtmr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyAllowList(t *testing.T) {
+	var r TDReport
+	for i := range r.MrTd {
+		r.MrTd[i] = byte(i)
+	}
+	r.Rtmr0[0], r.Rtmr0[1] = 0xab, 0xcd
+	mrtd := fmt.Sprintf("%x", r.MrTd[:])
+
+	dir := t.TempDir()
+	builds := fmt.Sprintf("# week 42 builds\n%x\n\n%s\n", make([]byte, 48), mrtd)
+	if err := os.WriteFile(filepath.Join(dir, "builds.txt"), []byte(builds), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policyPath := filepath.Join(dir, "policy.yaml")
+	policy := "mrTd: [\"@builds.txt\"]\nrtmr0: [\"ffff*\", \"0xabcd*\"]\nrtmr1: [\"00*\"]\n"
+	if err := os.WriteFile(policyPath, []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadPolicy(policyPath)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+	if len(p.MrTd) != 2 {
+		t.Fatalf("mrTd has %d values from builds.txt, want 2", len(p.MrTd))
+	}
+	want := map[string]string{
+		"mrTd":  "matches allowed value 2 of 2: " + mrtd,
+		"rtmr0": "matches allowed value 2 of 2: 0xabcd*",
+		"rtmr1": "matches allowed value 1 of 1: 00*",
+	}
+	results := p.Evaluate(&r)
+	if len(results) != len(want) {
+		t.Fatalf("Evaluate() = %d results, want %d", len(results), len(want))
+	}
+	for _, res := range results {
+		if !res.Pass || res.Detail != want[res.Rule] {
+			t.Errorf("%s: pass %v, detail %q; want %q", res.Rule, res.Pass, res.Detail, want[res.Rule])
+		}
+	}
+
+	r.Rtmr0[1] = 0
+	for _, res := range p.Evaluate(&r) {
+		if res.Rule == "rtmr0" && res.Pass {
+			t.Error("rtmr0 passed after its pinned prefix changed")
+		}
+	}
+}
+
+func TestParsePolicyInvalidEntries(t *testing.T) {
+	for _, policy := range []string{
+		"rtmr0: [\"abcd\"]",
+		"rtmr0: [\"*\"]",
+		"rtmr0: [\"zz*\"]",
+		fmt.Sprintf("rtmr0: [\"%x*\"]", make([]byte, 49)),
+		"rtmr0: [\"@does-not-exist.txt\"]",
+	} {
+		if _, err := ParsePolicy([]byte(policy)); err == nil {
+			t.Errorf("ParsePolicy(%q) accepted an invalid entry", policy)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package rtmr
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// UEFIMeasurement is one event measured into RTMR0 by the firmware, given
+// either as the SHA-384 digest that was extended or as the raw event data,
+// which is hashed first.
+type UEFIMeasurement struct {
+	Digest []byte
+	Data   []byte
+}
+
+// digest returns the SHA-384 digest extended for the event.
+func (m UEFIMeasurement) digest() ([]byte, error) {
+	switch {
+	case m.Digest != nil && m.Data != nil:
+		return nil, fmt.Errorf("both a digest and data are given")
+	case m.Digest != nil:
+		if len(m.Digest) != sha512.Size384 {
+			return nil, fmt.Errorf("digest is %d bytes, expected %d", len(m.Digest), sha512.Size384)
+		}
+		return m.Digest, nil
+	case m.Data != nil:
+		d := sha512.Sum384(m.Data)
+		return d[:], nil
+	}
+	return nil, fmt.Errorf("neither a digest nor data is given")
+}
+
+// PredictRTMR0 computes the RTMR0 value that the ordered events produce,
+// for comparison with a quote's Rtmr0 before an image is deployed.
+func PredictRTMR0(events []UEFIMeasurement) ([48]byte, error) {
+	digests := make([][]byte, len(events))
+	for i, e := range events {
+		d, err := e.digest()
+		if err != nil {
+			return [48]byte{}, fmt.Errorf("event %d: %v", i, err)
+		}
+		digests[i] = d
+	}
+	return ReplayRTMR(digests), nil
+}
+
+// ParseUEFIMeasurements decodes a JSON array of events in measurement
+// order. Each event is an object with either a "digest" (the 48-byte
+// SHA-384 digest) or "data" (the raw event bytes), as hex:
+//
+//	[{"digest": "00..."}, {"data": "4546495f..."}]
+func ParseUEFIMeasurements(data []byte) ([]UEFIMeasurement, error) {
+	var entries []struct {
+		Digest *string `json:"digest"`
+		Data   *string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid events JSON: %v", err)
+	}
+
+	events := make([]UEFIMeasurement, len(entries))
+	for i, entry := range entries {
+		var err error
+		if entry.Digest != nil {
+			if events[i].Digest, err = decodeHexField(*entry.Digest); err != nil {
+				return nil, fmt.Errorf("event %d: digest: %v", i, err)
+			}
+		}
+		if entry.Data != nil {
+			if events[i].Data, err = decodeHexField(*entry.Data); err != nil {
+				return nil, fmt.Errorf("event %d: data: %v", i, err)
+			}
+		}
+	}
+	return events, nil
+}
+
+// decodeHexField decodes a hex string, with an optional 0x prefix, to a
+// non-nil slice so that an empty value still counts as given.
+func decodeHexField(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %v", err)
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, nil
+}
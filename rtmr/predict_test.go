@@ -0,0 +1,37 @@
+package rtmr
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPredictRTMR0(t *testing.T) {
+	events, err := ParseUEFIMeasurements([]byte(`[
+		{"digest": "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f"},
+		{"data": "45565f4546495f414354494f4e"}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseUEFIMeasurements() error = %v", err)
+	}
+
+	got, err := PredictRTMR0(events)
+	if err != nil {
+		t.Fatalf("PredictRTMR0() error = %v", err)
+	}
+	const want = "e7ff5930fccd122f79279b48b6a292ad9063706bc32d47578ea6ee4503edcacf98c7bea0f6f76279c869ac3c6210baee"
+	if hex.EncodeToString(got[:]) != want {
+		t.Errorf("PredictRTMR0() = %x, want %s", got, want)
+	}
+}
+
+func TestPredictRTMR0InvalidEvent(t *testing.T) {
+	for _, events := range [][]UEFIMeasurement{
+		{{}},
+		{{Digest: make([]byte, 32)}},
+		{{Digest: make([]byte, 48), Data: []byte("x")}},
+	} {
+		if _, err := PredictRTMR0(events); err == nil {
+			t.Errorf("PredictRTMR0(%v) accepted an invalid event", events)
+		}
+	}
+}
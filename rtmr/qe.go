@@ -0,0 +1,115 @@
+package rtmr
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/google/go-tdx-guest/proto/tdx"
+)
+
+// QEReport holds the Quoting Enclave's certification data from the signed
+// portion of a QuoteV4: the QE's own SGX report, the PCK signature over it,
+// and the PCK certificate chain that anchors the attestation key to Intel.
+type QEReport struct {
+	MrEnclave []byte
+	MrSigner  []byte
+	IsvProdID uint32
+	IsvSvn    uint32
+	// CpuSvn is the CPUSVN the QE report was produced under.
+	CpuSvn CPUSVN
+	// Signature is the PCK key's signature over the QE report.
+	Signature []byte
+	// PCKCertChain is the PEM-encoded chain (PCK, intermediate, root).
+	PCKCertChain []byte
+}
+
+// parseQEReport extracts the QE report certification data from a QuoteV4.
+func parseQEReport(quote *tdx.QuoteV4) (*QEReport, error) {
+	certData := quote.GetSignedData().GetCertificationData()
+	qeData := certData.GetQeReportCertificationData()
+	if qeData == nil {
+		return nil, fmt.Errorf("no QE report certification data in quote (certification data type %d)", certData.GetCertificateDataType())
+	}
+	qeReport := qeData.GetQeReport()
+	if qeReport == nil {
+		return nil, fmt.Errorf("no QE report in certification data")
+	}
+	qe := &QEReport{
+		MrEnclave:    qeReport.GetMrEnclave(),
+		MrSigner:     qeReport.GetMrSigner(),
+		IsvProdID:    qeReport.GetIsvProdId(),
+		IsvSvn:       qeReport.GetIsvSvn(),
+		Signature:    qeData.GetQeReportSignature(),
+		PCKCertChain: qeData.GetPckCertificateChainData().GetPckCertChain(),
+	}
+	copy(qe.CpuSvn[:], qeReport.GetCpuSvn())
+	return qe, nil
+}
+
+// CertCount returns the number of PEM certificates in the PCK chain.
+func (q *QEReport) CertCount() int {
+	return CountCertificates(q.PCKCertChain)
+}
+
+// CountCertificates returns the number of PEM certificates in chain.
+func CountCertificates(chain []byte) int {
+	n := 0
+	rest := chain
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return n
+		}
+		if block.Type == "CERTIFICATE" {
+			n++
+		}
+	}
+}
+
+// ParseCertificates parses the PEM certificates of chain, such as a PCK
+// chain, in order. Blocks of other types are skipped.
+func ParseCertificates(chain []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := chain
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing PCK chain certificate %d: %v", len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// CAFingerprints returns the SHA-256 fingerprints of the CA certificates of
+// the PCK chain, that is every certificate after the leaf, in chain order
+// (intermediate, then root).
+func (q *QEReport) CAFingerprints() [][sha256.Size]byte {
+	var fingerprints [][sha256.Size]byte
+	rest := q.PCKCertChain
+	leaf := true
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return fingerprints
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if !leaf {
+			fingerprints = append(fingerprints, sha256.Sum256(block.Bytes))
+		}
+		leaf = false
+	}
+}
@@ -0,0 +1,265 @@
+// Package rtmr extracts the runtime measurement registers (RTMRs) and related
+// measurements from Intel TDX attestation quotes.
+//
+// ParseQuote accepts a quote in any of the encodings seen on GCP: a
+// protobuf-encoded tdx.QuoteV4 (as returned by GetAttestation), a raw ABI
+// QuoteV4 or QuoteV5, or, as a last resort, any buffer long enough to hold a
+// header and TD Report at their fixed offsets.
+package rtmr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/go-tdx-guest/abi"
+	"github.com/google/go-tdx-guest/proto/tdx"
+	"google.golang.org/protobuf/proto"
+)
+
+// Format identifies how ParseQuote decoded a quote.
+type Format int
+
+const (
+	// FormatProtoV4 is a protobuf-encoded tdx.QuoteV4.
+	FormatProtoV4 Format = iota + 1
+	// FormatRawV4 is a raw ABI QuoteV4, converted to protobuf by abi.QuoteToProto.
+	FormatRawV4
+	// FormatRawV5 is a raw ABI QuoteV5.
+	FormatRawV5
+	// FormatRaw is a raw quote the structured decoders rejected; the TD
+	// Report was read from its fixed V4 offset.
+	FormatRaw
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatProtoV4:
+		return "protobuf QuoteV4"
+	case FormatRawV4:
+		return "raw QuoteV4"
+	case FormatRawV5:
+		return "raw QuoteV5"
+	case FormatRaw:
+		return "raw quote"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// IntelQEVendorID is the header QE Vendor ID of quotes produced by Intel's
+// standard Quoting Enclave.
+var IntelQEVendorID = []byte{0x93, 0x9a, 0x72, 0x33, 0xf7, 0x9c, 0x4c, 0xa9, 0x94, 0x0a, 0x0d, 0xb3, 0x95, 0x7f, 0x06, 0x07}
+
+// Header TEE types.
+const (
+	teeTypeSGX = 0x00000000
+	teeTypeTDX = 0x00000081
+)
+
+// ErrTooShort is wrapped by errors from ParseQuote and the other readers
+// when the data ends before a part of the quote it declares.
+var ErrTooShort = errors.New("quote too short")
+
+// ErrUnsupportedVersion is wrapped by errors from ParseQuote and the other
+// readers when the quote version or QuoteV5 body type is not one they
+// decode.
+var ErrUnsupportedVersion = errors.New("unsupported quote version")
+
+// ErrWrongTeeType is wrapped by errors from ParseQuote when the header TEE
+// type is not TDX, so the body is not a TD Report.
+var ErrWrongTeeType = errors.New("not a TDX quote")
+
+// ErrNoTdBody is wrapped by errors from ParseQuote when a protobuf quote
+// has no TD quote body.
+var ErrNoTdBody = errors.New("no TD Quote Body found in quote")
+
+// ErrSGXQuote is returned by ParseQuote for an SGX quote. Its body is an SGX
+// enclave report, not a TD Report, so there are no RTMRs to extract. It
+// wraps ErrWrongTeeType.
+var ErrSGXQuote = fmt.Errorf("%w: this is an SGX quote; RTMRs are not present", ErrWrongTeeType)
+
+// checkHeader rejects a quote header that is inconsistent with reading the
+// bodySize bytes after it as a TD Report: SGX quotes, QuoteV3 (which is
+// SGX-only), QuoteV4 and QuoteV5 headers with a TEE type other than TDX, and
+// bodies shorter than a TD Report. Only
+// headers with a known quote version are considered, so that arbitrary bytes
+// reaching the fixed-offset fallback are not mistaken for a bad quote.
+//
+// A quote body has no REPORTTYPE field (it is part of the TDREPORT_STRUCT
+// only), so the header TEE type is what identifies the body as a TD Report.
+func checkHeader(header *tdx.Header, bodySize int) error {
+	version, tee := header.GetVersion(), header.GetTeeType()
+	switch version {
+	case 3, 4, quoteVersion5:
+	default:
+		return nil
+	}
+	if tee == teeTypeSGX {
+		return ErrSGXQuote
+	}
+	if version == 3 {
+		return fmt.Errorf("%w: QuoteV3 header has TEE type 0x%08x, but QuoteV3 is SGX-only", ErrWrongTeeType, tee)
+	}
+	if tee != teeTypeTDX {
+		return fmt.Errorf("%w: QuoteV%d header has TEE type 0x%08x, expected TDX (0x%08x)", ErrWrongTeeType, version, tee, teeTypeTDX)
+	}
+	if bodySize < tdReportSize {
+		return fmt.Errorf("%w: QuoteV%d header declares a TD Report, but only %d of its %d bytes are present", ErrTooShort, version, bodySize, tdReportSize)
+	}
+	return nil
+}
+
+// Report is the result of parsing a quote.
+type Report struct {
+	TDReport
+
+	// Format records which decoder accepted the quote.
+	Format Format
+	// Header is the decoded quote header. It is nil only for FormatRaw
+	// inputs too short to hold one.
+	Header *tdx.Header
+	// Quote is the full protobuf quote for FormatProtoV4 and FormatRawV4.
+	Quote *tdx.QuoteV4
+	// BodyType is the QuoteV5 body descriptor type (FormatRawV5 only).
+	BodyType uint16
+	// QE is the Quoting Enclave certification data. It is set only for
+	// QuoteV4 inputs that carry it.
+	QE *QEReport
+	// CertData is the type of the certification data and the PCK chain it
+	// carries. It is nil when the quote has no readable certification data.
+	CertData *CertificationData
+
+	// body is the raw TD Report region for inputs without a Quote.
+	body []byte
+}
+
+// ParseQuote decodes quoteData and extracts its TD Report. The encodings are
+// tried in order: protobuf QuoteV4, raw QuoteV4 via the abi package, raw
+// QuoteV5, and finally fixed-offset extraction.
+func ParseQuote(quoteData []byte) (*Report, error) {
+	// Try to parse as protobuf QuoteV4 first (if it's from GetAttestation)
+	var quote tdx.QuoteV4
+	if err := proto.Unmarshal(quoteData, &quote); err == nil {
+		if err := checkHeader(quote.GetHeader(), tdReportSize); err != nil {
+			return nil, err
+		}
+		return fromQuoteV4(&quote, FormatProtoV4)
+	}
+
+	// Reject SGX quotes, other TEE types and truncated bodies before any
+	// decoder reads the body as a TD Report
+	if header, err := parseRawHeader(quoteData); err == nil {
+		bodyStart := tdReportStart
+		if header.GetVersion() == quoteVersion5 {
+			bodyStart = quoteV5BodyStart
+		}
+		if err := checkHeader(header, len(quoteData)-bodyStart); err != nil {
+			return nil, err
+		}
+		if err := checkVersionSkew(quoteData); err != nil {
+			return nil, err
+		}
+	}
+
+	// Try to parse as raw quote using ABI package
+	if quoteProto, err := abi.QuoteToProto(quoteData); err == nil {
+		if q4, ok := quoteProto.(*tdx.QuoteV4); ok {
+			return fromQuoteV4(q4, FormatRawV4)
+		}
+	}
+
+	// The abi package only understands QuoteV4, so decode V5 directly
+	if rawQuoteVersion(quoteData) == quoteVersion5 {
+		q, err := parseQuoteV5(quoteData)
+		if err != nil {
+			return nil, err
+		}
+		tdReport, err := q.tdReport()
+		if err != nil {
+			return nil, err
+		}
+		certData, _ := parseCertificationData(quoteData)
+		return &Report{TDReport: *tdReport, Format: FormatRawV5, Header: q.header, BodyType: q.bodyType, CertData: certData, body: q.body}, nil
+	}
+
+	// If ABI parsing failed, fall back to fixed-offset extraction
+	tdReport, err := extractTDReportFromRawQuote(quoteData)
+	if err != nil {
+		return nil, err
+	}
+	header, _ := parseRawHeader(quoteData)
+	certData, _ := parseCertificationData(quoteData)
+	return &Report{TDReport: *tdReport, Format: FormatRaw, Header: header, CertData: certData, body: quoteData[tdReportStart:tdReportEnd]}, nil
+}
+
+// ParseHeader decodes only the header of a quote, without looking at the
+// body, so it also works on quotes whose body does not parse. A protobuf
+// QuoteV4 gives its header field; anything else is read as the raw 48-byte
+// header shared by every quote version.
+func ParseHeader(quoteData []byte) (*tdx.Header, error) {
+	var quote tdx.QuoteV4
+	if err := proto.Unmarshal(quoteData, &quote); err == nil && quote.GetHeader() != nil {
+		return quote.GetHeader(), nil
+	}
+	return parseRawHeader(quoteData)
+}
+
+// HasServTdHash reports whether the quote carries SERVTD_HASH. Only TDX 1.5
+// QuoteV5 bodies have MRSERVICETD; for every other quote TDReport.ServTdHash
+// is zero because the quote says nothing about service TDs.
+func (r *Report) HasServTdHash() bool {
+	return r.Format == FormatRawV5 && r.BodyType == BodyTypeTDX15
+}
+
+// TDReportBytes returns the TD Report region of the quote in ABI layout: the
+// 584 bytes following the header of a raw QuoteV4. For protobuf quotes the
+// bytes are re-encoded from the parsed body, which gives the same result. A
+// QuoteV5 TDX 1.5 body is returned whole (648 bytes).
+func (r *Report) TDReportBytes() ([]byte, error) {
+	if r.Quote == nil {
+		return append([]byte(nil), r.body...), nil
+	}
+	b, err := abi.TdQuoteBodyToAbiBytes(r.Quote.GetTdQuoteBody())
+	if err != nil {
+		return nil, fmt.Errorf("could not convert TD quote body to ABI bytes: %v", err)
+	}
+	if len(b) != tdReportSize {
+		return nil, fmt.Errorf("TD quote body is %d bytes, expected %d", len(b), tdReportSize)
+	}
+	return b, nil
+}
+
+// QuoteToRawBytes serializes a QuoteV4, such as one decoded from protobuf,
+// to the raw ABI form of the quote: the header and TD quote body (the
+// SignedPayload), the signed-data size, the signed data and any extra bytes
+// that followed it. Every encoding of a quote thus yields the same bytes,
+// those of the raw quote it was decoded from.
+func QuoteToRawBytes(q *tdx.QuoteV4) ([]byte, error) {
+	raw, err := abi.QuoteToAbiBytes(q)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert quote to ABI bytes: %v", err)
+	}
+	end := tdReportEnd + signedDataSizeLen + int(q.GetSignedDataSize())
+	if got := len(raw) - len(q.GetExtraBytes()); got != end {
+		return nil, fmt.Errorf("signed data is %d bytes, but the quote's size field says %d", got-tdReportEnd-signedDataSizeLen, q.GetSignedDataSize())
+	}
+	return raw, nil
+}
+
+func fromQuoteV4(quote *tdx.QuoteV4, format Format) (*Report, error) {
+	tdQuoteBody := quote.GetTdQuoteBody()
+	if tdQuoteBody == nil {
+		return nil, ErrNoTdBody
+	}
+	// Certification data is optional for our purposes; a quote without it
+	// still yields its RTMRs.
+	qe, _ := parseQEReport(quote)
+	return &Report{
+		TDReport: *fromQuoteBody(tdQuoteBody),
+		Format:   format,
+		Header:   quote.GetHeader(),
+		Quote:    quote,
+		QE:       qe,
+		CertData: certificationDataFromProto(quote),
+	}, nil
+}
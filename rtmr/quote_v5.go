@@ -0,0 +1,199 @@
+package rtmr
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/go-tdx-guest/proto/tdx"
+)
+
+// QuoteV5 layout. The header is the same as V4, but it is followed by a body
+// descriptor (type + size) so that TDX 1.0 and TDX 1.5 bodies can be told
+// apart. See the Intel TDX DCAP quote spec, "Quote Format V5".
+const (
+	quoteVersion5 = 5
+
+	bodyDescriptorSize = 6 // body type (2) + body size (4)
+	quoteV5BodyStart   = quoteHeaderSize + bodyDescriptorSize
+
+	// BodyTypeTDX10 is a TD Report for TDX 1.0 (same layout as V4).
+	BodyTypeTDX10 = 2
+	// BodyTypeTDX15 is a TD Report for TDX 1.5.
+	BodyTypeTDX15 = 3
+
+	tdReportV15Size  = 648 // tdReportSize + TEE_TCB_SVN_2 (16) + MRSERVICETD (48)
+	offMrServiceTdV5 = 0x258
+)
+
+// quoteV5 is a QuoteV5 split into its header and TD Report body. go-tdx-guest
+// has no QuoteV5 proto, so only the parts we need are decoded.
+type quoteV5 struct {
+	header   *tdx.Header
+	bodyType uint16
+	body     []byte
+}
+
+// rawQuoteVersion returns the version field of a raw quote header, or 0 if
+// the data is too short to contain one.
+func rawQuoteVersion(quoteData []byte) uint16 {
+	if len(quoteData) < 2 {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(quoteData[0:2])
+}
+
+// parseRawHeader decodes the 48-byte quote header shared by V4 and V5 into
+// the go-tdx-guest header proto.
+func parseRawHeader(b []byte) (*tdx.Header, error) {
+	if len(b) < quoteHeaderSize {
+		return nil, fmt.Errorf("%w: %d bytes, need %d for the header", ErrTooShort, len(b), quoteHeaderSize)
+	}
+	return &tdx.Header{
+		Version:            uint32(binary.LittleEndian.Uint16(b[0:2])),
+		AttestationKeyType: uint32(binary.LittleEndian.Uint16(b[2:4])),
+		TeeType:            binary.LittleEndian.Uint32(b[4:8]),
+		PceSvn:             b[8:10],
+		QeSvn:              b[10:12],
+		QeVendorId:         b[12:28],
+		UserData:           b[28:48],
+	}, nil
+}
+
+// rawHeaderBytes encodes a header proto in the 48-byte ABI layout, the
+// inverse of parseRawHeader. Unlike abi.HeaderToAbiBytes it accepts any
+// attestation key type, so that quotes signed with a P-384 key can be
+// checked.
+func rawHeaderBytes(h *tdx.Header) ([]byte, error) {
+	if h == nil {
+		return nil, fmt.Errorf("no quote header")
+	}
+	for _, f := range []struct {
+		name  string
+		value []byte
+		size  int
+	}{{"PCE SVN", h.GetPceSvn(), 2}, {"QE SVN", h.GetQeSvn(), 2}, {"QE vendor ID", h.GetQeVendorId(), 16}, {"user data", h.GetUserData(), 20}} {
+		if len(f.value) != f.size {
+			return nil, fmt.Errorf("header %s is %d bytes, expected %d", f.name, len(f.value), f.size)
+		}
+	}
+	b := make([]byte, quoteHeaderSize)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(h.GetVersion()))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(h.GetAttestationKeyType()))
+	binary.LittleEndian.PutUint32(b[4:8], h.GetTeeType())
+	copy(b[8:10], h.GetPceSvn())
+	copy(b[10:12], h.GetQeSvn())
+	copy(b[12:28], h.GetQeVendorId())
+	copy(b[28:48], h.GetUserData())
+	return b, nil
+}
+
+// minSignedDataSize is the smallest signed data a quote can carry: a P-256
+// signature and attestation key and an empty certification data header.
+const minSignedDataSize = 64 + 64 + certDataHeaderSize
+
+// signedDataFits reports whether a plausible signed-data size field is at
+// offset and the signed data it declares fits in quoteData.
+func signedDataFits(quoteData []byte, offset int) bool {
+	if len(quoteData) < offset+signedDataSizeLen {
+		return false
+	}
+	size := uint64(binary.LittleEndian.Uint32(quoteData[offset : offset+signedDataSizeLen]))
+	return size >= minSignedDataSize && uint64(offset)+signedDataSizeLen+size <= uint64(len(quoteData))
+}
+
+// v5BodyLayout reports whether quoteData holds a valid QuoteV5 body
+// descriptor after the header, followed by the body and signed data it
+// implies, whatever the header version says.
+func v5BodyLayout(quoteData []byte) (bodyType uint16, bodySize int, ok bool) {
+	if len(quoteData) < quoteV5BodyStart {
+		return 0, 0, false
+	}
+	bodyType = binary.LittleEndian.Uint16(quoteData[quoteHeaderSize : quoteHeaderSize+2])
+	bodySize = int(binary.LittleEndian.Uint32(quoteData[quoteHeaderSize+2 : quoteV5BodyStart]))
+	switch {
+	case bodyType == BodyTypeTDX10 && bodySize == tdReportSize:
+	case bodyType == BodyTypeTDX15 && bodySize == tdReportV15Size:
+	default:
+		return 0, 0, false
+	}
+	return bodyType, bodySize, signedDataFits(quoteData, quoteV5BodyStart+bodySize)
+}
+
+// checkVersionSkew rejects a raw quote whose header version does not match
+// the layout of what follows it, as when a buggy producer puts a V4 header
+// on a V5 body. The fixed-offset fallback would otherwise read the TD
+// Report 6 bytes off and return wrong RTMRs without complaint. A quote is
+// only rejected when its own version's layout does not hold and the other
+// version's does, so truncated or unusual quotes are left to the decoders.
+func checkVersionSkew(quoteData []byte) error {
+	switch rawQuoteVersion(quoteData) {
+	case 4:
+		if signedDataFits(quoteData, tdReportEnd) {
+			return nil
+		}
+		if bodyType, bodySize, ok := v5BodyLayout(quoteData); ok {
+			return fmt.Errorf("header says QuoteV4, but the body is laid out as QuoteV5 (body descriptor type %d, %d bytes, at offset %d, and the signed-data size after it fits): header and body versions do not match", bodyType, bodySize, quoteHeaderSize)
+		}
+	case quoteVersion5:
+		if _, _, ok := v5BodyLayout(quoteData); ok {
+			return nil
+		}
+		if signedDataFits(quoteData, tdReportEnd) {
+			return fmt.Errorf("header says QuoteV5, but there is no valid body descriptor at offset %d and the body is laid out as QuoteV4 (the signed-data size at offset %d fits): header and body versions do not match", quoteHeaderSize, tdReportEnd)
+		}
+	}
+	return nil
+}
+
+func parseQuoteV5(quoteData []byte) (*quoteV5, error) {
+	if len(quoteData) < quoteV5BodyStart {
+		return nil, fmt.Errorf("%w: QuoteV5 is %d bytes, need %d for the header and body descriptor", ErrTooShort, len(quoteData), quoteV5BodyStart)
+	}
+
+	header, err := parseRawHeader(quoteData)
+	if err != nil {
+		return nil, err
+	}
+	if header.GetVersion() != quoteVersion5 {
+		return nil, fmt.Errorf("%w: header version %d, expected a QuoteV5", ErrUnsupportedVersion, header.GetVersion())
+	}
+
+	bodyType := binary.LittleEndian.Uint16(quoteData[quoteHeaderSize : quoteHeaderSize+2])
+	bodySize := binary.LittleEndian.Uint32(quoteData[quoteHeaderSize+2 : quoteV5BodyStart])
+
+	var wantSize uint32
+	switch bodyType {
+	case BodyTypeTDX10:
+		wantSize = tdReportSize
+	case BodyTypeTDX15:
+		wantSize = tdReportV15Size
+	default:
+		return nil, fmt.Errorf("%w: QuoteV5 body type %d", ErrUnsupportedVersion, bodyType)
+	}
+	if bodySize != wantSize {
+		return nil, fmt.Errorf("QuoteV5 body type %d has size %d, expected %d", bodyType, bodySize, wantSize)
+	}
+	if uint64(len(quoteData)) < uint64(quoteV5BodyStart)+uint64(bodySize) {
+		return nil, fmt.Errorf("%w: QuoteV5 is %d bytes, too short for its %d-byte body", ErrTooShort, len(quoteData), bodySize)
+	}
+
+	return &quoteV5{
+		header:   header,
+		bodyType: bodyType,
+		body:     quoteData[quoteV5BodyStart : quoteV5BodyStart+int(bodySize)],
+	}, nil
+}
+
+// tdReport decodes the body. The first tdReportSize bytes share the V4
+// layout; a TDX 1.5 body additionally carries MRSERVICETD, which is the
+// quote's view of SERVTD_HASH.
+func (q *quoteV5) tdReport() (*TDReport, error) {
+	tdReport, err := parseTDQuoteBody(q.body[:tdReportSize])
+	if err != nil {
+		return nil, err
+	}
+	if q.bodyType == BodyTypeTDX15 {
+		copy(tdReport.ServTdHash[:], q.body[offMrServiceTdV5:offMrServiceTdV5+measurementSize])
+	}
+	return tdReport, nil
+}
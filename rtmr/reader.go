@@ -0,0 +1,79 @@
+package rtmr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxSignedDataSize bounds the signed data ReadQuote accepts. Real quotes
+// carry a few KiB of signature and certification data.
+const maxSignedDataSize = 1 << 20
+
+// ReadQuote reads one raw QuoteV4 or QuoteV5 from r and returns its bytes.
+// The length is taken from the header and the signed-data size field, so
+// exactly one quote is read and nothing after it. Protobuf and base64
+// quotes have no length prefix and cannot be read this way.
+func ReadQuote(r io.Reader) ([]byte, error) {
+	data := make([]byte, quoteHeaderSize)
+	if err := readFull(r, data, "header"); err != nil {
+		return nil, err
+	}
+	if v := rawQuoteVersion(data); v == quoteVersion5 {
+		data = append(data, make([]byte, bodyDescriptorSize)...)
+		if err := readFull(r, data[quoteHeaderSize:], "body descriptor"); err != nil {
+			return nil, err
+		}
+	}
+
+	sizeOffset, err := signedDataSizeOffset(data)
+	if err != nil {
+		return nil, err
+	}
+	header, err := parseRawHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHeader(header, sizeOffset-len(data)); err != nil {
+		return nil, err
+	}
+
+	start := len(data)
+	data = append(data, make([]byte, sizeOffset+signedDataSizeLen-start)...)
+	if err := readFull(r, data[start:], "TD Report"); err != nil {
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(data[sizeOffset:])
+	if size > maxSignedDataSize {
+		return nil, fmt.Errorf("signed data size %d exceeds the maximum of %d", size, maxSignedDataSize)
+	}
+	start = len(data)
+	data = append(data, make([]byte, size)...)
+	if err := readFull(r, data[start:], "signed data"); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ParseQuoteReader reads one raw quote from r with ReadQuote and parses it
+// with ParseQuote.
+func ParseQuoteReader(r io.Reader) (*Report, error) {
+	data, err := ReadQuote(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseQuote(data)
+}
+
+// readFull fills b from r, naming the quote part in errors.
+func readFull(r io.Reader, b []byte, part string) error {
+	if _, err := io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: truncated in %s", ErrTooShort, part)
+		}
+		return fmt.Errorf("reading quote %s: %v", part, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package rtmr
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestParseQuoteReader(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := QuoteLength(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trailing data after the quote must be left unread.
+	r := bytes.NewReader(append(bytes.Clone(raw[:n]), "trailer"...))
+	report, err := ParseQuoteReader(r)
+	if err != nil {
+		t.Fatalf("ParseQuoteReader() error = %v", err)
+	}
+	want, err := ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TDReport != want.TDReport {
+		t.Error("ParseQuoteReader() and ParseQuote() give different TD Reports")
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "trailer" {
+		t.Errorf("ParseQuoteReader() left %q unread, want %q", rest, "trailer")
+	}
+}
+
+func TestParseQuoteReaderTruncated(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 20, 700} {
+		if _, err := ParseQuoteReader(bytes.NewReader(raw[:n])); err == nil {
+			t.Errorf("ParseQuoteReader() accepted a quote truncated to %d bytes", n)
+		}
+	}
+}
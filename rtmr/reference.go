@@ -0,0 +1,137 @@
+package rtmr
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReferenceBundle is a signed set of reference measurements, stored as JSON
+// with both fields base64-encoded:
+//
+//	{"payload": "<base64>", "signature": "<base64>"}
+//
+// Payload is text of "name=hex" lines, where name is rtmr0 to rtmr3 or
+// mrtd; blank lines and lines starting with # are ignored. Signature is the
+// ed25519 signature over the payload bytes exactly as encoded.
+type ReferenceBundle struct {
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature"`
+}
+
+// ReferenceValues are the measurements of a verified ReferenceBundle. A nil
+// entry was not in the bundle and is not checked.
+type ReferenceValues struct {
+	RTMRs [4][]byte
+	MrTd  []byte
+}
+
+// ErrReferenceSignature is wrapped by errors from OpenReferenceBundle when
+// the bundle is unsigned or its signature does not verify with the pinned
+// key.
+var ErrReferenceSignature = errors.New("reference bundle signature invalid")
+
+// SignReferenceBundle signs payload with key and returns the JSON bundle.
+func SignReferenceBundle(payload []byte, key ed25519.PrivateKey) ([]byte, error) {
+	if _, err := parseReferenceValues(payload); err != nil {
+		return nil, err
+	}
+	return json.Marshal(ReferenceBundle{Payload: payload, Signature: ed25519.Sign(key, payload)})
+}
+
+// OpenReferenceBundle verifies the JSON bundle data against the pinned
+// public key and only then parses its payload. Unsigned and mis-signed
+// bundles are rejected with ErrReferenceSignature.
+func OpenReferenceBundle(data []byte, key ed25519.PublicKey) (*ReferenceValues, error) {
+	var bundle ReferenceBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("parsing reference bundle: %v", err)
+	}
+	if len(bundle.Signature) == 0 {
+		return nil, fmt.Errorf("%w: the bundle is not signed", ErrReferenceSignature)
+	}
+	if len(bundle.Signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: signature is %d bytes, expected %d", ErrReferenceSignature, len(bundle.Signature), ed25519.SignatureSize)
+	}
+	if !ed25519.Verify(key, bundle.Payload, bundle.Signature) {
+		return nil, fmt.Errorf("%w: it does not verify with the pinned public key", ErrReferenceSignature)
+	}
+	return parseReferenceValues(bundle.Payload)
+}
+
+// ParseReferencePublicKey parses the pinned key of a reference bundle: a
+// PEM "PUBLIC KEY" block (as written by openssl pkey -pubout) or the 32 raw
+// key bytes as hex.
+func ParseReferencePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is %T, not ed25519", key)
+		}
+		return edKey, nil
+	}
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("expected a PEM public key or %d bytes of hex: %v", ed25519.PublicKeySize, err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public key is %d bytes, expected %d", len(raw), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// parseReferenceValues parses the "name=hex" lines of a bundle payload.
+func parseReferenceValues(payload []byte) (*ReferenceValues, error) {
+	var values ReferenceValues
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("reference payload line %d: expected name=hex", n)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		var slot *[]byte
+		if name == "mrtd" {
+			slot = &values.MrTd
+		} else if i, err := strconv.Atoi(strings.TrimPrefix(name, "rtmr")); err == nil && strings.HasPrefix(name, "rtmr") && i >= 0 && i < len(values.RTMRs) {
+			slot = &values.RTMRs[i]
+		} else {
+			return nil, fmt.Errorf("reference payload line %d: unknown measurement %q (expected rtmr0-rtmr3 or mrtd)", n, name)
+		}
+		if *slot != nil {
+			return nil, fmt.Errorf("reference payload line %d: %s is given twice", n, name)
+		}
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(value), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("reference payload line %d: %s: invalid hex: %v", n, name, err)
+		}
+		if len(b) != measurementSize {
+			return nil, fmt.Errorf("reference payload line %d: %s is %d bytes, expected %d", n, name, len(b), measurementSize)
+		}
+		*slot, found = b, true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("reference payload has no measurements")
+	}
+	return &values, nil
+}
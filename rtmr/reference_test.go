@@ -0,0 +1,68 @@
+package rtmr
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReferenceBundle(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtmr2 := strings.Repeat("ab", 48)
+	payload := []byte("# golden build\nrtmr2=" + rtmr2 + "\nmrtd=0x" + strings.Repeat("01", 48) + "\n")
+	bundle, err := SignReferenceBundle(payload, priv)
+	if err != nil {
+		t.Fatalf("SignReferenceBundle() error = %v", err)
+	}
+
+	values, err := OpenReferenceBundle(bundle, pub)
+	if err != nil {
+		t.Fatalf("OpenReferenceBundle() error = %v", err)
+	}
+	if hex.EncodeToString(values.RTMRs[2]) != rtmr2 || values.RTMRs[0] != nil || len(values.MrTd) != 48 {
+		t.Errorf("OpenReferenceBundle() = %x, want rtmr2 and mrtd only", *values)
+	}
+
+	var b ReferenceBundle
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		t.Fatal(err)
+	}
+	b.Payload = []byte(strings.Replace(string(b.Payload), "ab", "cd", 1))
+	tampered, _ := json.Marshal(b)
+	b.Signature = nil
+	unsigned, _ := json.Marshal(b)
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	for name, c := range map[string]struct {
+		data []byte
+		key  ed25519.PublicKey
+	}{
+		"tampered payload": {tampered, pub},
+		"unsigned bundle":  {unsigned, pub},
+		"other key":        {bundle, otherKey},
+	} {
+		if _, err := OpenReferenceBundle(c.data, c.key); !errors.Is(err, ErrReferenceSignature) {
+			t.Errorf("OpenReferenceBundle(%s) error = %v, want ErrReferenceSignature", name, err)
+		}
+	}
+
+	if _, err := SignReferenceBundle([]byte("rtmr4="+rtmr2), priv); err == nil {
+		t.Error("SignReferenceBundle() accepted rtmr4")
+	}
+}
+
+func TestParseReferencePublicKey(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	key, err := ParseReferencePublicKey([]byte(hex.EncodeToString(pub)))
+	if err != nil || !key.Equal(pub) {
+		t.Errorf("ParseReferencePublicKey(hex) = %x, %v; want %x", key, err, pub)
+	}
+	if _, err := ParseReferencePublicKey([]byte("abcd")); err == nil {
+		t.Error("ParseReferencePublicKey() accepted a 2-byte key")
+	}
+}
@@ -0,0 +1,454 @@
+package rtmr
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-tdx-guest/proto/tdx"
+)
+
+// TDReport represents the runtime TD Report structure (584 bytes)
+// This is the actual TD Report that contains the runtime RTMR values
+// Based on TDX Architecture Specification
+type TDReport struct {
+	ReportType     [4]byte   // Report type (TDREPORT_STRUCT only, zero from quotes)
+	Reserved1      [12]byte  // Reserved
+	CpuSvn         [16]byte  // CPU SVN
+	TeeTcbInfoHash [48]byte  // TEE TCB Info Hash
+	TeeInfoHash    [48]byte  // TEE Info Hash
+	ReportData     [64]byte  // Report data
+	Reserved2      [32]byte  // Reserved
+	MacStruct      [256]byte // MAC structure
+	TeeTcbSvn      [16]byte  // TEE TCB SVN
+	MrSeam         [48]byte  // SEAM measurement
+	MrSignerSeam   [48]byte  // SEAM signer measurement
+	SeamAttributes [8]byte   // SEAM attributes
+	TdAttributes   [8]byte   // TD attributes
+	Xfam           [8]byte   // XFAM
+	MrTd           [48]byte  // TD measurement
+	MrConfigId     [48]byte  // Config ID
+	MrOwner        [48]byte  // Owner measurement
+	MrOwnerConfig  [48]byte  // Owner config
+	Rtmr0          [48]byte  // RTMR 0 - Runtime measurement register 0
+	Rtmr1          [48]byte  // RTMR 1 - Runtime measurement register 1
+	Rtmr2          [48]byte  // RTMR 2 - Runtime measurement register 2
+	Rtmr3          [48]byte  // RTMR 3 - Runtime measurement register 3
+	ServTdHash     [48]byte  // Service TD hash
+}
+
+// Raw quote layout. A TDX quote starts with a fixed header followed by the
+// TD Report (the "TD Quote Body"); signature and certification data follow.
+const (
+	quoteHeaderSize = 48
+	tdReportSize    = 584
+	tdReportStart   = quoteHeaderSize
+	tdReportEnd     = tdReportStart + tdReportSize
+
+	measurementSize = 48 // SHA-384 measurement registers
+)
+
+// Byte offsets of each field within the 584-byte TD Report, per the
+// "TD Quote Body" table of the Intel TDX DCAP quote spec.
+const (
+	offTeeTcbSvn      = 0x000
+	offMrSeam         = 0x010
+	offMrSignerSeam   = 0x040
+	offSeamAttributes = 0x070
+	offTdAttributes   = 0x078
+	offXfam           = 0x080
+	offMrTd           = 0x088
+	offMrConfigId     = 0x0B8
+	offMrOwner        = 0x0E8
+	offMrOwnerConfig  = 0x118
+	offRtmr0          = 0x148
+	offRtmr1          = 0x178
+	offRtmr2          = 0x1A8
+	offRtmr3          = 0x1D8
+	offReportData     = 0x208
+)
+
+// tdReportField describes where one TDReport member lives in the raw TD Report.
+type tdReportField struct {
+	name   string
+	offset int
+	size   int
+	field  func(*TDReport) []byte
+}
+
+// tdReportLayout lists the TD Report fields in byte order. The parser is
+// driven entirely by this table; TestTDReportLayout checks that the entries
+// are contiguous and add up to tdReportSize.
+var tdReportLayout = []tdReportField{
+	{"TeeTcbSvn", offTeeTcbSvn, 16, func(r *TDReport) []byte { return r.TeeTcbSvn[:] }},
+	{"MrSeam", offMrSeam, measurementSize, func(r *TDReport) []byte { return r.MrSeam[:] }},
+	{"MrSignerSeam", offMrSignerSeam, measurementSize, func(r *TDReport) []byte { return r.MrSignerSeam[:] }},
+	{"SeamAttributes", offSeamAttributes, 8, func(r *TDReport) []byte { return r.SeamAttributes[:] }},
+	{"TdAttributes", offTdAttributes, 8, func(r *TDReport) []byte { return r.TdAttributes[:] }},
+	{"Xfam", offXfam, 8, func(r *TDReport) []byte { return r.Xfam[:] }},
+	{"MrTd", offMrTd, measurementSize, func(r *TDReport) []byte { return r.MrTd[:] }},
+	{"MrConfigId", offMrConfigId, measurementSize, func(r *TDReport) []byte { return r.MrConfigId[:] }},
+	{"MrOwner", offMrOwner, measurementSize, func(r *TDReport) []byte { return r.MrOwner[:] }},
+	{"MrOwnerConfig", offMrOwnerConfig, measurementSize, func(r *TDReport) []byte { return r.MrOwnerConfig[:] }},
+	{"Rtmr0", offRtmr0, measurementSize, func(r *TDReport) []byte { return r.Rtmr0[:] }},
+	{"Rtmr1", offRtmr1, measurementSize, func(r *TDReport) []byte { return r.Rtmr1[:] }},
+	{"Rtmr2", offRtmr2, measurementSize, func(r *TDReport) []byte { return r.Rtmr2[:] }},
+	{"Rtmr3", offRtmr3, measurementSize, func(r *TDReport) []byte { return r.Rtmr3[:] }},
+	{"ReportData", offReportData, 64, func(r *TDReport) []byte { return r.ReportData[:] }},
+}
+
+func extractTDReportFromRawQuote(quoteData []byte) (*TDReport, error) {
+	// This extracts the runtime TD Report from the TDX quote
+	// TDX Quote v4 structure:
+	// - Header (quoteHeaderSize bytes)
+	// - TD Report (tdReportSize bytes) <- This is what we want (the runtime TD Report)
+	// - Signature and certificates follow...
+	// V5 inserts a body descriptor after the header, so it is handled separately.
+
+	if err := CheckSignedDataSize(quoteData); err != nil {
+		return nil, err
+	}
+
+	if rawQuoteVersion(quoteData) == quoteVersion5 {
+		q, err := parseQuoteV5(quoteData)
+		if err != nil {
+			return nil, err
+		}
+		return q.tdReport()
+	}
+
+	if len(quoteData) < tdReportEnd {
+		return nil, fmt.Errorf("%w: %d bytes, need at least %d", ErrTooShort, len(quoteData), tdReportEnd)
+	}
+
+	// Skip the header and extract the actual TD Report
+	tdReportBytes := quoteData[tdReportStart:tdReportEnd]
+
+	// Parse the raw TD Report bytes into our structure
+	// This gives us the runtime RTMR values
+	return parseTDQuoteBody(tdReportBytes)
+}
+
+// CheckSignedDataSize reads the signed-data size field that follows the TD
+// Report of a raw QuoteV4 or QuoteV5 and checks that the signature and
+// certification data it declares fit in quoteData, so that a truncated or
+// corrupt quote is reported instead of being sliced past its end. Data
+// without a V4 or V5 header is not checked.
+func CheckSignedDataSize(quoteData []byte) error {
+	version := rawQuoteVersion(quoteData)
+	offset := tdReportEnd
+	switch version {
+	case 4:
+	case quoteVersion5:
+		q, err := parseQuoteV5(quoteData)
+		if err != nil {
+			return err
+		}
+		offset = quoteV5BodyStart + len(q.body)
+	default:
+		return nil
+	}
+
+	if len(quoteData) < offset+signedDataSizeLen {
+		return fmt.Errorf("%w: QuoteV%d is %d bytes, need %d for the signed-data size field", ErrTooShort, version, len(quoteData), offset+signedDataSizeLen)
+	}
+	size := binary.LittleEndian.Uint32(quoteData[offset : offset+signedDataSizeLen])
+	if want := uint64(offset) + signedDataSizeLen + uint64(size); want > uint64(len(quoteData)) {
+		return fmt.Errorf("%w: QuoteV%d signed data is %d bytes, which needs a %d-byte quote, but only %d bytes are present", ErrTooShort, version, size, want, len(quoteData))
+	}
+	return nil
+}
+
+// parseTDQuoteBody decodes the TD Report carried in a quote (the "TD Quote
+// Body" in the Intel TDX DCAP quote spec) into a TDReport, copying each field
+// from its offset in tdReportLayout. All fields are opaque byte strings, so no
+// host byte-order conversion is involved.
+//
+// The quote body is a subset of the full TDREPORT_STRUCT, so ReportType,
+// CpuSvn, TeeTcbInfoHash, TeeInfoHash, MacStruct and ServTdHash are left zero.
+func parseTDQuoteBody(b []byte) (*TDReport, error) {
+	if len(b) != tdReportSize {
+		return nil, fmt.Errorf("invalid TD Report size: %d bytes, expected %d", len(b), tdReportSize)
+	}
+
+	tdReport := &TDReport{}
+	for _, f := range tdReportLayout {
+		copy(f.field(tdReport), b[f.offset:f.offset+f.size])
+	}
+
+	return tdReport, nil
+}
+
+// fromQuoteBody converts the protobuf TDQuoteBody to our runtime TD Report
+// structure. It fills the same fields as parseTDQuoteBody, so both paths give
+// identical TDReports for the same quote; the fields that are not part of a
+// quote body are left zero by both. That includes ServTdHash: the TDX 1.0
+// body that TDQuoteBody models has no MRSERVICETD, which only TDX 1.5
+// QuoteV5 bodies carry (see quoteV5.tdReport).
+func fromQuoteBody(tdQuoteBody *tdx.TDQuoteBody) *TDReport {
+	tdReport := &TDReport{}
+
+	// Copy the RTMR values from the protobuf structure
+	rtmrs := tdQuoteBody.GetRtmrs()
+	if len(rtmrs) >= 4 {
+		copy(tdReport.Rtmr0[:], rtmrs[0])
+		copy(tdReport.Rtmr1[:], rtmrs[1])
+		copy(tdReport.Rtmr2[:], rtmrs[2])
+		copy(tdReport.Rtmr3[:], rtmrs[3])
+	}
+
+	// Copy other important measurements
+	copy(tdReport.TeeTcbSvn[:], tdQuoteBody.GetTeeTcbSvn())
+	copy(tdReport.MrSeam[:], tdQuoteBody.GetMrSeam())
+	copy(tdReport.MrSignerSeam[:], tdQuoteBody.GetMrSignerSeam())
+	copy(tdReport.SeamAttributes[:], tdQuoteBody.GetSeamAttributes())
+	copy(tdReport.MrTd[:], tdQuoteBody.GetMrTd())
+	copy(tdReport.MrConfigId[:], tdQuoteBody.GetMrConfigId())
+	copy(tdReport.MrOwner[:], tdQuoteBody.GetMrOwner())
+	copy(tdReport.MrOwnerConfig[:], tdQuoteBody.GetMrOwnerConfig())
+	copy(tdReport.ReportData[:], tdQuoteBody.GetReportData())
+	copy(tdReport.TdAttributes[:], tdQuoteBody.GetTdAttributes())
+	copy(tdReport.Xfam[:], tdQuoteBody.GetXfam())
+
+	return tdReport
+}
+
+// RTMRs returns the four runtime measurement registers in index order.
+func (r *TDReport) RTMRs() [4][48]byte {
+	return [4][48]byte{r.Rtmr0, r.Rtmr1, r.Rtmr2, r.Rtmr3}
+}
+
+// Initialized reports, per register, whether the RTMR has been extended.
+// An RTMR that was never extended is all zeros.
+func (r *TDReport) Initialized() [4]bool {
+	var initialized [4]bool
+	for i, rtmr := range r.RTMRs() {
+		initialized[i] = !isAllZeros(rtmr[:])
+	}
+	return initialized
+}
+
+// InitializedMask returns Initialized as a bitmask: bit i is set when RTMR[i]
+// has been extended, so 0b1000 (8) means only RTMR3 is populated.
+func (r *TDReport) InitializedMask() uint8 {
+	var mask uint8
+	for i, initialized := range r.Initialized() {
+		if initialized {
+			mask |= 1 << i
+		}
+	}
+	return mask
+}
+
+// SHA256Padded reports, per register, whether an initialized RTMR ends in
+// 16 zero bytes, as when a 32-byte SHA-256 digest is stored zero-padded in
+// the 48-byte register instead of a SHA-384 one. A genuine SHA-384 value
+// ends that way with negligible probability, so a true entry points at a
+// measurement agent using the wrong hash algorithm.
+func (r *TDReport) SHA256Padded() [4]bool {
+	var padded [4]bool
+	for i, rtmr := range r.RTMRs() {
+		padded[i] = !isAllZeros(rtmr[:]) && isAllZeros(rtmr[sha256.Size:])
+	}
+	return padded
+}
+
+// Field returns the TD Report field with the given name, matched without
+// regard to case against the names in tdReportLayout (rtmr0, mrtd,
+// reportdata, ...). It reports false for an unknown name.
+func (r *TDReport) Field(name string) ([]byte, bool) {
+	for _, f := range tdReportLayout {
+		if strings.EqualFold(f.name, name) {
+			return f.field(r), true
+		}
+	}
+	return nil, false
+}
+
+// FieldRange is where a field lies in the TD Report region of a quote.
+type FieldRange struct {
+	Name   string // as in TDReport, e.g. "MrTd"
+	Offset int    // from the start of the TD Report
+	Size   int
+}
+
+// End returns the offset just past the field.
+func (f FieldRange) End() int {
+	return f.Offset + f.Size
+}
+
+// Layout returns the byte range of every TD Report field, in byte order.
+// It is read from tdReportLayout, the table the parser itself uses.
+func Layout() []FieldRange {
+	layout := make([]FieldRange, len(tdReportLayout))
+	for i, f := range tdReportLayout {
+		layout[i] = FieldRange{Name: f.name, Offset: f.offset, Size: f.size}
+	}
+	return layout
+}
+
+// FieldNames returns the names accepted by Field, in lowercase and in byte
+// order.
+func FieldNames() []string {
+	names := make([]string, len(tdReportLayout))
+	for i, f := range tdReportLayout {
+		names[i] = strings.ToLower(f.name)
+	}
+	return names
+}
+
+// DefaultRTMRMeanings describes what each RTMR holds on a typical GCP TD
+// boot stack. Measurements.Meanings starts out with these.
+var DefaultRTMRMeanings = [4]string{
+	"Static/dynamic configuration data",
+	"OS kernel, boot parameters, initrd",
+	"Additional boot components, ACPI tables",
+	"Application-specific measurements",
+}
+
+// Measurements is the printable form of a TDReport. All byte values are
+// encoded in one HashFormat, lowercase hex by default; Initialized[i] is
+// false when RTMR[i] is all zeros, and InitializedMask holds the same as
+// bits for aggregation.
+//
+// Its JSON and CBOR encodings are byte-for-byte stable for the same report:
+// fields appear in struct order, flag lists in bit order and the keys of
+// Meanings sorted, so output can be compared against golden files.
+type Measurements struct {
+	Rtmr0       string  `json:"rtmr0"`
+	Rtmr1       string  `json:"rtmr1"`
+	Rtmr2       string  `json:"rtmr2"`
+	Rtmr3       string  `json:"rtmr3"`
+	Initialized [4]bool `json:"initialized"`
+	// InitializedMask is TDReport.InitializedMask.
+	InitializedMask uint8  `json:"initializedMask"`
+	MrTd            string `json:"mrTd"`
+	MrConfigId      string `json:"mrConfigId"`
+	MrOwner         string `json:"mrOwner"`
+	MrOwnerConfig   string `json:"mrOwnerConfig"`
+	ReportData      string `json:"reportData"`
+	// MrSeam and MrSignerSeam identify the TDX module (SEAM) and its signer.
+	MrSeam       string `json:"mrSeam"`
+	MrSignerSeam string `json:"mrSignerSeam"`
+	// ServTdHash is the service TD hash (MRSERVICETD) of TDX 1.5 quotes,
+	// all zeros for quotes without one.
+	ServTdHash string `json:"servTdHash"`
+	// TeeTcbSvn is the raw TEE_TCB_SVN field; TeeTcbSvnDecoded splits out
+	// its named components.
+	TeeTcbSvn        string           `json:"teeTcbSvn"`
+	TeeTcbSvnDecoded TeeTcbSvnDecoded `json:"teeTcbSvnDecoded"`
+	// SeamAttributes is the raw SEAMATTRIBUTES field, all zeros for a
+	// production TDX module; SeamAttributesFlags lists its set bits.
+	SeamAttributes      string   `json:"seamAttributes"`
+	SeamAttributesFlags []string `json:"seamAttributesFlags"`
+	// TdAttributes and Xfam are the raw fields; the *Flags lists name the
+	// set bits.
+	TdAttributes      string   `json:"tdAttributes"`
+	TdAttributesFlags []string `json:"tdAttributesFlags"`
+	Xfam              string   `json:"xfam"`
+	XfamFeatures      []string `json:"xfamFeatures"`
+	// Meanings describes what each RTMR holds, keyed rtmr0 to rtmr3; see
+	// SetMeanings.
+	Meanings map[string]string `json:"meanings"`
+}
+
+// SetMeanings replaces the RTMR descriptions in m.Meanings.
+func (m *Measurements) SetMeanings(meanings [4]string) {
+	m.Meanings = make(map[string]string, len(meanings))
+	for i, meaning := range meanings {
+		m.Meanings[fmt.Sprintf("rtmr%d", i)] = meaning
+	}
+}
+
+// TeeTcbSvnDecoded is the printable form of TEETCBSVN.
+type TeeTcbSvnDecoded struct {
+	ModuleSVN   uint8 `json:"tdxModuleSvn"`
+	ModuleMajor uint8 `json:"tdxModuleMajorVersion"`
+	SeamLdrSVN  uint8 `json:"seamLdrSvn"`
+}
+
+// Measurements returns the hex-encoded measurement set of r.
+func (r *TDReport) Measurements() Measurements {
+	return r.MeasurementsIn(HashHex)
+}
+
+// MeasurementsIn returns the measurement set of r with byte values encoded
+// in format f.
+func (r *TDReport) MeasurementsIn(f HashFormat) Measurements {
+	m := Measurements{
+		Rtmr0:           f.Encode(r.Rtmr0[:]),
+		Rtmr1:           f.Encode(r.Rtmr1[:]),
+		Rtmr2:           f.Encode(r.Rtmr2[:]),
+		Rtmr3:           f.Encode(r.Rtmr3[:]),
+		Initialized:     r.Initialized(),
+		InitializedMask: r.InitializedMask(),
+		MrTd:            f.Encode(r.MrTd[:]),
+		MrConfigId:      f.Encode(r.MrConfigId[:]),
+		MrOwner:         f.Encode(r.MrOwner[:]),
+		MrOwnerConfig:   f.Encode(r.MrOwnerConfig[:]),
+		ReportData:      f.Encode(r.ReportData[:]),
+		MrSeam:          f.Encode(r.MrSeam[:]),
+		MrSignerSeam:    f.Encode(r.MrSignerSeam[:]),
+		ServTdHash:      f.Encode(r.ServTdHash[:]),
+		TeeTcbSvn:       f.Encode(r.TeeTcbSvn[:]),
+		TeeTcbSvnDecoded: TeeTcbSvnDecoded{
+			ModuleSVN:   r.TCBSVN().ModuleSVN(),
+			ModuleMajor: r.TCBSVN().ModuleMajor(),
+			SeamLdrSVN:  r.TCBSVN().SeamLdrSVN(),
+		},
+		SeamAttributes:      f.Encode(r.SeamAttributes[:]),
+		SeamAttributesFlags: r.SEAMAttributes().Names(),
+
+		TdAttributes:      f.Encode(r.TdAttributes[:]),
+		TdAttributesFlags: r.Attributes().Names(),
+		Xfam:              f.Encode(r.Xfam[:]),
+		XfamFeatures:      r.XFAM().Names(),
+	}
+	m.SetMeanings(DefaultRTMRMeanings)
+	return m
+}
+
+// Fingerprint returns a SHA-256 digest summarizing the security-relevant
+// measurements, for cheap equality checks across many TDs. The input is the
+// concatenation, in this order and without separators, of the raw 48-byte
+// values MRTD || MRCONFIGID || RTMR0 || RTMR1 || RTMR2 || RTMR3 (288 bytes).
+// This ordering is fixed; changing it changes every fingerprint.
+func (r *TDReport) Fingerprint() [32]byte {
+	h := sha256.New()
+	h.Write(r.MrTd[:])
+	h.Write(r.MrConfigId[:])
+	for _, value := range r.RTMRs() {
+		h.Write(value[:])
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// RTMRDigest returns a SHA-384 digest of the runtime registers alone, for
+// comparing the boot state of TDs launched from the same image. The input
+// is the concatenation, in this order and without separators, of the raw
+// 48-byte values RTMR0 || RTMR1 || RTMR2 || RTMR3 (192 bytes). Unlike
+// Fingerprint it leaves out MRTD and MRCONFIGID.
+func (r *TDReport) RTMRDigest() [48]byte {
+	h := sha512.New384()
+	for _, value := range r.RTMRs() {
+		h.Write(value[:])
+	}
+	var sum [48]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// isAllZeros reports whether b contains only zero bytes, which is how an
+// RTMR that was never extended appears in the TD Report.
+func isAllZeros(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,393 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-tdx-guest/abi"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseTDQuoteBody(t *testing.T) {
+	body := make([]byte, 584)
+	fill := func(start, end int, v byte) {
+		for i := start; i < end; i++ {
+			body[i] = v
+		}
+	}
+	fill(0x088, 0x0B8, 0xAA) // MRTD
+	fill(0x148, 0x178, 0x10) // RTMR0
+	fill(0x178, 0x1A8, 0x11) // RTMR1
+	fill(0x1A8, 0x1D8, 0x12) // RTMR2
+	fill(0x1D8, 0x208, 0x13) // RTMR3
+	fill(0x208, 0x248, 0xDD) // REPORTDATA
+
+	// Prefix a zeroed header so the full raw-quote path is exercised.
+	quote := append(make([]byte, quoteHeaderSize), body...)
+	got, err := extractTDReportFromRawQuote(quote)
+	if err != nil {
+		t.Fatalf("extractTDReportFromRawQuote() error = %v", err)
+	}
+
+	checks := []struct {
+		name string
+		got  []byte
+		want byte
+	}{
+		{"MrTd", got.MrTd[:], 0xAA},
+		{"Rtmr0", got.Rtmr0[:], 0x10},
+		{"Rtmr1", got.Rtmr1[:], 0x11},
+		{"Rtmr2", got.Rtmr2[:], 0x12},
+		{"Rtmr3", got.Rtmr3[:], 0x13},
+		{"ReportData", got.ReportData[:], 0xDD},
+		{"MrConfigId", got.MrConfigId[:], 0x00},
+	}
+	for _, c := range checks {
+		if want := bytes.Repeat([]byte{c.want}, len(c.got)); !bytes.Equal(c.got, want) {
+			t.Errorf("%s = %x, want %x", c.name, c.got, want)
+		}
+	}
+}
+
+func TestParseTDQuoteBodyShort(t *testing.T) {
+	if _, err := parseTDQuoteBody(make([]byte, 583)); err == nil {
+		t.Error("parseTDQuoteBody() accepted a 583-byte body")
+	}
+	if _, err := extractTDReportFromRawQuote(make([]byte, 631)); err == nil {
+		t.Error("extractTDReportFromRawQuote() accepted a 631-byte quote")
+	}
+}
+
+func TestParseQuoteInconsistentHeader(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTeeType := func(tee uint32) []byte {
+		b := bytes.Clone(raw)
+		binary.LittleEndian.PutUint32(b[4:8], tee)
+		return b
+	}
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"SGX TEE type", withTeeType(teeTypeSGX)},
+		{"unknown TEE type", withTeeType(0x42)},
+		{"truncated body", raw[:tdReportStart+tdReportSize-1]},
+	}
+	for _, c := range cases {
+		if _, err := ParseQuote(c.data); err == nil {
+			t.Errorf("ParseQuote() accepted a quote with %s", c.name)
+		}
+	}
+	if _, err := ParseQuote(raw); err != nil {
+		t.Errorf("ParseQuote() error = %v, want nil for a genuine quote", err)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	quote, raw := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	withTeeType := bytes.Clone(raw)
+	binary.LittleEndian.PutUint32(withTeeType[4:8], 0x42)
+	v3 := bytes.Clone(raw)
+	binary.LittleEndian.PutUint16(v3[0:2], 3)
+	quote.TdQuoteBody = nil
+	noBody, err := proto.Marshal(quote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	tampered.GetTdQuoteBody().GetRtmrs()[0][0] ^= 0x01
+
+	_, tooShort := ParseQuote(raw[:tdReportEnd-1])
+	_, wrongTee := ParseQuote(withTeeType)
+	_, unsupported := QuoteLength(v3)
+	_, truncated := ReadQuote(bytes.NewReader(raw[:700]))
+	_, missingBody := ParseQuote(noBody)
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"truncated body", tooShort, ErrTooShort},
+		{"truncated stream", truncated, ErrTooShort},
+		{"unknown TEE type", wrongTee, ErrWrongTeeType},
+		{"SGX quote", ErrSGXQuote, ErrWrongTeeType},
+		{"QuoteV3 length", unsupported, ErrUnsupportedVersion},
+		{"protobuf quote without a body", missingBody, ErrNoTdBody},
+		{"modified RTMR", CheckSignature(tampered), ErrSignatureInvalid},
+	}
+	for _, c := range cases {
+		if !errors.Is(c.err, c.want) {
+			t.Errorf("%s: error = %v, want one wrapping %q", c.name, c.err, c.want)
+		}
+	}
+}
+
+func TestParseQuoteVersionSkew(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same quote as a QuoteV5 with a TDX 1.0 body.
+	v5 := bytes.Clone(raw[:quoteHeaderSize])
+	v5 = binary.LittleEndian.AppendUint16(v5, BodyTypeTDX10)
+	v5 = binary.LittleEndian.AppendUint32(v5, tdReportSize)
+	v5 = append(v5, raw[quoteHeaderSize:]...)
+	binary.LittleEndian.PutUint16(v5[0:2], quoteVersion5)
+	if report, err := ParseQuote(v5); err != nil || report.Format != FormatRawV5 {
+		t.Fatalf("ParseQuote(QuoteV5) = %v, %v; want a %s", report, err, FormatRawV5)
+	}
+
+	v4OnV5 := bytes.Clone(v5)
+	binary.LittleEndian.PutUint16(v4OnV5[0:2], 4)
+	v5OnV4 := bytes.Clone(raw)
+	binary.LittleEndian.PutUint16(v5OnV4[0:2], quoteVersion5)
+	for name, data := range map[string][]byte{"V4 header on a V5 body": v4OnV5, "V5 header on a V4 body": v5OnV4} {
+		if _, err := ParseQuote(data); err == nil || !strings.Contains(err.Error(), "header and body versions do not match") {
+			t.Errorf("ParseQuote(%s) error = %v, want a version mismatch", name, err)
+		}
+	}
+}
+
+func TestCheckSignedDataSize(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSignedDataSize(raw); err != nil {
+		t.Errorf("CheckSignedDataSize() error = %v, want nil for a genuine quote", err)
+	}
+
+	if err := CheckSignedDataSize(raw[:700]); err == nil {
+		t.Error("CheckSignedDataSize() accepted a quote truncated to 700 bytes")
+	}
+	if _, err := ParseQuote(raw[:700]); err == nil {
+		t.Error("ParseQuote() accepted a quote truncated to 700 bytes")
+	}
+
+	corrupt := bytes.Clone(raw)
+	binary.LittleEndian.PutUint32(corrupt[tdReportEnd:], 0xffffffff)
+	if err := CheckSignedDataSize(corrupt); err == nil {
+		t.Error("CheckSignedDataSize() accepted a signed-data size past the end of the quote")
+	}
+}
+
+func TestParseQuoteProtoMatchesRaw(t *testing.T) {
+	quote, raw := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	quote.GetTdQuoteBody().SeamAttributes[0] = 0x01
+	rawBody, err := abi.TdQuoteBodyToAbiBytes(quote.GetTdQuoteBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(raw[tdReportStart:], rawBody)
+
+	fromRaw, err := parseTDQuoteBody(raw[tdReportStart:tdReportEnd])
+	if err != nil {
+		t.Fatalf("parseTDQuoteBody() error = %v", err)
+	}
+	protoData, err := proto.Marshal(quote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromProto, err := ParseQuote(protoData)
+	if err != nil {
+		t.Fatalf("ParseQuote(protobuf) error = %v", err)
+	}
+	if fromProto.Format != FormatProtoV4 {
+		t.Fatalf("ParseQuote(protobuf) format = %s, want %s", fromProto.Format, FormatProtoV4)
+	}
+
+	if !reflect.DeepEqual(fromProto.TDReport, *fromRaw) {
+		for _, f := range tdReportLayout {
+			if a, b := f.field(&fromProto.TDReport), f.field(fromRaw); !bytes.Equal(a, b) {
+				t.Errorf("%s: protobuf path %x, raw path %x", f.name, a, b)
+			}
+		}
+		t.Error("protobuf and raw paths produce different TDReports")
+	}
+}
+
+func TestTDReportLayout(t *testing.T) {
+	next, total := 0, 0
+	for _, f := range tdReportLayout {
+		if f.offset != next {
+			t.Errorf("%s starts at 0x%03x, want 0x%03x", f.name, f.offset, next)
+		}
+		if n := len(f.field(&TDReport{})); n != f.size {
+			t.Errorf("%s is %d bytes in the table but %d bytes in TDReport", f.name, f.size, n)
+		}
+		next = f.offset + f.size
+		total += f.size
+	}
+	if total != tdReportSize {
+		t.Errorf("TD Report fields add up to %d bytes, want %d", total, tdReportSize)
+	}
+}
+
+func TestSHA256Padded(t *testing.T) {
+	var r TDReport
+	r.Rtmr1[0] = 1                 // SHA-256 sized value, zero tail
+	r.Rtmr2[0], r.Rtmr2[47] = 1, 1 // full 48-byte value
+	r.Rtmr3[sha256.Size] = 1       // non-zero tail only
+
+	if got, want := r.SHA256Padded(), [4]bool{false, true, false, false}; got != want {
+		t.Errorf("SHA256Padded() = %v, want %v", got, want)
+	}
+}
+
+func TestInitializedMask(t *testing.T) {
+	var r TDReport
+	if got := r.InitializedMask(); got != 0 {
+		t.Errorf("InitializedMask() of zero report = %#b, want 0", got)
+	}
+	r.Rtmr0[0] = 1
+	r.Rtmr3[47] = 1
+	if got, want := r.InitializedMask(), uint8(0b1001); got != want {
+		t.Errorf("InitializedMask() = %#b, want %#b", got, want)
+	}
+}
+
+func TestRTMRDigest(t *testing.T) {
+	var r TDReport
+	r.MrTd[0] = 1
+	r.Rtmr0[0], r.Rtmr3[47] = 0xaa, 0xbb
+	concat := append(append(append(r.Rtmr0[:], r.Rtmr1[:]...), r.Rtmr2[:]...), r.Rtmr3[:]...)
+	if got, want := r.RTMRDigest(), sha512.Sum384(concat); got != want {
+		t.Errorf("RTMRDigest() = %x, want SHA-384 of RTMR0..3 %x", got, want)
+	}
+	before := r.RTMRDigest()
+	r.MrTd[0] = 2
+	if r.RTMRDigest() != before {
+		t.Error("RTMRDigest() changed with MRTD")
+	}
+}
+
+func TestSEAMAttributes(t *testing.T) {
+	var r TDReport
+	if got := r.SEAMAttributes().Names(); len(got) != 0 {
+		t.Errorf("SEAMAttributes().Names() of zero report = %v, want none", got)
+	}
+	r.SeamAttributes[0], r.SeamAttributes[7] = 0x01, 0x80
+	if got, want := r.SEAMAttributes().Names(), []string{"BIT0", "BIT63"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SEAMAttributes().Names() = %v, want %v", got, want)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	layout := Layout()
+	if len(layout) != len(tdReportLayout) {
+		t.Fatalf("Layout() has %d fields, want %d", len(layout), len(tdReportLayout))
+	}
+	if end := layout[len(layout)-1].End(); end != tdReportSize {
+		t.Errorf("last field ends at %d, want %d", end, tdReportSize)
+	}
+	var r TDReport
+	for _, f := range layout {
+		if value, ok := r.Field(f.Name); !ok || len(value) != f.Size {
+			t.Errorf("Field(%q) = %d bytes, %v; want %d bytes", f.Name, len(value), ok, f.Size)
+		}
+	}
+}
+
+func TestQuoteToRawBytesRoundTrip(t *testing.T) {
+	quote, raw := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	protoData, err := proto.Marshal(quote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ParseQuote(protoData)
+	if err != nil {
+		t.Fatalf("ParseQuote(protobuf) error = %v", err)
+	}
+	got, err := QuoteToRawBytes(report.Quote)
+	if err != nil {
+		t.Fatalf("QuoteToRawBytes() error = %v", err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Errorf("raw -> protobuf -> raw gave %d bytes that differ from the %d-byte original", len(got), len(raw))
+	}
+
+	report.Quote.SignedDataSize++
+	if _, err := QuoteToRawBytes(report.Quote); err == nil {
+		t.Error("QuoteToRawBytes() accepted a wrong signed-data size")
+	}
+}
+
+func TestServTdHashV5(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	servTd := bytes.Repeat([]byte{0x5a}, measurementSize)
+
+	// The same quote as a QuoteV5 with a TDX 1.5 body: TEE_TCB_SVN_2 and
+	// MRSERVICETD follow the TDX 1.0 fields.
+	v5 := bytes.Clone(raw[:quoteHeaderSize])
+	binary.LittleEndian.PutUint16(v5[0:2], quoteVersion5)
+	v5 = binary.LittleEndian.AppendUint16(v5, BodyTypeTDX15)
+	v5 = binary.LittleEndian.AppendUint32(v5, tdReportV15Size)
+	v5 = append(v5, raw[tdReportStart:tdReportEnd]...)
+	v5 = append(v5, make([]byte, 16)...)
+	v5 = append(v5, servTd...)
+	v5 = append(v5, raw[tdReportEnd:]...)
+
+	report, err := ParseQuote(v5)
+	if err != nil {
+		t.Fatalf("ParseQuote(QuoteV5) error = %v", err)
+	}
+	if !bytes.Equal(report.ServTdHash[:], servTd) {
+		t.Errorf("ServTdHash = %x, want %x", report.ServTdHash, servTd)
+	}
+	if got := report.Measurements().ServTdHash; got != strings.Repeat("5a", measurementSize) {
+		t.Errorf("Measurements().ServTdHash = %s", got)
+	}
+	if !report.HasServTdHash() {
+		t.Error("HasServTdHash() = false for a TDX 1.5 QuoteV5")
+	}
+
+	// A V4 quote has no MRSERVICETD.
+	report, err = ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isAllZeros(report.ServTdHash[:]) || report.HasServTdHash() {
+		t.Errorf("V4 ServTdHash = %x, HasServTdHash() = %v; want zeros and false", report.ServTdHash, report.HasServTdHash())
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	for _, name := range []string{"tdx_prod_quote_SPR_E4.dat", "tdx_prod_quote_SPR_E4.pb"} {
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, err := ParseHeader(data)
+		if err != nil {
+			t.Fatalf("ParseHeader(%s) error = %v", name, err)
+		}
+		if header.GetVersion() != 4 || header.GetTeeType() != teeTypeTDX || !bytes.Equal(header.GetQeVendorId(), IntelQEVendorID) {
+			t.Errorf("ParseHeader(%s) = %v, want a TDX QuoteV4 header from Intel's QE", name, header)
+		}
+	}
+
+	// The body is not needed.
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header, err := ParseHeader(raw[:quoteHeaderSize]); err != nil || header.GetVersion() != 4 {
+		t.Errorf("ParseHeader(header only) = %v, %v; want version 4", header, err)
+	}
+	if _, err := ParseHeader(raw[:quoteHeaderSize-1]); !errors.Is(err, ErrTooShort) {
+		t.Errorf("ParseHeader(47 bytes) error = %v, want ErrTooShort", err)
+	}
+}
@@ -0,0 +1,85 @@
+package rtmr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// schemaDraft is the JSON Schema dialect of MeasurementsSchema.
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// MeasurementsSchema returns a JSON Schema (draft-07) describing the JSON
+// encoding of Measurements in format f, which is the --json output. It is
+// generated from the struct and its json tags, so it cannot drift from what
+// is marshaled.
+func MeasurementsSchema(f HashFormat) ([]byte, error) {
+	schema, err := typeSchema(reflect.TypeOf(Measurements{}), f)
+	if err != nil {
+		return nil, err
+	}
+	schema["$schema"] = schemaDraft
+	schema["title"] = "TDX quote measurements"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// typeSchema returns the schema for values of type t. Strings in
+// Measurements are all bytes encoded in f, which the pattern enforces;
+// string slices hold flag names.
+func typeSchema(t reflect.Type, f HashFormat) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string", "pattern": f.pattern()}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Int:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Map:
+		// The only map is Meanings, of RTMR names to free text.
+		return map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, nil
+		}
+		items, err := typeSchema(t.Elem(), f)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Array:
+		items, err := typeSchema(t.Elem(), f)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items, "minItems": t.Len(), "maxItems": t.Len()}, nil
+	case reflect.Struct:
+		properties := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			property, err := typeSchema(field.Type, f)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", t.Name(), field.Name, err)
+			}
+			properties[name] = property
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}, nil
+	}
+	return nil, fmt.Errorf("no JSON Schema mapping for %s", t)
+}
@@ -0,0 +1,51 @@
+package rtmr
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestMeasurementsSchemaCoversOutput checks that every key of a marshaled
+// Measurements is described by the schema and required by it, and that the
+// schema describes nothing else.
+func TestMeasurementsSchemaCoversOutput(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	b, err := json.Marshal(fromQuoteBody(quote.GetTdQuoteBody()).Measurements())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output map[string]any
+	if err := json.Unmarshal(b, &output); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := MeasurementsSchema(HashHex)
+	if err != nil {
+		t.Fatalf("MeasurementsSchema() error = %v", err)
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	for key := range output {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("output key %q missing from schema properties", key)
+		}
+		if !required[key] {
+			t.Errorf("output key %q not required by schema", key)
+		}
+	}
+	for key := range schema.Properties {
+		if _, ok := output[key]; !ok {
+			t.Errorf("schema property %q not present in output", key)
+		}
+	}
+}
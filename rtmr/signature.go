@@ -0,0 +1,284 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/go-tdx-guest/abi"
+	"github.com/google/go-tdx-guest/proto/tdx"
+)
+
+// SignedPayload returns the exact bytes the Quoting Enclave signs for a
+// QuoteV4: the quote header followed by the TD quote body, both in ABI
+// layout with nothing in between (632 bytes). The signature in the quote's
+// signed data is ECDSA over a digest of these bytes, per the "Quote
+// Signature Data" section of the Intel TDX DCAP quote spec; see
+// AttestationKeyAlgorithm.
+func SignedPayload(quote *tdx.QuoteV4) ([]byte, error) {
+	headerBytes, err := rawHeaderBytes(quote.GetHeader())
+	if err != nil {
+		return nil, fmt.Errorf("could not convert header to ABI bytes: %v", err)
+	}
+
+	tdQuoteBodyBytes, err := abi.TdQuoteBodyToAbiBytes(quote.GetTdQuoteBody())
+	if err != nil {
+		return nil, fmt.Errorf("could not convert TD quote body to ABI bytes: %v", err)
+	}
+
+	payload := make([]byte, 0, tdReportEnd)
+	payload = append(payload, headerBytes...)
+	payload = append(payload, tdQuoteBodyBytes...)
+	if len(payload) != tdReportEnd {
+		return nil, fmt.Errorf("signed payload is %d bytes, expected %d", len(payload), tdReportEnd)
+	}
+	return payload, nil
+}
+
+// Attestation key types of the quote header.
+const (
+	AttestationKeyECDSAP256 = 2 // ECDSA-256-with-P-256, signs SHA-256
+	AttestationKeyECDSAP384 = 3 // ECDSA-384-with-P-384, signs SHA-384
+)
+
+// AttestationKeyAlgorithm returns the curve of the attestation key and the
+// digest it signs for a header attestation key type. Current Quoting
+// Enclaves all use P-256 with SHA-256; the spec also defines P-384 with
+// SHA-384.
+func AttestationKeyAlgorithm(keyType uint32) (elliptic.Curve, crypto.Hash, error) {
+	switch keyType {
+	case AttestationKeyECDSAP256:
+		return elliptic.P256(), crypto.SHA256, nil
+	case AttestationKeyECDSAP384:
+		return elliptic.P384(), crypto.SHA384, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported attestation key type %d", keyType)
+}
+
+// ErrSignatureInvalid is wrapped by errors from CheckSignature,
+// CheckQEReportSignature and Verify when a signature is malformed or does
+// not verify.
+var ErrSignatureInvalid = errors.New("signature invalid")
+
+// CheckSignature verifies the quote's signature over SignedPayload with the
+// ECDSA attestation key embedded in the quote, using the curve and digest
+// of the header's attestation key type. This is an offline check: it shows
+// the header and TD quote body are intact, not that the attestation key
+// belongs to a genuine TDX platform (see Report.Verify for that).
+func CheckSignature(quote *tdx.QuoteV4) error {
+	curve, hash, err := AttestationKeyAlgorithm(quote.GetHeader().GetAttestationKeyType())
+	if err != nil {
+		return err
+	}
+	// Signatures (r || s) and keys (x || y) are two coordinates each.
+	size := 2 * ((curve.Params().BitSize + 7) / 8)
+	signature := quote.GetSignedData().GetSignature()
+	publicKey := quote.GetSignedData().GetEcdsaAttestationKey()
+	if len(signature) != size {
+		return fmt.Errorf("%w: length %d (expected %d)", ErrSignatureInvalid, len(signature), size)
+	}
+	if len(publicKey) != size {
+		return fmt.Errorf("%w: public key length %d (expected %d)", ErrSignatureInvalid, len(publicKey), size)
+	}
+
+	key := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(publicKey[:size/2]),
+		Y:     new(big.Int).SetBytes(publicKey[size/2:]),
+	}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return fmt.Errorf("%w: attestation key is not on the %s curve", ErrSignatureInvalid, curve.Params().Name)
+	}
+
+	payload, err := SignedPayload(quote)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(payload)
+
+	r := new(big.Int).SetBytes(signature[:size/2])
+	s := new(big.Int).SetBytes(signature[size/2:])
+	if !ecdsa.Verify(key, h.Sum(nil), r, s) {
+		return fmt.Errorf("%w: signature does not match the header and TD quote body", ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// CheckQEReportSignature verifies the PCK signature over the QE report in the
+// quote's certification data, using the public key of the PCK leaf
+// certificate (the first certificate of the embedded chain). Like
+// CheckSignature this is offline: it does not check the chain up to the
+// Intel root, only that the QE report was signed by the certified PCK key.
+func CheckQEReportSignature(quote *tdx.QuoteV4) error {
+	qeData := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData()
+	if qeData == nil {
+		return errors.New("no QE report certification data in quote")
+	}
+	signature := qeData.GetQeReportSignature()
+	if len(signature) != 64 {
+		return fmt.Errorf("%w: QE report signature length %d (expected 64)", ErrSignatureInvalid, len(signature))
+	}
+
+	reportBytes, err := abi.EnclaveReportToAbiBytes(qeData.GetQeReport())
+	if err != nil {
+		return fmt.Errorf("could not convert QE report to ABI bytes: %v", err)
+	}
+
+	block, _ := pem.Decode(qeData.GetPckCertificateChainData().GetPckCertChain())
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("no PCK certificate in certification data")
+	}
+	pck, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parsing PCK certificate: %v", err)
+	}
+	key, ok := pck.PublicKey.(*ecdsa.PublicKey)
+	if !ok || key.Curve != elliptic.P256() {
+		return errors.New("PCK certificate key is not ECDSA P-256")
+	}
+
+	hash := sha256.Sum256(reportBytes)
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, hash[:], r, s) {
+		return fmt.Errorf("%w: QE report signature does not match the PCK certificate key", ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// ErrAttestationKeyMismatch is wrapped by errors from
+// CheckAttestationKeyBinding and CheckCertifiedKey when an attestation key
+// is not the one the QE report certifies.
+var ErrAttestationKeyMismatch = errors.New("attestation key is not the certified key")
+
+// CheckAttestationKeyBinding checks that the QE report vouches for the
+// quote's attestation key: the first 32 bytes of its REPORTDATA must be
+// SHA-256 of the ECDSA attestation key followed by the QE authentication
+// data, and the rest zero. With CheckQEReportSignature this ties the key
+// that signed the quote to the certified platform.
+func CheckAttestationKeyBinding(quote *tdx.QuoteV4) error {
+	return checkKeyBinding(quote, quote.GetSignedData().GetEcdsaAttestationKey())
+}
+
+// checkKeyBinding checks that the QE report data of quote commits to
+// publicKey, the raw x || y coordinates of an attestation key.
+func checkKeyBinding(quote *tdx.QuoteV4, publicKey []byte) error {
+	qeData := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData()
+	if qeData == nil {
+		return errors.New("no QE report certification data in quote")
+	}
+	reportData := qeData.GetQeReport().GetReportData()
+	if len(reportData) != 64 {
+		return fmt.Errorf("invalid QE report data length: %d (expected 64)", len(reportData))
+	}
+
+	h := sha256.New()
+	h.Write(publicKey)
+	h.Write(qeData.GetQeAuthData().GetData())
+	want := h.Sum(nil)
+	if !bytes.Equal(reportData[:sha256.Size], want) || !isAllZeros(reportData[sha256.Size:]) {
+		return fmt.Errorf("%w: QE report data %x does not match SHA-256(attestation key || QE auth data) %x", ErrAttestationKeyMismatch, reportData[:sha256.Size], want)
+	}
+	return nil
+}
+
+// CheckCertifiedKey checks that publicKey, an attestation key as raw x || y
+// coordinates such as the one CheckSignature verified the quote with, is
+// the key the platform certified: the PCK chain must lead to the Intel SGX
+// Root CA, the PCK leaf must have signed the QE report, and the QE report
+// data must commit to publicKey. This connects the offline signature check
+// to the trust chain, so that a quote carrying an arbitrary key of its own
+// is not accepted. Like CheckPCKChain it does not check revocation.
+func CheckCertifiedKey(quote *tdx.QuoteV4, publicKey []byte) error {
+	return checkCertifiedKeyAt(quote, publicKey, nil, time.Now())
+}
+
+// CheckCertifiedKeyWithRoot is CheckCertifiedKey with the PCK chain
+// anchored at the PEM certificates of rootCA, as VerifyOptions.RootCA. An
+// empty rootCA means the Intel SGX Root CA.
+func CheckCertifiedKeyWithRoot(quote *tdx.QuoteV4, publicKey, rootCA []byte) error {
+	return checkCertifiedKeyAt(quote, publicKey, rootCA, time.Now())
+}
+
+func checkCertifiedKeyAt(quote *tdx.QuoteV4, publicKey, rootCA []byte, now time.Time) error {
+	if _, err := checkPCKChainAt(quote, rootCA, now); err != nil {
+		return err
+	}
+	if err := CheckQEReportSignature(quote); err != nil {
+		return err
+	}
+	return checkKeyBinding(quote, publicKey)
+}
+
+// CheckPCKChain verifies the PCK certificate chain embedded in the quote's
+// certification data up to the Intel SGX Root CA, and returns the chain
+// from the PCK leaf to the root. Like the other checks here it is offline:
+// validity periods are checked, revocation is not (see Report.Verify).
+func CheckPCKChain(quote *tdx.QuoteV4) ([]*x509.Certificate, error) {
+	return checkPCKChainAt(quote, nil, time.Now())
+}
+
+// CheckPCKChainWithRoot is CheckPCKChain with the chain anchored at the PEM
+// certificates of rootCA, as VerifyOptions.RootCA. An empty rootCA means
+// the Intel SGX Root CA.
+func CheckPCKChainWithRoot(quote *tdx.QuoteV4, rootCA []byte) ([]*x509.Certificate, error) {
+	return checkPCKChainAt(quote, rootCA, time.Now())
+}
+
+func checkPCKChainAt(quote *tdx.QuoteV4, rootCA []byte, now time.Time) ([]*x509.Certificate, error) {
+	chains, err := pckChains(quote, rootCA, now)
+	if err != nil {
+		return nil, err
+	}
+	return chains[0], nil
+}
+
+// pckChains verifies the PCK leaf certificate of the quote against rootCA
+// (see trustedRoots), with the other certificates of the certification data
+// as candidate intermediates, and returns every verified chain from the
+// leaf to a root. Certificates of the certification data that are in no
+// chain are not trusted for anything.
+func pckChains(quote *tdx.QuoteV4, rootCA []byte, now time.Time) ([][]*x509.Certificate, error) {
+	qeData := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData()
+	if qeData == nil {
+		return nil, errors.New("no QE report certification data in quote")
+	}
+	certs, err := ParseCertificates(qeData.GetPckCertificateChainData().GetPckCertChain())
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PCK certificate in certification data")
+	}
+
+	roots, err := trustedRoots(rootCA)
+	if err != nil {
+		return nil, err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		CurrentTime:   now,
+	})
+	if err != nil {
+		if len(rootCA) != 0 {
+			return nil, fmt.Errorf("PCK certificate does not chain to the configured root CA: %v", err)
+		}
+		return nil, fmt.Errorf("PCK certificate does not chain to the Intel SGX Root CA: %v", err)
+	}
+	return chains, nil
+}
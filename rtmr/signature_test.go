@@ -0,0 +1,226 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-tdx-guest/abi"
+	"github.com/google/go-tdx-guest/proto/tdx"
+)
+
+// loadQuoteV4 parses a raw QuoteV4 fixture from testdata.
+func loadQuoteV4(t *testing.T, name string) (*tdx.QuoteV4, []byte) {
+	t.Helper()
+	raw, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := abi.QuoteToProto(raw)
+	if err != nil {
+		t.Fatalf("abi.QuoteToProto(%s) error = %v", name, err)
+	}
+	return q.(*tdx.QuoteV4), raw
+}
+
+func TestCheckSignatureKnownGoodQuote(t *testing.T) {
+	quote, raw := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+
+	payload, err := SignedPayload(quote)
+	if err != nil {
+		t.Fatalf("SignedPayload() error = %v", err)
+	}
+	if string(payload) != string(raw[:tdReportEnd]) {
+		t.Error("SignedPayload() differs from the header and TD quote body bytes of the raw quote")
+	}
+
+	if err := CheckSignature(quote); err != nil {
+		t.Errorf("CheckSignature() error = %v, want nil for a genuine quote", err)
+	}
+}
+
+func TestCheckSignatureTamperedQuote(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	quote.GetTdQuoteBody().GetRtmrs()[3][0] ^= 0x01
+
+	if err := CheckSignature(quote); err == nil {
+		t.Error("CheckSignature() accepted a quote with a modified RTMR")
+	}
+}
+
+// resignP384 turns the fixture into a quote with a P-384 attestation key
+// (type 3) and signs it over the given digest of SignedPayload.
+func resignP384(t *testing.T, digest func([]byte) []byte) *tdx.QuoteV4 {
+	t.Helper()
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	quote.GetHeader().AttestationKeyType = AttestationKeyECDSAP384
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := SignedPayload(quote)
+	if err != nil {
+		t.Fatalf("SignedPayload() error = %v", err)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	quote.GetSignedData().Signature = append(r.FillBytes(make([]byte, 48)), s.FillBytes(make([]byte, 48))...)
+	quote.GetSignedData().EcdsaAttestationKey = append(key.X.FillBytes(make([]byte, 48)), key.Y.FillBytes(make([]byte, 48))...)
+	return quote
+}
+
+func TestCheckSignatureP384(t *testing.T) {
+	quote := resignP384(t, func(b []byte) []byte { h := sha512.Sum384(b); return h[:] })
+	if err := CheckSignature(quote); err != nil {
+		t.Errorf("CheckSignature() error = %v, want nil for a SHA-384 signature with key type 3", err)
+	}
+
+	quote = resignP384(t, func(b []byte) []byte { h := sha256.Sum256(b); return h[:] })
+	if err := CheckSignature(quote); err == nil {
+		t.Error("CheckSignature() accepted a SHA-256 signature with key type 3")
+	}
+}
+
+func TestCheckSignatureUnsupportedKeyType(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	quote.GetHeader().AttestationKeyType = 7
+	if err := CheckSignature(quote); err == nil {
+		t.Error("CheckSignature() accepted an unknown attestation key type")
+	}
+}
+
+func TestCheckQEReportSignatureKnownGoodQuote(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+
+	if err := CheckQEReportSignature(quote); err != nil {
+		t.Errorf("CheckQEReportSignature() error = %v, want nil for a genuine quote", err)
+	}
+}
+
+func TestCheckQEReportSignatureTamperedReport(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	qeReport := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetQeReport()
+	qeReport.GetReportData()[0] ^= 0x01
+
+	if err := CheckQEReportSignature(quote); err == nil {
+		t.Error("CheckQEReportSignature() accepted a modified QE report")
+	}
+}
+
+func TestCheckAttestationKeyBinding(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	if err := CheckAttestationKeyBinding(quote); err != nil {
+		t.Errorf("CheckAttestationKeyBinding() error = %v, want nil for a genuine quote", err)
+	}
+
+	quote.GetSignedData().GetEcdsaAttestationKey()[0] ^= 0x01
+	if err := CheckAttestationKeyBinding(quote); err == nil {
+		t.Error("CheckAttestationKeyBinding() accepted a modified attestation key")
+	}
+}
+
+func TestCheckPCKChain(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	// The fixture's PCK certificate is valid until 2029.
+	chain, err := checkPCKChainAt(quote, nil, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("CheckPCKChain() error = %v, want nil for a genuine quote", err)
+	}
+	if len(chain) != 3 {
+		t.Errorf("CheckPCKChain() returned %d certificates, want 3 (PCK, intermediate, root)", len(chain))
+	}
+}
+
+func TestCheckPCKChainWithRoot(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	certs, err := ParseCertificates(quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetPckCertificateChainData().GetPckCertChain())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trusting the intermediate CA directly shortens the chain to it.
+	intermediate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[1].Raw})
+	chain, err := checkPCKChainAt(quote, intermediate, now)
+	if err != nil {
+		t.Fatalf("CheckPCKChainWithRoot(intermediate) error = %v", err)
+	}
+	if len(chain) != 2 {
+		t.Errorf("CheckPCKChainWithRoot(intermediate) returned %d certificates, want 2", len(chain))
+	}
+	key := quote.GetSignedData().GetEcdsaAttestationKey()
+	if err := checkCertifiedKeyAt(quote, key, intermediate, now); err != nil {
+		t.Errorf("CheckCertifiedKeyWithRoot(intermediate) error = %v", err)
+	}
+
+	// An unrelated root replaces the Intel root rather than adding to it.
+	other := selfSignedPEM(t)
+	if _, err := checkPCKChainAt(quote, other, now); err == nil || !strings.Contains(err.Error(), "configured root CA") {
+		t.Errorf("CheckPCKChainWithRoot(unrelated root) error = %v, want a chain error", err)
+	}
+	if err := checkCertifiedKeyAt(quote, key, other, now); err == nil {
+		t.Error("CheckCertifiedKeyWithRoot(unrelated root) succeeded")
+	}
+	if _, err := checkPCKChainAt(quote, []byte("not PEM"), now); err == nil {
+		t.Error("CheckPCKChainWithRoot(not PEM) succeeded")
+	}
+}
+
+// selfSignedPEM returns a fresh self-signed CA certificate as PEM.
+func selfSignedPEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCheckCertifiedKey(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := bytes.Clone(quote.GetSignedData().GetEcdsaAttestationKey())
+	if err := checkCertifiedKeyAt(quote, key, nil, now); err != nil {
+		t.Fatalf("CheckCertifiedKey() error = %v, want nil for the quote's own key", err)
+	}
+
+	other := bytes.Clone(key)
+	other[0] ^= 0x01
+	if err := checkCertifiedKeyAt(quote, other, nil, now); !errors.Is(err, ErrAttestationKeyMismatch) {
+		t.Errorf("CheckCertifiedKey(other key) error = %v, want ErrAttestationKeyMismatch", err)
+	}
+
+	// The key the QE report commits to counts only if the PCK signed it.
+	quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetQeReportSignature()[0] ^= 0x01
+	if err := checkCertifiedKeyAt(quote, key, nil, now); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("CheckCertifiedKey(bad QE report signature) error = %v, want ErrSignatureInvalid", err)
+	}
+}
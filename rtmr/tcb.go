@@ -0,0 +1,316 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-tdx-guest/pcs"
+	"github.com/google/go-tdx-guest/proto/tdx"
+	"github.com/google/go-tdx-guest/verify/trust"
+)
+
+// TCBStatus is a TCB level status as reported by the Intel PCS, such as
+// "UpToDate" or "OutOfDate".
+type TCBStatus string
+
+// tcbStatusOrder ranks the PCS statuses from best to worst.
+var tcbStatusOrder = []TCBStatus{
+	TCBStatus(pcs.TcbComponentStatusUpToDate),
+	TCBStatus(pcs.TcbComponentStatusSwHardeningNeeded),
+	TCBStatus(pcs.TcbComponentStatusConfigurationNeeded),
+	TCBStatus(pcs.TcbComponentStatusConfigurationAndSWHardeningNeeded),
+	TCBStatus(pcs.TcbComponentStatusOutOfDate),
+	TCBStatus(pcs.TcbComponentStatusOutOfDateConfigurationNeeded),
+	TCBStatus(pcs.TcbComponentStatusRevoked),
+}
+
+// ParseTCBStatus returns the status named s, ignoring case.
+func ParseTCBStatus(s string) (TCBStatus, error) {
+	for _, status := range tcbStatusOrder {
+		if strings.EqualFold(string(status), strings.TrimSpace(s)) {
+			return status, nil
+		}
+	}
+	names := make([]string, len(tcbStatusOrder))
+	for i, status := range tcbStatusOrder {
+		names[i] = string(status)
+	}
+	return "", fmt.Errorf("unknown TCB status %q (want one of %s)", s, strings.Join(names, ", "))
+}
+
+func (s TCBStatus) rank() int {
+	for i, status := range tcbStatusOrder {
+		if status == s {
+			return i
+		}
+	}
+	// Unknown statuses are treated as worse than any known one.
+	return len(tcbStatusOrder)
+}
+
+// WorseThan reports whether s is a worse status than t.
+func (s TCBStatus) WorseThan(t TCBStatus) bool {
+	return s.rank() > t.rank()
+}
+
+// VerifyResult is what full verification learned about the platform beyond
+// pass/fail: the TCB level statuses matched in the PCS collateral.
+type VerifyResult struct {
+	FMSPC string
+	// TCBStatus is the status of the platform TCB level matching the PCK
+	// certificate and the TD's TEE_TCB_SVN.
+	TCBStatus TCBStatus
+	TCBDate   string
+	// TDXModuleStatus is the status of the TDX module TCB level, or empty
+	// for quotes whose TEE_TCB_SVN does not identify a module version.
+	TDXModuleStatus TCBStatus
+	// QEStatus is the status of the Quoting Enclave TCB level.
+	QEStatus TCBStatus
+	// AdvisoryIDs are the Intel security advisories listed for the matched
+	// levels.
+	AdvisoryIDs []string
+	// TCBInfoIssueDate is when the TCB info collateral was issued.
+	TCBInfoIssueDate time.Time
+}
+
+// Status returns the worst of the matched statuses.
+func (r *VerifyResult) Status() TCBStatus {
+	status := r.TCBStatus
+	for _, s := range []TCBStatus{r.TDXModuleStatus, r.QEStatus} {
+		if s != "" && s.WorseThan(status) {
+			status = s
+		}
+	}
+	return status
+}
+
+// collateralRecorder passes collateral requests through and keeps the TCB
+// info and QE identity bodies, so the statuses can be evaluated after
+// verification.
+type collateralRecorder struct {
+	getter     trust.HTTPSGetter
+	tcbInfo    []byte
+	qeIdentity []byte
+	// fetchErr is the first error returned by getter, if any.
+	fetchErr error
+	// files, if not nil, receives every response (VerifyOptions.Collateral).
+	files Collateral
+}
+
+func (g *collateralRecorder) Get(url string) (map[string][]string, []byte, error) {
+	header, body, err := g.getter.Get(url)
+	if err != nil && g.fetchErr == nil {
+		g.fetchErr = err
+	}
+	if err == nil {
+		if g.files != nil {
+			g.files.add(url, header, body)
+		}
+		switch {
+		case strings.Contains(url, "/tcb?"):
+			g.tcbInfo = body
+		case strings.HasSuffix(url, "/qe/identity"):
+			g.qeIdentity = body
+		}
+	}
+	return header, body, err
+}
+
+// tcbStatusErrors are the messages of the verify package rejecting a TCB
+// level status that is not UpToDate, as built by checkTcbInfoTcbStatus and
+// checkQeTcbStatus in go-tdx-guest v0.3.1. They are wrapped with %v, not
+// %w, so there is no error value to match with errors.Is; TestIsTCBStatusError
+// pins the wording.
+var tcbStatusErrors = []string{
+	"failed TCB status check: TCB Status is not",
+	"failed TCB status check: TDX Module TCB Status is not",
+}
+
+// isTCBStatusError reports whether err is the verify package rejecting a TCB
+// level status that is not UpToDate. It has no typed error for this, so the
+// message is matched against tcbStatusErrors.
+func isTCBStatusError(err error) bool {
+	for _, msg := range tcbStatusErrors {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateTCB matches the quote against the recorded collateral the same way
+// the verify package does, and returns the statuses it finds.
+func evaluateTCB(quote *tdx.QuoteV4, rec *collateralRecorder) (*VerifyResult, error) {
+	if rec.tcbInfo == nil || rec.qeIdentity == nil {
+		return nil, fmt.Errorf("collateral was not fetched")
+	}
+	var tcbInfo pcs.TdxTcbInfo
+	if err := json.Unmarshal(rec.tcbInfo, &tcbInfo); err != nil {
+		return nil, fmt.Errorf("parsing TCB info: %v", err)
+	}
+	var qeIdentity pcs.QeIdentity
+	if err := json.Unmarshal(rec.qeIdentity, &qeIdentity); err != nil {
+		return nil, fmt.Errorf("parsing QE identity: %v", err)
+	}
+	exts, err := pckExtensions(quote)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{FMSPC: exts.FMSPC, TCBInfoIssueDate: tcbInfo.TcbInfo.IssueDate}
+	teeTcbSvn := quote.GetTdQuoteBody().GetTeeTcbSvn()
+
+	level, err := matchTCBLevel(tcbInfo.TcbInfo.TcbLevels, teeTcbSvn, exts.TCB.PCESvn, exts.TCB.CPUSvnComponents)
+	if err != nil {
+		return nil, err
+	}
+	result.TCBStatus = TCBStatus(level.TcbStatus)
+	result.TCBDate = level.TcbDate
+	result.AdvisoryIDs = append(result.AdvisoryIDs, level.AdvisoryIDs...)
+
+	if len(teeTcbSvn) > 1 && teeTcbSvn[1] > 0 {
+		module, err := matchTDXModuleLevel(tcbInfo.TcbInfo.TdxModuleIdentities, teeTcbSvn)
+		if err != nil {
+			return nil, err
+		}
+		result.TDXModuleStatus = TCBStatus(module.TcbStatus)
+		result.AdvisoryIDs = append(result.AdvisoryIDs, module.AdvisoryIDs...)
+	}
+
+	qeIsvSvn := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetQeReport().GetIsvSvn()
+	for _, level := range qeIdentity.EnclaveIdentity.TcbLevels {
+		if level.Tcb.Isvsvn <= qeIsvSvn {
+			result.QEStatus = TCBStatus(level.TcbStatus)
+			result.AdvisoryIDs = append(result.AdvisoryIDs, level.AdvisoryIDs...)
+			break
+		}
+	}
+	if result.QEStatus == "" {
+		return nil, fmt.Errorf("no QE identity TCB level matches QE ISVSVN %d", qeIsvSvn)
+	}
+
+	result.AdvisoryIDs = dedupe(result.AdvisoryIDs)
+	return result, nil
+}
+
+// checkQEIdentity performs the QE identity comparison that the verify
+// package skips when it stops early on a TDX TCB status that is not
+// UpToDate. The QE TCB status itself is left to the caller.
+func checkQEIdentity(quote *tdx.QuoteV4, rec *collateralRecorder) error {
+	var qeIdentity pcs.QeIdentity
+	if err := json.Unmarshal(rec.qeIdentity, &qeIdentity); err != nil {
+		return fmt.Errorf("parsing QE identity: %v", err)
+	}
+	id := qeIdentity.EnclaveIdentity
+	qeReport := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetQeReport()
+
+	if len(id.Miscselect.Bytes) != 4 || len(id.MiscselectMask.Bytes) != 4 {
+		return fmt.Errorf("QE identity MISCSELECT fields have the wrong size")
+	}
+	if qeReport.GetMiscSelect()&binary.LittleEndian.Uint32(id.MiscselectMask.Bytes) != binary.LittleEndian.Uint32(id.Miscselect.Bytes) {
+		return fmt.Errorf("QE report MISCSELECT does not match the QE identity")
+	}
+	attributes := qeReport.GetAttributes()
+	if len(id.AttributesMask.Bytes) != len(attributes) {
+		return fmt.Errorf("QE identity attributes mask has the wrong size")
+	}
+	masked := make([]byte, len(attributes))
+	for i := range attributes {
+		masked[i] = attributes[i] & id.AttributesMask.Bytes[i]
+	}
+	if !bytes.Equal(masked, id.Attributes.Bytes) {
+		return fmt.Errorf("QE report attributes do not match the QE identity")
+	}
+	if !bytes.Equal(qeReport.GetMrSigner(), id.Mrsigner.Bytes) {
+		return fmt.Errorf("QE report MRSIGNER %s does not match the QE identity", hex.EncodeToString(qeReport.GetMrSigner()))
+	}
+	if qeReport.GetIsvProdId() != uint32(id.IsvProdID) {
+		return fmt.Errorf("QE report ISVPRODID %d does not match the QE identity", qeReport.GetIsvProdId())
+	}
+	return nil
+}
+
+// pckExtensions returns the SGX extensions of the PCK leaf certificate, the
+// first certificate of the quote's PCK chain.
+func pckExtensions(quote *tdx.QuoteV4) (*pcs.PckExtensions, error) {
+	chain := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetPckCertificateChainData().GetPckCertChain()
+	block, _ := pem.Decode(chain)
+	if block == nil {
+		return nil, fmt.Errorf("no PCK certificate in quote")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing PCK certificate: %v", err)
+	}
+	return pcs.PckCertificateExtensions(cert)
+}
+
+// matchTCBLevel returns the first (highest) TCB level that the platform's
+// SGX components, PCESVN and TEE_TCB_SVN all meet.
+func matchTCBLevel(levels []pcs.TcbLevel, teeTcbSvn []byte, pceSvn uint16, cpuSvn []byte) (*pcs.TcbLevel, error) {
+	for i, level := range levels {
+		if svnAtLeast(cpuSvn, level.Tcb.SgxTcbcomponents, 0) &&
+			pceSvn >= level.Tcb.Pcesvn &&
+			svnAtLeast(teeTcbSvn, level.Tcb.TdxTcbcomponents, teeTcbSvnStart(teeTcbSvn)) {
+			return &levels[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no matching TCB level found")
+}
+
+// teeTcbSvnStart skips the TDX module SVN and version bytes when the module
+// version is set; those are matched against the TDX module identities
+// instead.
+func teeTcbSvnStart(teeTcbSvn []byte) int {
+	if len(teeTcbSvn) > 1 && teeTcbSvn[1] > 0 {
+		return 2
+	}
+	return 0
+}
+
+func svnAtLeast(svns []byte, components []pcs.TcbComponent, start int) bool {
+	if len(svns) != len(components) {
+		return false
+	}
+	for i := start; i < len(svns); i++ {
+		if svns[i] < components[i].Svn {
+			return false
+		}
+	}
+	return true
+}
+
+func matchTDXModuleLevel(identities []pcs.TdxModuleIdentity, teeTcbSvn []byte) (*pcs.TcbLevel, error) {
+	id := "TDX_" + hex.EncodeToString(teeTcbSvn[1:2])
+	for _, identity := range identities {
+		if !strings.EqualFold(identity.ID, id) {
+			continue
+		}
+		for i, level := range identity.TcbLevels {
+			if uint32(teeTcbSvn[0]) >= level.Tcb.Isvsvn {
+				return &identity.TcbLevels[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no TDX module TCB level matches ISVSVN %d", teeTcbSvn[0])
+	}
+	return nil, fmt.Errorf("no TDX module identity %s in TCB info", id)
+}
+
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
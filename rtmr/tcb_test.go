@@ -0,0 +1,314 @@
+package rtmr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-tdx-guest/pcs"
+	"github.com/google/go-tdx-guest/verify"
+)
+
+// components returns 16 TCB components with the given leading SVNs.
+func components(svns ...byte) []pcs.TcbComponent {
+	c := make([]pcs.TcbComponent, 16)
+	for i, svn := range svns {
+		c[i].Svn = svn
+	}
+	return c
+}
+
+// svn16 returns a 16-byte SVN array with the given leading bytes.
+func svn16(b ...byte) []byte {
+	s := make([]byte, 16)
+	copy(s, b)
+	return s
+}
+
+func TestTCBStatusOrder(t *testing.T) {
+	for i, s := range tcbStatusOrder {
+		for j, u := range tcbStatusOrder {
+			if got := s.WorseThan(u); got != (i > j) {
+				t.Errorf("%s.WorseThan(%s) = %v, want %v", s, u, got, i > j)
+			}
+		}
+		if parsed, err := ParseTCBStatus(strings.ToLower(string(s))); err != nil || parsed != s {
+			t.Errorf("ParseTCBStatus(%q) = %q, %v", strings.ToLower(string(s)), parsed, err)
+		}
+		if !TCBStatus("Unknown").WorseThan(s) {
+			t.Errorf("an unknown status is not worse than %s", s)
+		}
+	}
+	if _, err := ParseTCBStatus("Fine"); err == nil {
+		t.Error("ParseTCBStatus(Fine) succeeded")
+	}
+}
+
+func TestVerifyResultStatus(t *testing.T) {
+	for _, tc := range []struct {
+		tcb, module, qe TCBStatus
+		want            TCBStatus
+	}{
+		{"UpToDate", "", "UpToDate", "UpToDate"},
+		{"UpToDate", "OutOfDate", "UpToDate", "OutOfDate"},
+		{"SWHardeningNeeded", "UpToDate", "UpToDate", "SWHardeningNeeded"},
+		{"SWHardeningNeeded", "", "ConfigurationNeeded", "ConfigurationNeeded"},
+		{"OutOfDate", "UpToDate", "Revoked", "Revoked"},
+		{"ConfigurationAndSWHardeningNeeded", "OutOfDateConfigurationNeeded", "UpToDate", "OutOfDateConfigurationNeeded"},
+	} {
+		r := &VerifyResult{TCBStatus: tc.tcb, TDXModuleStatus: tc.module, QEStatus: tc.qe}
+		if got := r.Status(); got != tc.want {
+			t.Errorf("Status() of %s/%s/%s = %s, want %s", tc.tcb, tc.module, tc.qe, got, tc.want)
+		}
+	}
+}
+
+func TestSVNAtLeast(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		svns  []byte
+		min   []pcs.TcbComponent
+		start int
+		want  bool
+	}{
+		{"equal", svn16(3, 1, 4), components(3, 1, 4), 0, true},
+		{"higher", svn16(4, 1, 5), components(3, 1, 4), 0, true},
+		{"one component lower", svn16(4, 0, 5), components(3, 1, 4), 0, false},
+		{"later component lower", svn16(9, 9, 3), components(3, 1, 4), 0, false},
+		{"lower component skipped", svn16(0, 0, 4), components(3, 1, 4), 2, true},
+		{"length mismatch", svn16(3, 1, 4)[:15], components(3, 1, 4), 0, false},
+	} {
+		if got := svnAtLeast(tc.svns, tc.min, tc.start); got != tc.want {
+			t.Errorf("%s: svnAtLeast = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMatchTCBLevel(t *testing.T) {
+	// Levels are listed from the highest down, as in PCS TCB info.
+	levels := []pcs.TcbLevel{
+		{Tcb: pcs.Tcb{SgxTcbcomponents: components(3, 3), Pcesvn: 13, TdxTcbcomponents: components(5, 0, 4)}, TcbStatus: pcs.TcbComponentStatusUpToDate},
+		{Tcb: pcs.Tcb{SgxTcbcomponents: components(3, 3), Pcesvn: 11, TdxTcbcomponents: components(3, 0, 4)}, TcbStatus: pcs.TcbComponentStatusSwHardeningNeeded},
+		{Tcb: pcs.Tcb{SgxTcbcomponents: components(2, 2), Pcesvn: 5, TdxTcbcomponents: components(1, 0, 1)}, TcbStatus: pcs.TcbComponentStatusOutOfDate},
+	}
+	for _, tc := range []struct {
+		name      string
+		teeTcbSvn []byte
+		pceSvn    uint16
+		cpuSvn    []byte
+		want      pcs.TcbComponentStatus
+	}{
+		{"top level", svn16(5, 0, 4), 13, svn16(3, 3), pcs.TcbComponentStatusUpToDate},
+		{"PCESVN below the top level", svn16(5, 0, 4), 12, svn16(3, 3), pcs.TcbComponentStatusSwHardeningNeeded},
+		{"TEE_TCB_SVN below the top level", svn16(4, 0, 4), 13, svn16(3, 3), pcs.TcbComponentStatusSwHardeningNeeded},
+		{"CPUSVN below the second level", svn16(5, 0, 4), 13, svn16(3, 2), pcs.TcbComponentStatusOutOfDate},
+		// With a module version in byte 1, bytes 0 and 1 are matched
+		// against the TDX module identities instead.
+		{"module version set", svn16(0, 1, 4), 13, svn16(3, 3), pcs.TcbComponentStatusUpToDate},
+		{"no level", svn16(5, 0, 4), 4, svn16(3, 3), ""},
+	} {
+		level, err := matchTCBLevel(levels, tc.teeTcbSvn, tc.pceSvn, tc.cpuSvn)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s: matched %s, want no level", tc.name, level.TcbStatus)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if level.TcbStatus != tc.want {
+			t.Errorf("%s: matched %s, want %s", tc.name, level.TcbStatus, tc.want)
+		}
+	}
+}
+
+func TestMatchTDXModuleLevel(t *testing.T) {
+	identities := []pcs.TdxModuleIdentity{
+		{ID: "TDX_01", TcbLevels: []pcs.TcbLevel{
+			{Tcb: pcs.Tcb{Isvsvn: 5}, TcbStatus: pcs.TcbComponentStatusUpToDate},
+			{Tcb: pcs.Tcb{Isvsvn: 3}, TcbStatus: pcs.TcbComponentStatusOutOfDate},
+		}},
+		{ID: "tdx_03", TcbLevels: []pcs.TcbLevel{
+			{Tcb: pcs.Tcb{Isvsvn: 0}, TcbStatus: pcs.TcbComponentStatusUpToDate},
+		}},
+	}
+	for _, tc := range []struct {
+		name      string
+		teeTcbSvn []byte
+		want      pcs.TcbComponentStatus
+	}{
+		{"current module", svn16(6, 1), pcs.TcbComponentStatusUpToDate},
+		{"exact SVN", svn16(5, 1), pcs.TcbComponentStatusUpToDate},
+		{"old module", svn16(4, 1), pcs.TcbComponentStatusOutOfDate},
+		{"identity ID case", svn16(0, 3), pcs.TcbComponentStatusUpToDate},
+		{"below every level", svn16(2, 1), ""},
+		{"unknown major version", svn16(6, 2), ""},
+	} {
+		level, err := matchTDXModuleLevel(identities, tc.teeTcbSvn)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s: matched %s, want an error", tc.name, level.TcbStatus)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if level.TcbStatus != tc.want {
+			t.Errorf("%s: matched %s, want %s", tc.name, level.TcbStatus, tc.want)
+		}
+	}
+}
+
+// The fixture's PCK certificate has CPUSVN 03 03 02 02 02 01 00 02 and
+// PCESVN 11, its TEE_TCB_SVN is 03 00 04 and its QE ISVSVN is 4.
+
+// tcbInfoJSON returns TCB info with one TDX module identity (TDX_01) and the
+// given platform level statuses: the first level matches the fixture
+// exactly, the second is for an older platform.
+func tcbInfoJSON(top, older, module string) []byte {
+	comps := func(svns ...int) string {
+		parts := make([]string, 16)
+		for i := range parts {
+			svn := 0
+			if i < len(svns) {
+				svn = svns[i]
+			}
+			parts[i] = fmt.Sprintf(`{"svn":%d}`, svn)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+	return []byte(fmt.Sprintf(`{"tcbInfo":{
+		"fmspc":"50806f000000","issueDate":"2026-01-01T00:00:00Z",
+		"tdxModuleIdentities":[{"id":"TDX_01","tcbLevels":[
+			{"tcb":{"isvsvn":3},"tcbStatus":%q,"advisoryIDs":["INTEL-SA-00003"]}]}],
+		"tcbLevels":[
+			{"tcb":{"sgxtcbcomponents":%s,"pcesvn":11,"tdxtcbcomponents":%s},"tcbDate":"2025-01-01T00:00:00Z","tcbStatus":%q,"advisoryIDs":["INTEL-SA-00001"]},
+			{"tcb":{"sgxtcbcomponents":%s,"pcesvn":5,"tdxtcbcomponents":%s},"tcbDate":"2024-01-01T00:00:00Z","tcbStatus":%q,"advisoryIDs":["INTEL-SA-00001","INTEL-SA-00002"]}]}}`,
+		module,
+		comps(3, 3, 2, 2, 2, 1, 0, 2), comps(3, 0, 4), top,
+		comps(1, 1), comps(1, 0, 1), older))
+}
+
+// qeIdentityJSON returns a QE identity matching the fixture's QE report,
+// with the given status for QE ISVSVN 4 and above.
+func qeIdentityJSON(status, mrsigner string) []byte {
+	return []byte(fmt.Sprintf(`{"enclaveIdentity":{
+		"miscselect":"00000000","miscselectMask":"ffffffff",
+		"attributes":"11000000000000000000000000000000","attributesMask":"fbffffffffffffff0000000000000000",
+		"mrsigner":%q,"isvprodid":2,
+		"tcbLevels":[
+			{"tcb":{"isvsvn":4},"tcbStatus":%q,"advisoryIDs":["INTEL-SA-00004"]},
+			{"tcb":{"isvsvn":0},"tcbStatus":"OutOfDate"}]}}`, mrsigner, status))
+}
+
+const fixtureQEMrSigner = "dc9e2a7c6f948f17474e34a7fc43ed030f7c1563f1babddf6340c82e0e54a8c5"
+
+func TestEvaluateTCB(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		top, module   string
+		qe            string
+		moduleVersion byte
+		want          VerifyResult
+	}{
+		{"up to date", "UpToDate", "UpToDate", "UpToDate", 0,
+			VerifyResult{TCBStatus: "UpToDate", QEStatus: "UpToDate", TCBDate: "2025-01-01T00:00:00Z", AdvisoryIDs: []string{"INTEL-SA-00001", "INTEL-SA-00004"}}},
+		{"platform needs hardening", "SWHardeningNeeded", "UpToDate", "UpToDate", 0,
+			VerifyResult{TCBStatus: "SWHardeningNeeded", QEStatus: "UpToDate", TCBDate: "2025-01-01T00:00:00Z", AdvisoryIDs: []string{"INTEL-SA-00001", "INTEL-SA-00004"}}},
+		{"QE out of date", "UpToDate", "UpToDate", "OutOfDate", 0,
+			VerifyResult{TCBStatus: "UpToDate", QEStatus: "OutOfDate", TCBDate: "2025-01-01T00:00:00Z", AdvisoryIDs: []string{"INTEL-SA-00001", "INTEL-SA-00004"}}},
+		// Module version 1 with module SVN 3 selects TDX_01; the module
+		// bytes no longer count against the platform level.
+		{"TDX module identity", "UpToDate", "OutOfDate", "UpToDate", 1,
+			VerifyResult{TCBStatus: "UpToDate", TDXModuleStatus: "OutOfDate", QEStatus: "UpToDate", TCBDate: "2025-01-01T00:00:00Z", AdvisoryIDs: []string{"INTEL-SA-00001", "INTEL-SA-00003", "INTEL-SA-00004"}}},
+	} {
+		quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+		quote.GetTdQuoteBody().GetTeeTcbSvn()[1] = tc.moduleVersion
+		rec := &collateralRecorder{tcbInfo: tcbInfoJSON(tc.top, "OutOfDate", tc.module), qeIdentity: qeIdentityJSON(tc.qe, fixtureQEMrSigner)}
+		got, err := evaluateTCB(quote, rec)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got.FMSPC != "50806f000000" || got.TCBStatus != tc.want.TCBStatus || got.TDXModuleStatus != tc.want.TDXModuleStatus ||
+			got.QEStatus != tc.want.QEStatus || got.TCBDate != tc.want.TCBDate ||
+			strings.Join(got.AdvisoryIDs, ",") != strings.Join(tc.want.AdvisoryIDs, ",") {
+			t.Errorf("%s: evaluateTCB = %+v, want %+v", tc.name, *got, tc.want)
+		}
+	}
+
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	if _, err := evaluateTCB(quote, &collateralRecorder{tcbInfo: tcbInfoJSON("UpToDate", "OutOfDate", "UpToDate")}); err == nil {
+		t.Error("evaluateTCB without QE identity succeeded")
+	}
+	// An unknown module version has no identity to match.
+	quote.GetTdQuoteBody().GetTeeTcbSvn()[1] = 2
+	rec := &collateralRecorder{tcbInfo: tcbInfoJSON("UpToDate", "OutOfDate", "UpToDate"), qeIdentity: qeIdentityJSON("UpToDate", fixtureQEMrSigner)}
+	if _, err := evaluateTCB(quote, rec); err == nil {
+		t.Error("evaluateTCB with an unknown TDX module version succeeded")
+	}
+}
+
+func TestCheckQEIdentity(t *testing.T) {
+	quote, _ := loadQuoteV4(t, "tdx_prod_quote_SPR_E4.dat")
+	rec := &collateralRecorder{qeIdentity: qeIdentityJSON("UpToDate", fixtureQEMrSigner)}
+	if err := checkQEIdentity(quote, rec); err != nil {
+		t.Fatalf("checkQEIdentity() error = %v, want nil for the fixture's QE", err)
+	}
+
+	rec.qeIdentity = qeIdentityJSON("UpToDate", strings.Repeat("00", 32))
+	if err := checkQEIdentity(quote, rec); err == nil || !strings.Contains(err.Error(), "MRSIGNER") {
+		t.Errorf("checkQEIdentity(other MRSIGNER) error = %v, want a MRSIGNER mismatch", err)
+	}
+
+	rec.qeIdentity = qeIdentityJSON("UpToDate", fixtureQEMrSigner)
+	report := quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetQeReport()
+	report.IsvProdId = 3
+	if err := checkQEIdentity(quote, rec); err == nil || !strings.Contains(err.Error(), "ISVPRODID") {
+		t.Errorf("checkQEIdentity(other ISVPRODID) error = %v, want an ISVPRODID mismatch", err)
+	}
+	report.IsvProdId = 2
+	report.MiscSelect = 1
+	if err := checkQEIdentity(quote, rec); err == nil || !strings.Contains(err.Error(), "MISCSELECT") {
+		t.Errorf("checkQEIdentity(other MISCSELECT) error = %v, want a MISCSELECT mismatch", err)
+	}
+	report.MiscSelect = 0
+	report.Attributes[0] = 0x17
+	if err := checkQEIdentity(quote, rec); err == nil || !strings.Contains(err.Error(), "attributes") {
+		t.Errorf("checkQEIdentity(debug QE) error = %v, want an attributes mismatch", err)
+	}
+}
+
+// TestIsTCBStatusError pins the messages of go-tdx-guest's TCB status
+// checks, which are wrapped with %v and so can only be matched as text. If
+// an upgrade of go-tdx-guest changes them, this test must be updated with
+// tcbStatusErrors.
+func TestIsTCBStatusError(t *testing.T) {
+	status := func(prefix, found string) error {
+		return fmt.Errorf("%s failed TCB status check: %v", prefix,
+			fmt.Errorf("TCB Status is not %q, found %q", pcs.TcbComponentStatusUpToDate, found))
+	}
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{status("TDX TCB info reported by Intel PCS", "OutOfDate"), true},
+		{status("QE Identity reported by Intel PCS", "SWHardeningNeeded"), true},
+		{fmt.Errorf("TDX TCB info reported by Intel PCS failed TCB status check: %v",
+			fmt.Errorf("TDX Module TCB Status is not %q, found %q", pcs.TcbComponentStatusUpToDate, "OutOfDate")), true},
+		// No matching level is not a status to relax with MinTCB.
+		{fmt.Errorf("TDX TCB info reported by Intel PCS failed TCB status check: %v", errors.New("no matching TCB level found")), false},
+		{fmt.Errorf("QE Identity reported by Intel PCS failed TCB status check: %v", verify.ErrTcbStatus), false},
+		{errors.New("MRSIGNER value in QE Report is not equal to MRSIGNER value in Intel PCS's reported QE Identity"), false},
+		{verify.ErrHashVerificationFail, false},
+	} {
+		if got := isTCBStatusError(tc.err); got != tc.want {
+			t.Errorf("isTCBStatusError(%q) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package rtmr
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-tdx-guest/proto/tdx"
+)
+
+// TEETCBSVN is the TEE_TCB_SVN field of a TD Report: the security version
+// numbers of the TDX TCB, one byte per component. The layout follows the
+// tdxtcbcomponents of Intel PCS TCB info, component 0 in byte 0; bytes past
+// SeamLdrSVN are reserved and zero on current platforms. It is an array of
+// components, not an integer, so it has no byte order.
+type TEETCBSVN [16]byte
+
+// ModuleSVN is the TDX module SVN (its minor version), byte 0.
+func (s TEETCBSVN) ModuleSVN() uint8 { return s[0] }
+
+// ModuleMajor is the TDX module major version, byte 1. It selects the TDX
+// module identity ("TDX_<major>") in TCB info; 0 means the platform predates
+// module identities.
+func (s TEETCBSVN) ModuleMajor() uint8 { return s[1] }
+
+// SeamLdrSVN is the SVN of the SEAM loader, byte 2 (listed as "TDX Late
+// Microcode Update" in TCB info).
+func (s TEETCBSVN) SeamLdrSVN() uint8 { return s[2] }
+
+// String returns the decoded components, e.g. "module SVN 3, major 1,
+// SEAMLDR SVN 4". Non-zero reserved bytes are listed by index.
+func (s TEETCBSVN) String() string {
+	parts := []string{
+		fmt.Sprintf("module SVN %d", s.ModuleSVN()),
+		fmt.Sprintf("major %d", s.ModuleMajor()),
+		fmt.Sprintf("SEAMLDR SVN %d", s.SeamLdrSVN()),
+	}
+	for i := 3; i < len(s); i++ {
+		if s[i] != 0 {
+			parts = append(parts, fmt.Sprintf("byte %d = %d", i, s[i]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AtLeast compares s against min component by component, the way TCB info
+// levels are matched. It returns -1 if every byte of s is at least the one
+// in min, otherwise the index of the first byte that is lower.
+func (s TEETCBSVN) AtLeast(min TEETCBSVN) int {
+	for i := range s {
+		if s[i] < min[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseTEETCBSVN parses a minimum TEE_TCB_SVN given as hex. Values shorter
+// than 16 bytes are zero-padded on the right, so "0301" means module SVN 3
+// and major version 1 with no minimum on the other components.
+func ParseTEETCBSVN(s string) (TEETCBSVN, error) {
+	var svn TEETCBSVN
+	value, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return svn, fmt.Errorf("invalid hex: %v", err)
+	}
+	if len(value) > len(svn) {
+		return svn, fmt.Errorf("expected at most %d bytes, got %d", len(svn), len(value))
+	}
+	copy(svn[:], value)
+	return svn, nil
+}
+
+// TCBSVN returns the decoded TEE_TCB_SVN field of r.
+func (r *TDReport) TCBSVN() TEETCBSVN {
+	return TEETCBSVN(r.TeeTcbSvn)
+}
+
+// CPUSVN is the CPUSVN field of an SGX or TD report: the security version
+// numbers of the CPU, one byte per component in the order of the
+// sgxtcbcomponents of Intel PCS TCB info, component 0 in byte 0. Like
+// TEETCBSVN it is compared byte by byte and has no byte order.
+type CPUSVN [16]byte
+
+// String returns the components in byte order, e.g. "4 4 13 15 3 255 0 3 0
+// 0 0 0 0 0 0 0".
+func (s CPUSVN) String() string {
+	parts := make([]string, len(s))
+	for i, v := range s {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// headerSVN decodes the 2-byte QE SVN or PCE SVN field of a quote header.
+// Both are little-endian uint16 values, like every integer in the header,
+// so the raw bytes 0b 00 are SVN 11. A field of the wrong size decodes as 0.
+func headerSVN(b []byte) uint16 {
+	if len(b) != 2 {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+// QESVN returns the decoded QE SVN of a quote header, or 0 for a nil header.
+func QESVN(h *tdx.Header) uint16 {
+	return headerSVN(h.GetQeSvn())
+}
+
+// PCESVN returns the decoded PCE SVN of a quote header, or 0 for a nil
+// header.
+func PCESVN(h *tdx.Header) uint16 {
+	return headerSVN(h.GetPceSvn())
+}
+
+// CPUSVN returns the decoded CPUSVN field of r. Quotes do not carry it, so
+// it is zero for a TDReport from ParseQuote; the QE's CPUSVN is in
+// QEReport.CpuSvn.
+func (r *TDReport) CPUSVN() CPUSVN {
+	return CPUSVN(r.CpuSvn)
+}
@@ -0,0 +1,43 @@
+package rtmr
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSVNDecoding(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.TCBSVN().String(); got != "module SVN 3, major 0, SEAMLDR SVN 4" {
+		t.Errorf("TCBSVN() = %q", got)
+	}
+	if QESVN(report.Header) != 0 || PCESVN(report.Header) != 0 {
+		t.Errorf("header QE SVN, PCE SVN = %d, %d; want 0, 0", QESVN(report.Header), PCESVN(report.Header))
+	}
+	if report.QE == nil {
+		t.Fatal("no QE report")
+	}
+	if report.QE.IsvSvn != 4 {
+		t.Errorf("QE ISVSVN = %d, want 4", report.QE.IsvSvn)
+	}
+	if got := report.QE.CpuSvn.String(); got != "4 4 13 15 3 255 0 3 0 0 0 0 0 0 0 0" {
+		t.Errorf("QE CPUSVN = %q", got)
+	}
+
+	// The header SVNs are little-endian: 0b 00 is 11, 00 01 is 256.
+	raw[8], raw[9] = 0x00, 0x01   // PCE SVN
+	raw[10], raw[11] = 0x0b, 0x00 // QE SVN
+	report, err = ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if QESVN(report.Header) != 11 || PCESVN(report.Header) != 256 {
+		t.Errorf("header QE SVN, PCE SVN = %d, %d; want 11, 256", QESVN(report.Header), PCESVN(report.Header))
+	}
+}
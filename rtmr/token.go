@@ -0,0 +1,139 @@
+package rtmr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// tokenQuoteClaims are the claim paths searched, in order, for a
+// base64-encoded quote in an attestation token or JSON evidence. There is no
+// standard claim for a raw quote, so these follow the evidence formats seen
+// in practice:
+//
+//   - "quote": the TDX evidence of an Azure Attestation (MAA) attest request,
+//     next to "runtimeData", and of the Confidential Containers attestation
+//     agent, next to "cc_eventlog".
+//   - "tdx.quote": the same evidence nested under the TEE type, as wrappers
+//     that carry evidence of several TEEs do.
+//   - "tdx_quote" and "tdx.tdx_quote": no attestation service is known to
+//     emit these; they are the spellings used by custom token issuers that
+//     re-sign a quote into their own token, and are searched so that such
+//     tokens work without configuration.
+var tokenQuoteClaims = [][]string{
+	{"tdx_quote"},
+	{"quote"},
+	{"tdx", "quote"},
+	{"tdx", "tdx_quote"},
+}
+
+// LooksLikeToken reports whether data has the shape of a JWT: three
+// base64url segments separated by dots, the first of which decodes to a JSON
+// object with an "alg" header.
+func LooksLikeToken(data []byte) bool {
+	parts := strings.Split(strings.TrimSpace(string(data)), ".")
+	if len(parts) != 3 {
+		return false
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	return json.Unmarshal(header, &h) == nil && h.Alg != ""
+}
+
+// QuoteFromToken returns the TDX quote embedded in the claims of a JWT. The
+// quote is looked up under the claim keys "tdx_quote" and "quote", at the top
+// level or inside a "tdx" object (see tokenQuoteClaims for where each comes
+// from), and may be standard or URL-safe base64.
+//
+// The token's signature is NOT checked; the quote itself must be verified
+// (see Report.Verify) for the result to be trusted.
+func QuoteFromToken(token []byte) ([]byte, error) {
+	parts := strings.Split(strings.TrimSpace(string(token)), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token has %d segments, expected 3", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding token claims: %v", err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("parsing token claims: %v", err)
+	}
+	return quoteFromClaims(claims)
+}
+
+// LooksLikeJSONQuote reports whether data is a JSON object, the shape of the
+// evidence wrappers handled by QuoteFromJSON.
+func LooksLikeJSONQuote(data []byte) bool {
+	var v map[string]any
+	return json.Unmarshal(data, &v) == nil
+}
+
+// QuoteFromJSON returns the TDX quote wrapped in a JSON evidence object, as
+// produced by Azure (MAA) and other non-GCP attestation paths. The quote is
+// looked up under the same keys as in QuoteFromToken; other fields are
+// ignored.
+func QuoteFromJSON(data []byte) ([]byte, error) {
+	var claims map[string]any
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("parsing JSON evidence: %v", err)
+	}
+	return quoteFromClaims(claims)
+}
+
+// quoteFromClaims decodes the base64 quote at the first of
+// tokenQuoteClaims present in claims.
+func quoteFromClaims(claims map[string]any) ([]byte, error) {
+	var tried []string
+	for _, path := range tokenQuoteClaims {
+		tried = append(tried, strings.Join(path, "."))
+		value, ok := lookupClaim(claims, path)
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("claim %s is not a string", strings.Join(path, "."))
+		}
+		quote, err := decodeClaimBase64(s)
+		if err != nil {
+			return nil, fmt.Errorf("claim %s: %v", strings.Join(path, "."), err)
+		}
+		return quote, nil
+	}
+	return nil, fmt.Errorf("no quote field (looked for %s)", strings.Join(tried, ", "))
+}
+
+func lookupClaim(claims map[string]any, path []string) (any, bool) {
+	var value any = claims
+	for _, key := range path {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// decodeClaimBase64 accepts standard or URL-safe base64, padded or not.
+func decodeClaimBase64(s string) ([]byte, error) {
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+	b, err := enc.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %v", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,110 @@
+package rtmr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQuoteFromJSON(t *testing.T) {
+	quote := []byte{4, 0, 2, 0, 0x81, 0, 0, 0}
+	for _, evidence := range []string{
+		`{"quote": "` + base64.StdEncoding.EncodeToString(quote) + `", "runtimeData": {"data": "e30"}}`,
+		`{"tdx": {"quote": "` + base64.RawURLEncoding.EncodeToString(quote) + `"}}`,
+	} {
+		data := []byte(evidence)
+		if !LooksLikeJSONQuote(data) {
+			t.Errorf("LooksLikeJSONQuote(%s) = false", evidence)
+		}
+		got, err := QuoteFromJSON(data)
+		if err != nil {
+			t.Errorf("QuoteFromJSON(%s): %v", evidence, err)
+			continue
+		}
+		if !bytes.Equal(got, quote) {
+			t.Errorf("QuoteFromJSON(%s) = %x, want %x", evidence, got, quote)
+		}
+	}
+
+	if _, err := QuoteFromJSON([]byte(`{"report": "AAAA"}`)); err == nil {
+		t.Error("QuoteFromJSON without a quote field succeeded")
+	}
+	if LooksLikeJSONQuote(quote) {
+		t.Error("LooksLikeJSONQuote(raw quote) = true")
+	}
+}
+
+// unsignedJWT returns a JWT with alg "none" and the given claims, as the
+// token decoding never checks signatures.
+func unsignedJWT(claims string) []byte {
+	enc := base64.RawURLEncoding.EncodeToString
+	return []byte(enc([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + enc([]byte(claims)) + ".")
+}
+
+func TestQuoteFromToken(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	std := base64.StdEncoding.EncodeToString(raw)
+	url := base64.RawURLEncoding.EncodeToString(raw)
+	for _, claims := range []string{
+		`{"iss":"test","tdx_quote":"` + std + `"}`,
+		`{"iss":"test","quote":"` + url + `"}`,
+		`{"iss":"test","tdx":{"quote":"` + std + `"}}`,
+		`{"iss":"test","tdx":{"tdx_quote":"` + url + `"}}`,
+	} {
+		token := unsignedJWT(claims)
+		if !LooksLikeToken(token) {
+			t.Errorf("LooksLikeToken(%.40s...) = false", claims)
+		}
+		got, err := QuoteFromToken(append(token, '\n'))
+		if err != nil {
+			t.Errorf("QuoteFromToken(%.40s...): %v", claims, err)
+			continue
+		}
+		if !bytes.Equal(got, raw) {
+			t.Errorf("QuoteFromToken(%.40s...) returned %d bytes that differ from the quote", claims, len(got))
+		}
+		if _, err := ParseQuote(got); err != nil {
+			t.Errorf("ParseQuote(quote from %.40s...): %v", claims, err)
+		}
+	}
+
+	// tdx_quote is searched before quote.
+	both := unsignedJWT(`{"quote":"AAAA","tdx_quote":"` + std + `"}`)
+	if got, err := QuoteFromToken(both); err != nil || !bytes.Equal(got, raw) {
+		t.Errorf("QuoteFromToken with both claims = %d bytes, %v; want the tdx_quote claim", len(got), err)
+	}
+
+	valid := string(unsignedJWT(`{"quote":"` + std + `"}`))
+	segments := strings.Split(valid, ".")
+	for _, tc := range []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"two segments", segments[0] + "." + segments[1], "segments"},
+		{"four segments", valid + ".x", "segments"},
+		{"claims not base64url", segments[0] + ".!!!." + segments[2], "decoding token claims"},
+		{"claims not JSON", segments[0] + "." + base64.RawURLEncoding.EncodeToString([]byte("not json")) + ".", "parsing token claims"},
+		{"no quote claim", string(unsignedJWT(`{"sub":"x"}`)), "no quote field"},
+		{"quote not a string", string(unsignedJWT(`{"quote":42}`)), "not a string"},
+		{"quote not base64", string(unsignedJWT(`{"quote":"%%%"}`)), "invalid base64"},
+	} {
+		if _, err := QuoteFromToken([]byte(tc.token)); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: QuoteFromToken error = %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+
+	for _, notToken := range []string{segments[0] + "." + segments[1], "a.b.c", base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT"}`)) + ".e30."} {
+		if LooksLikeToken([]byte(notToken)) {
+			t.Errorf("LooksLikeToken(%q) = true", notToken)
+		}
+	}
+	if LooksLikeToken(raw) {
+		t.Error("LooksLikeToken(raw quote) = true")
+	}
+}
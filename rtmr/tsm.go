@@ -0,0 +1,82 @@
+package rtmr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TSMReportPath is where the Linux configfs-tsm interface exposes report
+// requests inside a confidential guest.
+const TSMReportPath = "/sys/kernel/config/tsm/report"
+
+// FetchQuote requests a fresh quote over reportData from the configfs-tsm
+// interface at TSMReportPath. It only works inside a TDX guest whose kernel
+// provides configfs-tsm, and usually needs root.
+func FetchQuote(reportData [64]byte) ([]byte, error) {
+	return fetchQuote(TSMReportPath, reportData)
+}
+
+// TSMOperations describes, in order, the configfs-tsm operations FetchQuote
+// performs for reportData, without performing any of them, so a request can
+// be reviewed before it reaches the device. The report entry directory is
+// named at random when the request is made.
+func TSMOperations(reportData [64]byte) []string {
+	return tsmOperations(TSMReportPath, reportData)
+}
+
+func tsmOperations(root string, reportData [64]byte) []string {
+	entry := filepath.Join(root, "tdx-gcp-rtmr-<random>")
+	return []string{
+		"stat " + root,
+		"mkdir " + entry,
+		fmt.Sprintf("write %s (%d bytes): %x", filepath.Join(entry, "inblob"), len(reportData), reportData),
+		"read " + filepath.Join(entry, "outblob") + " (the quote)",
+		"read " + filepath.Join(entry, "generation") + " (must be 1)",
+		"rmdir " + entry,
+	}
+}
+
+func fetchQuote(root string, reportData [64]byte) ([]byte, error) {
+	if _, err := os.Stat(root); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("configfs-tsm report interface not found at %s (not a TDX guest, or configfs not mounted)", root)
+		}
+		return nil, err
+	}
+
+	// Each report request is its own directory; the kernel populates the
+	// entry files when it is created.
+	entry, err := os.MkdirTemp(root, "tdx-gcp-rtmr-")
+	if err != nil {
+		return nil, fmt.Errorf("creating report entry: %v", err)
+	}
+	defer os.Remove(entry)
+
+	if err := os.WriteFile(filepath.Join(entry, "inblob"), reportData[:], 0); err != nil {
+		return nil, fmt.Errorf("writing report data: %v", err)
+	}
+	quote, err := os.ReadFile(filepath.Join(entry, "outblob"))
+	if err != nil {
+		return nil, fmt.Errorf("reading quote: %v", err)
+	}
+
+	// The generation counter is bumped on every write to the entry. Anything
+	// other than our single write means another process raced us and the
+	// quote may not cover our report data.
+	generation, err := os.ReadFile(filepath.Join(entry, "generation"))
+	if err != nil {
+		return nil, fmt.Errorf("reading generation: %v", err)
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(string(generation))); err != nil || n != 1 {
+		return nil, fmt.Errorf("report entry was modified concurrently (generation %q)", strings.TrimSpace(string(generation)))
+	}
+
+	if len(quote) == 0 {
+		return nil, errors.New("configfs-tsm returned an empty quote")
+	}
+	return quote, nil
+}
@@ -0,0 +1,25 @@
+package rtmr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTSMOperationsTouchNothing(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "report")
+	var reportData [64]byte
+	reportData[0] = 0xab
+
+	ops := tsmOperations(root, reportData)
+	if len(ops) == 0 || !strings.HasPrefix(ops[0], "stat "+root) {
+		t.Fatalf("tsmOperations() = %q, want a stat of %s first", ops, root)
+	}
+	if write := ops[2]; !strings.Contains(write, "inblob") || !strings.HasSuffix(write, "ab"+strings.Repeat("00", 63)) {
+		t.Errorf("tsmOperations() write = %q, want the inblob write with the ReportData", write)
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("tsmOperations() created %s", root)
+	}
+}
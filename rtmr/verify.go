@@ -0,0 +1,250 @@
+package rtmr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-tdx-guest/pcs"
+	"github.com/google/go-tdx-guest/proto/tdx"
+	"github.com/google/go-tdx-guest/verify"
+	"github.com/google/go-tdx-guest/verify/trust"
+)
+
+// DefaultPCSURL is the base URL of Intel's Provisioning Certification Service.
+const DefaultPCSURL = "https://api.trustedservices.intel.com"
+
+// intelRootCA is the Intel SGX Root CA that anchors the PCK certificate
+// chain. It is the same certificate go-tdx-guest embeds; passing it
+// explicitly keeps the verify package from logging a warning to stdout.
+//
+//go:embed intel_root_ca.pem
+var intelRootCA []byte
+
+// ErrCollateralFetch is wrapped by errors from Verify when collateral could
+// not be fetched from the PCS (or read from VerifyOptions.CollateralDir).
+var ErrCollateralFetch = errors.New("fetching collateral failed")
+
+// ErrTCBStatus is wrapped by errors from Verify when the quote is otherwise
+// valid but its TCB status is worse than VerifyOptions.MinTCB.
+var ErrTCBStatus = errors.New("TCB status not accepted")
+
+// ErrCollateralAge is wrapped by errors from Verify when the TCB info is
+// older than VerifyOptions.MaxCollateralAge.
+var ErrCollateralAge = errors.New("collateral too old")
+
+// ErrPCKCAPin is wrapped by errors from Verify when no CA certificate of
+// the quote's PCK chain matches VerifyOptions.PCKCAFingerprint.
+var ErrPCKCAPin = errors.New("PCK CA certificate does not match the pinned fingerprint")
+
+// VerifyOptions configures full quote verification.
+type VerifyOptions struct {
+	// PCSURL replaces DefaultPCSURL when fetching collateral, for mirrors of
+	// the Intel PCS in air-gapped setups. Empty means DefaultPCSURL.
+	PCSURL string
+	// CollateralDir, if set, is a directory of collateral files (see
+	// CollateralTCBInfo and friends) used instead of the PCS, so that
+	// verification works offline. PCSURL is ignored when it is set.
+	CollateralDir string
+	// MinTCB is the worst TCB status that is accepted. Empty means only
+	// UpToDate, which is what the verify package enforces on its own.
+	MinTCB TCBStatus
+	// CollateralRetries is how many times a collateral request that failed
+	// transiently (network error, timeout, 429 or 5xx) is retried, with
+	// exponential backoff or the server's Retry-After delay.
+	CollateralRetries int
+	// CollateralTimeout bounds each collateral request. Zero means
+	// DefaultCollateralTimeout.
+	CollateralTimeout time.Duration
+	// MaxCollateralAge, if not zero, is how long ago the TCB info may have
+	// been issued. Older collateral fails verification even while it is
+	// still within its validity period.
+	MaxCollateralAge time.Duration
+	// Collateral, if not nil, receives the collateral used for verification,
+	// so that it can be kept and the verification repeated offline.
+	Collateral Collateral
+	// PCKCAFingerprint, if set, pins the SHA-256 fingerprint of a CA
+	// certificate (intermediate or root) that must be part of the verified
+	// PCK chain, from the leaf in the quote's certification data to the
+	// trusted root; certificates merely present in the certification data
+	// do not count. This is checked before any
+	// collateral is fetched, so a tampered chain is rejected even if the
+	// PCS or its mirror is compromised.
+	PCKCAFingerprint []byte
+	// RootCA, if set, is one or more PEM certificates trusted instead of the
+	// embedded Intel SGX Root CA, for both the PCK chain and the collateral
+	// signing chain. It is meant for test platforms and private PKI; a quote
+	// from a production platform only verifies against the Intel root.
+	RootCA []byte
+}
+
+// Verify cryptographically verifies the quote behind r against the Intel PCS
+// chain: PCK certificate chain, collateral (TCB info and QE identity) and
+// revocation lists. Only QuoteV4 quotes can be verified.
+//
+// The result describes the matched TCB levels. It is returned whenever the
+// collateral could be evaluated, also alongside an error, so that callers
+// can report why a platform was rejected.
+func (r *Report) Verify(opts VerifyOptions) (*VerifyResult, error) {
+	return r.VerifyContext(context.Background(), opts)
+}
+
+// VerifyContext is Verify with a context that bounds the collateral
+// requests to the PCS. When ctx is done, requests in flight are aborted and
+// the error wraps both ErrCollateralFetch and ctx.Err().
+func (r *Report) VerifyContext(ctx context.Context, opts VerifyOptions) (*VerifyResult, error) {
+	if r.CertData != nil {
+		if _, err := r.CertData.CertChain(); err != nil {
+			return nil, err
+		}
+	}
+	if r.Quote == nil {
+		return nil, fmt.Errorf("full verification requires a QuoteV4, got %s", r.Format)
+	}
+	return verifyQuoteV4(ctx, r.Quote, opts)
+}
+
+// VerifyQuoteProto runs the verification of Verify on a QuoteV4 that is
+// already parsed, such as one obtained from go-tdx-guest, without
+// serializing and re-parsing it.
+func VerifyQuoteProto(q *tdx.QuoteV4, opts VerifyOptions) (*VerifyResult, error) {
+	if q == nil {
+		return nil, fmt.Errorf("full verification requires a QuoteV4, got nil")
+	}
+	return verifyQuoteV4(context.Background(), q, opts)
+}
+
+// trustedRoots returns the roots of verification: the certificates of
+// rootCA, or the embedded Intel root CA if rootCA is empty.
+func trustedRoots(rootCA []byte) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+	if len(rootCA) == 0 {
+		if !roots.AppendCertsFromPEM(intelRootCA) {
+			return nil, fmt.Errorf("could not load embedded Intel root CA")
+		}
+		return roots, nil
+	}
+	if !roots.AppendCertsFromPEM(rootCA) {
+		return nil, fmt.Errorf("root CA: no PEM certificates found")
+	}
+	return roots, nil
+}
+
+func verifyQuoteV4(ctx context.Context, quote *tdx.QuoteV4, opts VerifyOptions) (*VerifyResult, error) {
+	if opts.PCKCAFingerprint != nil {
+		if err := checkPCKCAPin(quote, opts.PCKCAFingerprint, opts.RootCA, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	roots, err := trustedRoots(opts.RootCA)
+	if err != nil {
+		return nil, err
+	}
+
+	options := verify.DefaultOptions()
+	options.GetCollateral = true
+	options.CheckRevocations = true
+	options.TrustedRoots = roots
+	if opts.CollateralDir != "" {
+		options.Getter = &dirGetter{dir: opts.CollateralDir}
+	} else {
+		options.Getter = newRetryGetter(ctx, opts.CollateralRetries, opts.CollateralTimeout)
+		if opts.PCSURL != "" && opts.PCSURL != DefaultPCSURL {
+			options.Getter = &mirrorGetter{base: strings.TrimSuffix(opts.PCSURL, "/"), getter: options.Getter}
+		}
+	}
+	rec := &collateralRecorder{getter: options.Getter, files: opts.Collateral}
+	options.Getter = rec
+
+	err = verify.TdxQuote(quote, options)
+	if err != nil && rec.fetchErr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCollateralFetch, ctxErr)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrCollateralFetch, err)
+	}
+	result, evalErr := evaluateTCB(quote, rec)
+	if err != nil {
+		if !isTCBStatusError(err) || evalErr != nil {
+			// Name a bad quote or QE report signature, which the verify
+			// package reports like any other failure.
+			if sigErr := CheckSignature(quote); errors.Is(sigErr, ErrSignatureInvalid) {
+				return result, sigErr
+			}
+			if sigErr := CheckQEReportSignature(quote); errors.Is(sigErr, ErrSignatureInvalid) {
+				return result, sigErr
+			}
+			return result, err
+		}
+		// The verify package rejects anything but UpToDate, and stops
+		// before the QE identity check when the TDX TCB level is the
+		// problem. Finish that check here and apply MinTCB instead.
+		if err := checkQEIdentity(quote, rec); err != nil {
+			return result, err
+		}
+	}
+	if evalErr != nil {
+		return nil, fmt.Errorf("evaluating TCB status: %v", evalErr)
+	}
+
+	minTCB := opts.MinTCB
+	if minTCB == "" {
+		minTCB = TCBStatus(pcs.TcbComponentStatusUpToDate)
+	}
+	if status := result.Status(); status.WorseThan(minTCB) {
+		return result, fmt.Errorf("%w: %s is worse than the minimum accepted %s", ErrTCBStatus, status, minTCB)
+	}
+	if age := time.Since(result.TCBInfoIssueDate); opts.MaxCollateralAge > 0 && age > opts.MaxCollateralAge {
+		return result, fmt.Errorf("%w: TCB info was issued %s ago (%s), more than the maximum %s",
+			ErrCollateralAge, age.Round(time.Second), result.TCBInfoIssueDate.Format(time.RFC3339), opts.MaxCollateralAge)
+	}
+	return result, nil
+}
+
+// checkPCKCAPin checks that a CA certificate of the quote's verified PCK
+// chain, from the leaf to rootCA (see trustedRoots), has the pinned SHA-256
+// fingerprint. Certificates that are only present in the certification data
+// do not count, so appending the pinned certificate to a chain through
+// another CA does not satisfy the pin. The error lists the fingerprints
+// found, so the pin can be updated deliberately.
+func checkPCKCAPin(quote *tdx.QuoteV4, pin, rootCA []byte, now time.Time) error {
+	chains, err := pckChains(quote, rootCA, now)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPCKCAPin, err)
+	}
+	var observed []string
+	for _, chain := range chains {
+		for _, cert := range chain[1:] {
+			fp := sha256.Sum256(cert.Raw)
+			if bytes.Equal(fp[:], pin) {
+				return nil
+			}
+			observed = append(observed, hex.EncodeToString(fp[:]))
+		}
+	}
+	if observed == nil {
+		return fmt.Errorf("%w: the PCK chain has no CA certificates", ErrPCKCAPin)
+	}
+	return fmt.Errorf("%w: pinned %x, chain has %s", ErrPCKCAPin, pin, strings.Join(observed, ", "))
+}
+
+// mirrorGetter rewrites Intel PCS URLs to a mirror before fetching them.
+type mirrorGetter struct {
+	base   string
+	getter trust.HTTPSGetter
+}
+
+func (g *mirrorGetter) Get(url string) (map[string][]string, []byte, error) {
+	if strings.HasPrefix(url, DefaultPCSURL) {
+		url = g.base + strings.TrimPrefix(url, DefaultPCSURL)
+	}
+	return g.getter.Get(url)
+}
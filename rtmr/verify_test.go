@@ -0,0 +1,149 @@
+package rtmr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyQuoteProto(t *testing.T) {
+	if _, err := VerifyQuoteProto(nil, VerifyOptions{}); err == nil {
+		t.Error("VerifyQuoteProto(nil) succeeded")
+	}
+
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With no collateral available, both entry points must fail the same
+	// way, without touching the network.
+	opts := VerifyOptions{CollateralDir: t.TempDir()}
+	_, protoErr := VerifyQuoteProto(report.Quote, opts)
+	_, reportErr := report.Verify(opts)
+	if !errors.Is(protoErr, ErrCollateralFetch) {
+		t.Errorf("VerifyQuoteProto error = %v, want ErrCollateralFetch", protoErr)
+	}
+	if protoErr == nil || reportErr == nil || protoErr.Error() != reportErr.Error() {
+		t.Errorf("VerifyQuoteProto error %v differs from Report.Verify error %v", protoErr, reportErr)
+	}
+}
+
+func TestPCKCAPin(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fps := report.QE.CAFingerprints()
+	if len(fps) != 2 {
+		t.Fatalf("CAFingerprints() returned %d fingerprints, want 2 (intermediate and root)", len(fps))
+	}
+	root := sha256.Sum256(mustDecodePEM(t, intelRootCA))
+	if fps[1] != root {
+		t.Errorf("root fingerprint = %x, want the Intel root CA %x", fps[1], root)
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := checkPCKCAPin(report.Quote, root[:], nil, now); err != nil {
+		t.Errorf("pinning the root CA: %v", err)
+	}
+	if err := checkPCKCAPin(report.Quote, fps[0][:], nil, now); err != nil {
+		t.Errorf("pinning the intermediate CA: %v", err)
+	}
+	wrong := make([]byte, sha256.Size)
+	if err := checkPCKCAPin(report.Quote, wrong, nil, now); !errors.Is(err, ErrPCKCAPin) {
+		t.Errorf("pinning a wrong fingerprint: error = %v, want ErrPCKCAPin", err)
+	}
+
+	// A pinned CA certificate appended to the certification data, but not
+	// part of the chain from the PCK leaf to the root, does not satisfy
+	// the pin.
+	unused := selfSignedPEM(t)
+	pinned := sha256.Sum256(mustDecodePEM(t, unused))
+	chainData := report.Quote.GetSignedData().GetCertificationData().GetQeReportCertificationData().GetPckCertificateChainData()
+	chainData.PckCertChain = append(bytes.Clone(chainData.GetPckCertChain()), unused...)
+	if qe, err := parseQEReport(report.Quote); err != nil || len(qe.CAFingerprints()) != 3 || qe.CAFingerprints()[2] != pinned {
+		t.Fatalf("the appended certificate is not in the certification data: %v", err)
+	}
+	if err := checkPCKCAPin(report.Quote, pinned[:], nil, now); !errors.Is(err, ErrPCKCAPin) {
+		t.Errorf("pinning an appended but unused CA: error = %v, want ErrPCKCAPin", err)
+	}
+	if err := checkPCKCAPin(report.Quote, root[:], nil, now); err != nil {
+		t.Errorf("pinning the root CA with an extra certificate appended: %v", err)
+	}
+
+	// Nor does a pin on a chain that does not verify.
+	if err := checkPCKCAPin(report.Quote, root[:], selfSignedPEM(t), now); !errors.Is(err, ErrPCKCAPin) {
+		t.Errorf("pinning the root CA of an untrusted chain: error = %v, want ErrPCKCAPin", err)
+	}
+}
+
+func TestRootCA(t *testing.T) {
+	raw, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ParseQuote(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A root CA without certificates is rejected before any collateral is
+	// looked up.
+	_, err = report.Verify(VerifyOptions{CollateralDir: t.TempDir(), RootCA: []byte("not PEM")})
+	if err == nil || errors.Is(err, ErrCollateralFetch) || !strings.Contains(err.Error(), "root CA") {
+		t.Errorf("Verify with an invalid root CA: error = %v, want a root CA error", err)
+	}
+
+	certs, err := ParseCertificates(report.QE.PCKCertChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[1].Raw})
+	for _, tc := range []struct {
+		name   string
+		rootCA []byte
+		want   *x509.Certificate
+		reject *x509.Certificate
+	}{
+		{"default", nil, certs[2], nil},
+		{"custom", custom, certs[1], certs[2]},
+	} {
+		roots, err := trustedRoots(tc.rootCA)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if _, err := tc.want.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: tc.want.NotBefore}); err != nil {
+			t.Errorf("%s: %s not trusted: %v", tc.name, tc.want.Subject.CommonName, err)
+		}
+		if tc.reject == nil {
+			continue
+		}
+		if _, err := tc.reject.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: tc.reject.NotBefore}); err == nil {
+			t.Errorf("%s: %s trusted", tc.name, tc.reject.Subject.CommonName)
+		}
+	}
+}
+
+func mustDecodePEM(t *testing.T, data []byte) []byte {
+	t.Helper()
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("no PEM block")
+	}
+	return block.Bytes
+}
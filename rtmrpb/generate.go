@@ -0,0 +1,6 @@
+// Package rtmrpb holds the protobuf messages and gRPC stubs of the
+// attestation service served by "tdx-gcp-rtmr grpc", generated from
+// rtmr.proto.
+package rtmrpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative rtmr.proto
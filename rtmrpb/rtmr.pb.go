@@ -0,0 +1,762 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: rtmr.proto
+
+// Attestation service of tdx-gcp-rtmr: measurement extraction and full
+// verification of Intel TDX quotes.
+
+package rtmrpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ExtractRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Quote in any encoding the tool accepts: raw, protobuf or base64.
+	Quote         []byte `protobuf:"bytes,1,opt,name=quote,proto3" json:"quote,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtractRequest) Reset() {
+	*x = ExtractRequest{}
+	mi := &file_rtmr_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtractRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtractRequest) ProtoMessage() {}
+
+func (x *ExtractRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rtmr_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtractRequest.ProtoReflect.Descriptor instead.
+func (*ExtractRequest) Descriptor() ([]byte, []int) {
+	return file_rtmr_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExtractRequest) GetQuote() []byte {
+	if x != nil {
+		return x.Quote
+	}
+	return nil
+}
+
+type ExtractResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Format is how the quote was decoded, e.g. "raw QuoteV4".
+	Format        string        `protobuf:"bytes,1,opt,name=format,proto3" json:"format,omitempty"`
+	Measurements  *Measurements `protobuf:"bytes,2,opt,name=measurements,proto3" json:"measurements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtractResponse) Reset() {
+	*x = ExtractResponse{}
+	mi := &file_rtmr_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtractResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtractResponse) ProtoMessage() {}
+
+func (x *ExtractResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rtmr_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtractResponse.ProtoReflect.Descriptor instead.
+func (*ExtractResponse) Descriptor() ([]byte, []int) {
+	return file_rtmr_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExtractResponse) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ExtractResponse) GetMeasurements() *Measurements {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+type VerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quote         []byte                 `protobuf:"bytes,1,opt,name=quote,proto3" json:"quote,omitempty"`
+	Options       *VerifyOptions         `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_rtmr_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rtmr_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_rtmr_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VerifyRequest) GetQuote() []byte {
+	if x != nil {
+		return x.Quote
+	}
+	return nil
+}
+
+func (x *VerifyRequest) GetOptions() *VerifyOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type VerifyOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Worst TCB status to accept, e.g. "SWHardeningNeeded". Empty means the
+	// server's --min-tcb.
+	MinTcb string `protobuf:"bytes,1,opt,name=min_tcb,json=minTcb,proto3" json:"min_tcb,omitempty"`
+	// Only check the quote signature, without fetching collateral.
+	SignatureOnly bool `protobuf:"varint,2,opt,name=signature_only,json=signatureOnly,proto3" json:"signature_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyOptions) Reset() {
+	*x = VerifyOptions{}
+	mi := &file_rtmr_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyOptions) ProtoMessage() {}
+
+func (x *VerifyOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_rtmr_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyOptions.ProtoReflect.Descriptor instead.
+func (*VerifyOptions) Descriptor() ([]byte, []int) {
+	return file_rtmr_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *VerifyOptions) GetMinTcb() string {
+	if x != nil {
+		return x.MinTcb
+	}
+	return ""
+}
+
+func (x *VerifyOptions) GetSignatureOnly() bool {
+	if x != nil {
+		return x.SignatureOnly
+	}
+	return false
+}
+
+type VerifyResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Valid is true when the quote parsed, its signature checked out and,
+	// unless signature_only is set, full verification passed.
+	Valid        bool          `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	Format       string        `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	Measurements *Measurements `protobuf:"bytes,3,opt,name=measurements,proto3" json:"measurements,omitempty"`
+	// Signature is "ok" or why the signature check failed.
+	Signature string     `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	Tcb       *TCBStatus `protobuf:"bytes,5,opt,name=tcb,proto3" json:"tcb,omitempty"`
+	Error     string     `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	// Reason categorizes the failure when valid is false, as in the HTTP
+	// server: parse_error, unsupported_format, signature_fail,
+	// collateral_fetch_fail, tcb_out_of_date or verification_fail.
+	Reason        string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyResponse) Reset() {
+	*x = VerifyResponse{}
+	mi := &file_rtmr_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyResponse) ProtoMessage() {}
+
+func (x *VerifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rtmr_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyResponse.ProtoReflect.Descriptor instead.
+func (*VerifyResponse) Descriptor() ([]byte, []int) {
+	return file_rtmr_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *VerifyResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *VerifyResponse) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetMeasurements() *Measurements {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetTcb() *TCBStatus {
+	if x != nil {
+		return x.Tcb
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Measurements carries the measurement fields of the --json output, with
+// byte values as bytes.
+type Measurements struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Rtmr0 []byte                 `protobuf:"bytes,1,opt,name=rtmr0,proto3" json:"rtmr0,omitempty"`
+	Rtmr1 []byte                 `protobuf:"bytes,2,opt,name=rtmr1,proto3" json:"rtmr1,omitempty"`
+	Rtmr2 []byte                 `protobuf:"bytes,3,opt,name=rtmr2,proto3" json:"rtmr2,omitempty"`
+	Rtmr3 []byte                 `protobuf:"bytes,4,opt,name=rtmr3,proto3" json:"rtmr3,omitempty"`
+	// Initialized[i] is false when RTMR[i] is all zeros.
+	Initialized []bool `protobuf:"varint,5,rep,packed,name=initialized,proto3" json:"initialized,omitempty"`
+	// InitializedMask holds initialized as bits, bit i for RTMR[i].
+	InitializedMask uint32 `protobuf:"varint,18,opt,name=initialized_mask,json=initializedMask,proto3" json:"initialized_mask,omitempty"`
+	MrTd            []byte `protobuf:"bytes,6,opt,name=mr_td,json=mrTd,proto3" json:"mr_td,omitempty"`
+	MrConfigId      []byte `protobuf:"bytes,7,opt,name=mr_config_id,json=mrConfigId,proto3" json:"mr_config_id,omitempty"`
+	MrOwner         []byte `protobuf:"bytes,8,opt,name=mr_owner,json=mrOwner,proto3" json:"mr_owner,omitempty"`
+	MrOwnerConfig   []byte `protobuf:"bytes,9,opt,name=mr_owner_config,json=mrOwnerConfig,proto3" json:"mr_owner_config,omitempty"`
+	ReportData      []byte `protobuf:"bytes,10,opt,name=report_data,json=reportData,proto3" json:"report_data,omitempty"`
+	MrSeam          []byte `protobuf:"bytes,11,opt,name=mr_seam,json=mrSeam,proto3" json:"mr_seam,omitempty"`
+	MrSignerSeam    []byte `protobuf:"bytes,12,opt,name=mr_signer_seam,json=mrSignerSeam,proto3" json:"mr_signer_seam,omitempty"`
+	// ServTdHash is the service TD hash (MRSERVICETD) of TDX 1.5 quotes, all
+	// zeros for quotes without one.
+	ServTdHash []byte `protobuf:"bytes,19,opt,name=serv_td_hash,json=servTdHash,proto3" json:"serv_td_hash,omitempty"`
+	TeeTcbSvn  []byte `protobuf:"bytes,13,opt,name=tee_tcb_svn,json=teeTcbSvn,proto3" json:"tee_tcb_svn,omitempty"`
+	// SeamAttributes is the raw SEAMATTRIBUTES field, all zeros for a
+	// production TDX module; seam_attributes_flags lists its set bits.
+	SeamAttributes      []byte   `protobuf:"bytes,20,opt,name=seam_attributes,json=seamAttributes,proto3" json:"seam_attributes,omitempty"`
+	SeamAttributesFlags []string `protobuf:"bytes,21,rep,name=seam_attributes_flags,json=seamAttributesFlags,proto3" json:"seam_attributes_flags,omitempty"`
+	TdAttributes        []byte   `protobuf:"bytes,14,opt,name=td_attributes,json=tdAttributes,proto3" json:"td_attributes,omitempty"`
+	TdAttributesFlags   []string `protobuf:"bytes,15,rep,name=td_attributes_flags,json=tdAttributesFlags,proto3" json:"td_attributes_flags,omitempty"`
+	Xfam                []byte   `protobuf:"bytes,16,opt,name=xfam,proto3" json:"xfam,omitempty"`
+	XfamFeatures        []string `protobuf:"bytes,17,rep,name=xfam_features,json=xfamFeatures,proto3" json:"xfam_features,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Measurements) Reset() {
+	*x = Measurements{}
+	mi := &file_rtmr_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Measurements) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Measurements) ProtoMessage() {}
+
+func (x *Measurements) ProtoReflect() protoreflect.Message {
+	mi := &file_rtmr_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Measurements.ProtoReflect.Descriptor instead.
+func (*Measurements) Descriptor() ([]byte, []int) {
+	return file_rtmr_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Measurements) GetRtmr0() []byte {
+	if x != nil {
+		return x.Rtmr0
+	}
+	return nil
+}
+
+func (x *Measurements) GetRtmr1() []byte {
+	if x != nil {
+		return x.Rtmr1
+	}
+	return nil
+}
+
+func (x *Measurements) GetRtmr2() []byte {
+	if x != nil {
+		return x.Rtmr2
+	}
+	return nil
+}
+
+func (x *Measurements) GetRtmr3() []byte {
+	if x != nil {
+		return x.Rtmr3
+	}
+	return nil
+}
+
+func (x *Measurements) GetInitialized() []bool {
+	if x != nil {
+		return x.Initialized
+	}
+	return nil
+}
+
+func (x *Measurements) GetInitializedMask() uint32 {
+	if x != nil {
+		return x.InitializedMask
+	}
+	return 0
+}
+
+func (x *Measurements) GetMrTd() []byte {
+	if x != nil {
+		return x.MrTd
+	}
+	return nil
+}
+
+func (x *Measurements) GetMrConfigId() []byte {
+	if x != nil {
+		return x.MrConfigId
+	}
+	return nil
+}
+
+func (x *Measurements) GetMrOwner() []byte {
+	if x != nil {
+		return x.MrOwner
+	}
+	return nil
+}
+
+func (x *Measurements) GetMrOwnerConfig() []byte {
+	if x != nil {
+		return x.MrOwnerConfig
+	}
+	return nil
+}
+
+func (x *Measurements) GetReportData() []byte {
+	if x != nil {
+		return x.ReportData
+	}
+	return nil
+}
+
+func (x *Measurements) GetMrSeam() []byte {
+	if x != nil {
+		return x.MrSeam
+	}
+	return nil
+}
+
+func (x *Measurements) GetMrSignerSeam() []byte {
+	if x != nil {
+		return x.MrSignerSeam
+	}
+	return nil
+}
+
+func (x *Measurements) GetServTdHash() []byte {
+	if x != nil {
+		return x.ServTdHash
+	}
+	return nil
+}
+
+func (x *Measurements) GetTeeTcbSvn() []byte {
+	if x != nil {
+		return x.TeeTcbSvn
+	}
+	return nil
+}
+
+func (x *Measurements) GetSeamAttributes() []byte {
+	if x != nil {
+		return x.SeamAttributes
+	}
+	return nil
+}
+
+func (x *Measurements) GetSeamAttributesFlags() []string {
+	if x != nil {
+		return x.SeamAttributesFlags
+	}
+	return nil
+}
+
+func (x *Measurements) GetTdAttributes() []byte {
+	if x != nil {
+		return x.TdAttributes
+	}
+	return nil
+}
+
+func (x *Measurements) GetTdAttributesFlags() []string {
+	if x != nil {
+		return x.TdAttributesFlags
+	}
+	return nil
+}
+
+func (x *Measurements) GetXfam() []byte {
+	if x != nil {
+		return x.Xfam
+	}
+	return nil
+}
+
+func (x *Measurements) GetXfamFeatures() []string {
+	if x != nil {
+		return x.XfamFeatures
+	}
+	return nil
+}
+
+// TCBStatus is the outcome of matching the quote against the TCB info and
+// QE identity collateral.
+type TCBStatus struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Fmspc     string                 `protobuf:"bytes,1,opt,name=fmspc,proto3" json:"fmspc,omitempty"`
+	Platform  string                 `protobuf:"bytes,2,opt,name=platform,proto3" json:"platform,omitempty"`
+	TcbDate   string                 `protobuf:"bytes,3,opt,name=tcb_date,json=tcbDate,proto3" json:"tcb_date,omitempty"`
+	TdxModule string                 `protobuf:"bytes,4,opt,name=tdx_module,json=tdxModule,proto3" json:"tdx_module,omitempty"`
+	Qe        string                 `protobuf:"bytes,5,opt,name=qe,proto3" json:"qe,omitempty"`
+	// Overall is the worst of the matched statuses.
+	Overall       string   `protobuf:"bytes,6,opt,name=overall,proto3" json:"overall,omitempty"`
+	AdvisoryIds   []string `protobuf:"bytes,7,rep,name=advisory_ids,json=advisoryIds,proto3" json:"advisory_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TCBStatus) Reset() {
+	*x = TCBStatus{}
+	mi := &file_rtmr_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TCBStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TCBStatus) ProtoMessage() {}
+
+func (x *TCBStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_rtmr_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TCBStatus.ProtoReflect.Descriptor instead.
+func (*TCBStatus) Descriptor() ([]byte, []int) {
+	return file_rtmr_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TCBStatus) GetFmspc() string {
+	if x != nil {
+		return x.Fmspc
+	}
+	return ""
+}
+
+func (x *TCBStatus) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *TCBStatus) GetTcbDate() string {
+	if x != nil {
+		return x.TcbDate
+	}
+	return ""
+}
+
+func (x *TCBStatus) GetTdxModule() string {
+	if x != nil {
+		return x.TdxModule
+	}
+	return ""
+}
+
+func (x *TCBStatus) GetQe() string {
+	if x != nil {
+		return x.Qe
+	}
+	return ""
+}
+
+func (x *TCBStatus) GetOverall() string {
+	if x != nil {
+		return x.Overall
+	}
+	return ""
+}
+
+func (x *TCBStatus) GetAdvisoryIds() []string {
+	if x != nil {
+		return x.AdvisoryIds
+	}
+	return nil
+}
+
+var File_rtmr_proto protoreflect.FileDescriptor
+
+const file_rtmr_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"rtmr.proto\x12\n" +
+	"tdxrtmr.v1\"&\n" +
+	"\x0eExtractRequest\x12\x14\n" +
+	"\x05quote\x18\x01 \x01(\fR\x05quote\"g\n" +
+	"\x0fExtractResponse\x12\x16\n" +
+	"\x06format\x18\x01 \x01(\tR\x06format\x12<\n" +
+	"\fmeasurements\x18\x02 \x01(\v2\x18.tdxrtmr.v1.MeasurementsR\fmeasurements\"Z\n" +
+	"\rVerifyRequest\x12\x14\n" +
+	"\x05quote\x18\x01 \x01(\fR\x05quote\x123\n" +
+	"\aoptions\x18\x02 \x01(\v2\x19.tdxrtmr.v1.VerifyOptionsR\aoptions\"O\n" +
+	"\rVerifyOptions\x12\x17\n" +
+	"\amin_tcb\x18\x01 \x01(\tR\x06minTcb\x12%\n" +
+	"\x0esignature_only\x18\x02 \x01(\bR\rsignatureOnly\"\xf1\x01\n" +
+	"\x0eVerifyResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12<\n" +
+	"\fmeasurements\x18\x03 \x01(\v2\x18.tdxrtmr.v1.MeasurementsR\fmeasurements\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\tR\tsignature\x12'\n" +
+	"\x03tcb\x18\x05 \x01(\v2\x15.tdxrtmr.v1.TCBStatusR\x03tcb\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x16\n" +
+	"\x06reason\x18\a \x01(\tR\x06reason\"\xba\x05\n" +
+	"\fMeasurements\x12\x14\n" +
+	"\x05rtmr0\x18\x01 \x01(\fR\x05rtmr0\x12\x14\n" +
+	"\x05rtmr1\x18\x02 \x01(\fR\x05rtmr1\x12\x14\n" +
+	"\x05rtmr2\x18\x03 \x01(\fR\x05rtmr2\x12\x14\n" +
+	"\x05rtmr3\x18\x04 \x01(\fR\x05rtmr3\x12 \n" +
+	"\vinitialized\x18\x05 \x03(\bR\vinitialized\x12)\n" +
+	"\x10initialized_mask\x18\x12 \x01(\rR\x0finitializedMask\x12\x13\n" +
+	"\x05mr_td\x18\x06 \x01(\fR\x04mrTd\x12 \n" +
+	"\fmr_config_id\x18\a \x01(\fR\n" +
+	"mrConfigId\x12\x19\n" +
+	"\bmr_owner\x18\b \x01(\fR\amrOwner\x12&\n" +
+	"\x0fmr_owner_config\x18\t \x01(\fR\rmrOwnerConfig\x12\x1f\n" +
+	"\vreport_data\x18\n" +
+	" \x01(\fR\n" +
+	"reportData\x12\x17\n" +
+	"\amr_seam\x18\v \x01(\fR\x06mrSeam\x12$\n" +
+	"\x0emr_signer_seam\x18\f \x01(\fR\fmrSignerSeam\x12 \n" +
+	"\fserv_td_hash\x18\x13 \x01(\fR\n" +
+	"servTdHash\x12\x1e\n" +
+	"\vtee_tcb_svn\x18\r \x01(\fR\tteeTcbSvn\x12'\n" +
+	"\x0fseam_attributes\x18\x14 \x01(\fR\x0eseamAttributes\x122\n" +
+	"\x15seam_attributes_flags\x18\x15 \x03(\tR\x13seamAttributesFlags\x12#\n" +
+	"\rtd_attributes\x18\x0e \x01(\fR\ftdAttributes\x12.\n" +
+	"\x13td_attributes_flags\x18\x0f \x03(\tR\x11tdAttributesFlags\x12\x12\n" +
+	"\x04xfam\x18\x10 \x01(\fR\x04xfam\x12#\n" +
+	"\rxfam_features\x18\x11 \x03(\tR\fxfamFeatures\"\xc4\x01\n" +
+	"\tTCBStatus\x12\x14\n" +
+	"\x05fmspc\x18\x01 \x01(\tR\x05fmspc\x12\x1a\n" +
+	"\bplatform\x18\x02 \x01(\tR\bplatform\x12\x19\n" +
+	"\btcb_date\x18\x03 \x01(\tR\atcbDate\x12\x1d\n" +
+	"\n" +
+	"tdx_module\x18\x04 \x01(\tR\ttdxModule\x12\x0e\n" +
+	"\x02qe\x18\x05 \x01(\tR\x02qe\x12\x18\n" +
+	"\aoverall\x18\x06 \x01(\tR\aoverall\x12!\n" +
+	"\fadvisory_ids\x18\a \x03(\tR\vadvisoryIds2\x92\x01\n" +
+	"\vAttestation\x12B\n" +
+	"\aExtract\x12\x1a.tdxrtmr.v1.ExtractRequest\x1a\x1b.tdxrtmr.v1.ExtractResponse\x12?\n" +
+	"\x06Verify\x12\x19.tdxrtmr.v1.VerifyRequest\x1a\x1a.tdxrtmr.v1.VerifyResponseB(Z&github.com/jsmorph/tdx-gcp-rtmr/rtmrpbb\x06proto3"
+
+var (
+	file_rtmr_proto_rawDescOnce sync.Once
+	file_rtmr_proto_rawDescData []byte
+)
+
+func file_rtmr_proto_rawDescGZIP() []byte {
+	file_rtmr_proto_rawDescOnce.Do(func() {
+		file_rtmr_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rtmr_proto_rawDesc), len(file_rtmr_proto_rawDesc)))
+	})
+	return file_rtmr_proto_rawDescData
+}
+
+var file_rtmr_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_rtmr_proto_goTypes = []any{
+	(*ExtractRequest)(nil),  // 0: tdxrtmr.v1.ExtractRequest
+	(*ExtractResponse)(nil), // 1: tdxrtmr.v1.ExtractResponse
+	(*VerifyRequest)(nil),   // 2: tdxrtmr.v1.VerifyRequest
+	(*VerifyOptions)(nil),   // 3: tdxrtmr.v1.VerifyOptions
+	(*VerifyResponse)(nil),  // 4: tdxrtmr.v1.VerifyResponse
+	(*Measurements)(nil),    // 5: tdxrtmr.v1.Measurements
+	(*TCBStatus)(nil),       // 6: tdxrtmr.v1.TCBStatus
+}
+var file_rtmr_proto_depIdxs = []int32{
+	5, // 0: tdxrtmr.v1.ExtractResponse.measurements:type_name -> tdxrtmr.v1.Measurements
+	3, // 1: tdxrtmr.v1.VerifyRequest.options:type_name -> tdxrtmr.v1.VerifyOptions
+	5, // 2: tdxrtmr.v1.VerifyResponse.measurements:type_name -> tdxrtmr.v1.Measurements
+	6, // 3: tdxrtmr.v1.VerifyResponse.tcb:type_name -> tdxrtmr.v1.TCBStatus
+	0, // 4: tdxrtmr.v1.Attestation.Extract:input_type -> tdxrtmr.v1.ExtractRequest
+	2, // 5: tdxrtmr.v1.Attestation.Verify:input_type -> tdxrtmr.v1.VerifyRequest
+	1, // 6: tdxrtmr.v1.Attestation.Extract:output_type -> tdxrtmr.v1.ExtractResponse
+	4, // 7: tdxrtmr.v1.Attestation.Verify:output_type -> tdxrtmr.v1.VerifyResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_rtmr_proto_init() }
+func file_rtmr_proto_init() {
+	if File_rtmr_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rtmr_proto_rawDesc), len(file_rtmr_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rtmr_proto_goTypes,
+		DependencyIndexes: file_rtmr_proto_depIdxs,
+		MessageInfos:      file_rtmr_proto_msgTypes,
+	}.Build()
+	File_rtmr_proto = out.File
+	file_rtmr_proto_goTypes = nil
+	file_rtmr_proto_depIdxs = nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// verifyResponse is the JSON body returned by POST /verify.
+type verifyResponse struct {
+	// Valid is true when the quote parsed, its signature checked out and,
+	// if the server runs with --verify, full verification passed.
+	Valid        bool               `json:"valid"`
+	Format       string             `json:"format,omitempty"`
+	Measurements *rtmr.Measurements `json:"measurements,omitempty"`
+	Signature    string             `json:"signature,omitempty"`
+	TCB          *rtmr.VerifyResult `json:"tcb,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	// Reason categorizes the failure when Valid is false; see reasonParse
+	// and friends.
+	Reason string `json:"reason,omitempty"`
+
+	// report is the parsed quote, or nil if it did not parse.
+	report *rtmr.Report
+}
+
+func runServe(args []string) {
+	fs := newFlagSet("serve", "[--addr host:port] [--verify] [verification flags]")
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	fs.BoolVar(&verifyFlag, "verify", false, "Also fully verify each quote against the Intel PCS (or --collateral-dir)")
+	addVerifyFlags(fs)
+	parseArgs(fs, args, 0)
+	parseVerifyFlags()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/verify", handleVerify)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	logger.Info("listening", "addr", *addr)
+	fatalf(exitError, "Server failed: %v", http.ListenAndServe(*addr, mux))
+}
+
+// handleVerify accepts a raw, protobuf or base64 quote as the request body
+// and returns a verifyResponse.
+func handleVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST with the quote as the request body", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxQuoteSize))
+	if err != nil {
+		resp := &verifyResponse{Error: fmt.Sprintf("reading request body: %v", err), Reason: reasonParse}
+		status := http.StatusBadRequest
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			resp.Error = fmt.Sprintf("request body exceeds the server's --max-quote-size of %d bytes", maxQuoteSize)
+			status = http.StatusRequestEntityTooLarge
+		}
+		countVerification(resp)
+		writeVerifyResponse(w, status, resp)
+		return
+	}
+	if decoded, ok := decodeBase64Quote(body); ok {
+		body = decoded
+	}
+
+	start := time.Now()
+	resp := verifyQuoteData(r.Context(), body, verifyFlag, verifyOptions())
+	recordVerification(resp, time.Since(start).Seconds())
+	status := http.StatusOK
+	if resp.Format == "" {
+		status = http.StatusBadRequest
+	}
+	writeVerifyResponse(w, status, resp)
+}
+
+// verifyQuoteData runs extraction, the offline signature check and, if full
+// is set, full verification with opts on one quote. Cancelling ctx, the
+// request's context, stops collateral fetches in flight.
+func verifyQuoteData(ctx context.Context, quoteData []byte, full bool, opts rtmr.VerifyOptions) *verifyResponse {
+	report, err := rtmr.ParseQuote(quoteData)
+	if err != nil {
+		return &verifyResponse{Error: err.Error(), Reason: reasonParse}
+	}
+	m := report.Measurements()
+	resp := &verifyResponse{Format: report.Format.String(), Measurements: &m, report: report}
+
+	if report.Quote == nil {
+		resp.Error = fmt.Sprintf("signature check requires a QuoteV4, got %s", report.Format)
+		resp.Reason = reasonUnsupportedFormat
+		return resp
+	}
+	if err := rtmr.CheckSignature(report.Quote); err != nil {
+		resp.Signature = err.Error()
+		resp.Error = "signature verification failed"
+		resp.Reason = reasonSignature
+		return resp
+	}
+	resp.Signature = "ok"
+
+	if full {
+		result, err := report.VerifyContext(ctx, opts)
+		resp.TCB = result
+		if err != nil {
+			resp.Error = err.Error()
+			switch {
+			case errors.Is(err, rtmr.ErrCollateralFetch):
+				resp.Reason = reasonCollateralFetch
+			case errors.Is(err, rtmr.ErrTCBStatus):
+				resp.Reason = reasonTCBOutOfDate
+			default:
+				resp.Reason = reasonVerification
+			}
+			return resp
+		}
+	}
+
+	resp.Valid = true
+	return resp
+}
+
+func writeVerifyResponse(w http.ResponseWriter, status int, resp *verifyResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Warn("writing response", "err", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jsmorph/tdx-gcp-rtmr/rtmr"
+)
+
+// tpmStyle is --tpm-style: print the measurements as a tpm2_pcrread sha384
+// bank, for scripts written against vTPM PCRs.
+var tpmStyle bool
+
+// tpmRegisters maps each TDX measurement register to its index in the
+// output. The index is the UEFI CC measurement register index (MRTD is 0,
+// RTMR[n] is n+1), which is what a TDX guest's EFI_CC_MEASUREMENT_PROTOCOL
+// reports in place of a PCR index; pcrs lists the vTPM PCRs whose events
+// the firmware extends into that register instead.
+var tpmRegisters = []struct {
+	index int
+	name  string
+	pcrs  string
+	value func(r *rtmr.TDReport) []byte
+}{
+	{0, "MRTD", "0", func(r *rtmr.TDReport) []byte { return r.MrTd[:] }},
+	{1, "RTMR0", "1, 7", func(r *rtmr.TDReport) []byte { return r.Rtmr0[:] }},
+	{2, "RTMR1", "2-6", func(r *rtmr.TDReport) []byte { return r.Rtmr1[:] }},
+	{3, "RTMR2", "8-15", func(r *rtmr.TDReport) []byte { return r.Rtmr2[:] }},
+	{4, "RTMR3", "none (workload)", func(r *rtmr.TDReport) []byte { return r.Rtmr3[:] }},
+}
+
+// printTPMStyle prints the registers of tpmRegisters the way tpm2_pcrread
+// prints a sha384 bank: "index : 0x<uppercase hex>" under a bank header.
+// The hex is fixed, regardless of --hash-format, so the lines compare
+// directly with tpm2_pcrread output.
+func printTPMStyle(tdReport *rtmr.TDReport) {
+	fmt.Fprintln(out, "  sha384:")
+	for _, reg := range tpmRegisters {
+		fmt.Fprintf(out, "    %-2d: 0x%s\n", reg.index, strings.ToUpper(hex.EncodeToString(reg.value(tdReport))))
+	}
+	fmt.Fprintln(diag, "\nRegister mapping (index : register : vTPM PCRs):")
+	for _, reg := range tpmRegisters {
+		fmt.Fprintf(diag, "%d : %s : %s\n", reg.index, reg.name, reg.pcrs)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the quote file must be quiet before it is
+// re-read, so that a quote still being written is not parsed half-way.
+const watchDebounce = 250 * time.Millisecond
+
+// watchQuote runs extract on path and again every time it changes, until
+// the process is interrupted. The directory is watched rather than the file
+// so that quotes replaced by rename (as most writers do) are still seen.
+func watchQuote(path string, c extractChecks) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fatalf(exitError, "Failed to start file watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	path = filepath.Clean(path)
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		fatalf(exitError, "Failed to watch %s: %v", filepath.Dir(path), err)
+	}
+
+	run := func() {
+		if isTerminal(os.Stdout) && !ndjson {
+			fmt.Print("\x1b[H\x1b[2J")
+		}
+		logger.Info("watching quote file", "path", path, "time", time.Now().Format(time.TimeOnly))
+		quoteData, err := loadQuoteData(path)
+		if err != nil {
+			logger.Warn("could not load quote", "err", err)
+			return
+		}
+		extractQuote(quoteData, c)
+	}
+	run()
+
+	var timer *time.Timer
+	changed := make(chan struct{}, 1)
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(watchDebounce, func() {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			})
+		case <-changed:
+			run()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("file watcher error", "err", err)
+		}
+	}
+}